@@ -0,0 +1,95 @@
+// Command dlq inspects and replays the dead-letter queue of the transcode
+// worker.
+//
+//	dlq list   [-n 20]   show messages without removing them
+//	dlq replay [-n 0]    move messages back to the work queue (0 = all)
+//	dlq purge            drop every message
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"media-svc/config"
+	"media-svc/internal/services"
+	"media-svc/pkgs/rabbitmq"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	limit := flags.Int("n", 20, "maximum number of messages, 0 for all")
+	queue := flags.String("queue", "", "work queue, defaults to rabbitmq.queue from the config")
+	flags.Parse(os.Args[2:])
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		panic(err)
+	}
+	if *queue == "" {
+		*queue = cfg.RabbitMQ.Queue
+	}
+
+	client, err := services.NewConsumer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+	defer client.Close()
+
+	switch cmd {
+	case "list":
+		letters, err := client.PeekDeadLetters(*queue, *limit)
+		if err != nil {
+			log.Fatalf("List dead letters: %v", err)
+		}
+		for _, l := range letters {
+			failedAt := "-"
+			if !l.FailedAt.IsZero() {
+				failedAt = l.FailedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s\tattempts=%d\terror=%q\n\t%s\n", failedAt, l.Attempts, l.LastError, l.Body)
+		}
+		fmt.Printf("%d message(s) in %s\n", len(letters), rabbitmq.DeadLetterQueueName(*queue))
+	case "replay":
+		n, err := client.ReplayDeadLetters(context.Background(), *queue, flagOr(flags, "n", *limit, 0))
+		if err != nil {
+			log.Fatalf("Replay dead letters (%d replayed): %v", n, err)
+		}
+		fmt.Printf("Replayed %d message(s) to %s\n", n, *queue)
+	case "purge":
+		n, err := client.PurgeDeadLetters(*queue)
+		if err != nil {
+			log.Fatalf("Purge dead letters: %v", err)
+		}
+		fmt.Printf("Purged %d message(s) from %s\n", n, rabbitmq.DeadLetterQueueName(*queue))
+	default:
+		usage()
+	}
+}
+
+// flagOr returns value when the flag was set explicitly and def otherwise,
+// so subcommands can have their own default for a shared flag.
+func flagOr(flags *flag.FlagSet, name string, value, def int) int {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	if set {
+		return value
+	}
+	return def
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq list|replay|purge [-n N] [-queue NAME]")
+	os.Exit(2)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestFlagOr(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		// replay moves the whole queue unless -n is given.
+		{nil, 0},
+		{[]string{"-n", "5"}, 5},
+		{[]string{"-n", "20"}, 20},
+	}
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+		limit := flags.Int("n", 20, "")
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := flagOr(flags, "n", *limit, 0); got != tt.want {
+			t.Errorf("flagOr(%v) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"media-svc/pkgs/rabbitmq"
+	"net/http"
+	"time"
+)
+
+// stateReporter is the part of *rabbitmq.Consumer the health server uses.
+type stateReporter interface {
+	State() rabbitmq.State
+}
+
+// newHealthServer serves GET /healthz, answering 200 while the consumer is
+// connected to RabbitMQ and 503 while it is (re)connecting or closed.
+func newHealthServer(addr string, consumer stateReporter) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		state := consumer.State()
+		status := http.StatusOK
+		if state != rabbitmq.StateConnected {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"rabbitmq": state.String()})
+	})
+	return &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+}
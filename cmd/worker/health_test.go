@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"media-svc/pkgs/rabbitmq"
+)
+
+type fixedState rabbitmq.State
+
+func (s fixedState) State() rabbitmq.State {
+	return rabbitmq.State(s)
+}
+
+func TestHealthz(t *testing.T) {
+	tests := []struct {
+		state rabbitmq.State
+		want  int
+	}{
+		{rabbitmq.StateConnected, http.StatusOK},
+		{rabbitmq.StateConnecting, http.StatusServiceUnavailable},
+		{rabbitmq.StateReconnecting, http.StatusServiceUnavailable},
+		{rabbitmq.StateClosed, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		server := newHealthServer(":0", fixedState(tt.state))
+
+		rec := httptest.NewRecorder()
+		server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.state, rec.Code, tt.want)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["rabbitmq"] != tt.state.String() {
+			t.Errorf("%s: body = %v, %v", tt.state, body, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newHealthServer(":0", fixedState(rabbitmq.StateConnected)).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
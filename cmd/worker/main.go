@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"media-svc/config"
@@ -11,6 +12,7 @@ import (
 	"media-svc/internal/services/media"
 	"media-svc/internal/types"
 	"media-svc/pkgs/rabbitmq"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -36,18 +38,27 @@ func main() {
 	}
 
 	// Initialize RabbitMQ client
-	client, err := rabbitmq.NewConsumer(cfg.RabbitMQ.DSN)
+	client, err := services.NewConsumer(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
 
+	// Remove job directories left behind by crashed runs
+	sweeper := services.NewScratchManager(cfg)
+	if removed, err := sweeper.Sweep(); err != nil {
+		log.Printf("Failed to sweep scratch dirs: %v", err)
+	} else if removed > 0 {
+		log.Printf("Removed %d stale scratch dirs from %s", removed, sweeper.Root())
+	}
+
 	// Initialize service layer
-	service := services.NewService(cfg, db, nil)
+	service, err := services.NewService(cfg, db, nil)
+	if err != nil {
+		log.Fatalf("Failed to initialize services: %v", err)
+	}
 
-	// Create orchestrator with desired number of workers and queue depth
-	workerCount := 4
-	queueDepth := 100
-	orcTranscode := transcode.New(service, workerCount, queueDepth)
+	// Create orchestrator running as many jobs as the consumer prefetches
+	orcTranscode := transcode.New(service, cfg.Worker.Concurrency)
 
 	// Start the orchestrator (start worker goroutines)
 	orcTranscode.Start()
@@ -64,27 +75,40 @@ func main() {
 		err := client.Consume(ctx, cfg.RabbitMQ.Queue, func(data []byte) error {
 			var job types.TranscodeJob
 			if err := json.Unmarshal(data, &job); err != nil {
-				return fmt.Errorf("invalid job format: %w", err)
+				return rabbitmq.Permanent(fmt.Errorf("invalid job format: %w", err))
 			}
 
 			log.Printf("Received job: %+v", job)
-			orcTranscode.AddJob(media.TranscodeVideoInput{MediaID: job.MediaID})
 
-			return nil
+			// Block until the job finishes so the message is acked or
+			// retried on its outcome
+			return orcTranscode.Process(ctx, media.TranscodeVideoInput{
+				MediaID:   job.MediaID,
+				Ladder:    job.Ladder,
+				Options:   job.Options,
+				MessageID: job.MessageID,
+				JobID:     job.JobID,
+			})
 		})
 
 		if err != nil {
-			// Nếu lỗi là channel closed do ctx bị cancel thì có thể không cần log lỗi nặng
-			if err.Error() == "channel closed" {
-				log.Println("Consumer stopped due to channel close (likely normal shutdown)")
-			} else {
-				log.Printf("Consumer exited with error: %v", err)
-			}
+			log.Printf("Consumer exited with error: %v", err)
 		} else {
 			log.Println("Consumer exited cleanly")
 		}
 	}()
 
+	// Expose the broker connection state for liveness checks
+	var health *http.Server
+	if cfg.Worker.HealthPort != "" {
+		health = newHealthServer(":"+cfg.Worker.HealthPort, client)
+		go func() {
+			if err := health.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Health server exited with error: %v", err)
+			}
+		}()
+	}
+
 	// Listen for OS signals for graceful shutdown
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
@@ -92,12 +116,14 @@ func main() {
 	<-sig
 	log.Println("Worker shutting down...")
 
-	// Stop consumer
+	// Stop taking messages, then interrupt the jobs in progress; their
+	// messages are requeued once the consumer sees them fail
+	if health != nil {
+		health.Close()
+	}
 	cancel()
-	wg.Wait()
-
-	// Stop orchestrator and wait for all workers to finish
 	orcTranscode.Stop()
+	wg.Wait()
 
 	// Close RabbitMQ client connection
 	client.Close()
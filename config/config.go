@@ -2,20 +2,268 @@ package config
 
 import (
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   Server   `mapstructure:"server"`
-	DB       DB       `mapstructure:"db"`
-	S3       S3       `mapstructure:"s3"`
-	RabbitMQ RabbitMQ `mapstructure:"rabbitmq"`
+	Server    Server    `mapstructure:"server"`
+	DB        DB        `mapstructure:"db"`
+	S3        S3        `mapstructure:"s3"`
+	Storage   Storage   `mapstructure:"storage"`
+	RabbitMQ  RabbitMQ  `mapstructure:"rabbitmq"`
+	Transcode Transcode `mapstructure:"transcode"`
+	Scratch   Scratch   `mapstructure:"scratch"`
+	Worker    Worker    `mapstructure:"worker"`
+	Upload    Upload    `mapstructure:"upload"`
+	Playback  Playback  `mapstructure:"playback"`
+	Auth      Auth      `mapstructure:"auth"`
+}
+
+// Auth configures authentication of the API. Streaming and key delivery
+// are authorized with playback and access tokens instead.
+type Auth struct {
+	// Enabled requires a JWT or API key on the API routes and limits media
+	// to their owner and admins. Without it every caller may do anything,
+	// so it defaults to true when the config does not set it.
+	Enabled bool `mapstructure:"enabled"`
+	// AdminRole is the role that may access the media of every owner.
+	AdminRole string `mapstructure:"admin_role"`
+	JWT       JWT    `mapstructure:"jwt"`
+}
+
+// JWT configures the bearer tokens accepted by the API. The subject of a
+// token is the owner of the media it uploads.
+type JWT struct {
+	// HS256Secret accepts HS256 tokens signed with it.
+	HS256Secret string `mapstructure:"hs256_secret"`
+	// JWKSFile accepts RS256 tokens signed with one of its keys.
+	JWKSFile string `mapstructure:"jwks_file"`
+	// Issuer and Audience are required in the iss and aud claims when set.
+	Issuer   string `mapstructure:"issuer"`
+	Audience string `mapstructure:"audience"`
+	// RolesClaim is the claim listing the roles of the subject.
+	RolesClaim string `mapstructure:"roles_claim"`
+	// Leeway allows for clock skew when checking the expiry.
+	Leeway time.Duration `mapstructure:"leeway"`
+}
+
+// Playback configures the signed tokens that authorize stream requests.
+type Playback struct {
+	// Secret is the HMAC key tokens are signed with. Stream files are
+	// served without a token when it is empty. Images such as posters and
+	// trickplay sprites never need one.
+	Secret string `mapstructure:"secret"`
+	// TokenTTL is how long a token stays valid when the request for it
+	// does not say.
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
+	// MaxTokenTTL caps the lifetime a client may ask for.
+	MaxTokenTTL time.Duration `mapstructure:"max_token_ttl"`
+}
+
+// Upload configures direct-to-storage uploads with presigned URLs.
+type Upload struct {
+	// URLExpiry is how long the presigned upload URLs stay valid.
+	URLExpiry time.Duration `mapstructure:"url_expiry"`
+	// MultipartThresholdMB is the file size from which an upload is split
+	// into parts with their own presigned URLs.
+	MultipartThresholdMB int64 `mapstructure:"multipart_threshold_mb"`
+	// PartSizeMB is the size of every part but the last. It grows for files
+	// that would need more than the 10000 parts S3 allows.
+	PartSizeMB int64 `mapstructure:"part_size_mb"`
+	// Tus configures resumable uploads with the tus protocol.
+	Tus Tus `mapstructure:"tus"`
+}
+
+// Tus configures the tus resumable upload endpoints.
+type Tus struct {
+	// MaxSizeMB is the largest accepted Upload-Length, 0 for unlimited.
+	MaxSizeMB int64 `mapstructure:"max_size_mb"`
+	// PartSizeMB is the size of the storage parts received bytes are
+	// grouped into. Each request buffers up to one part in memory, and S3
+	// needs at least 5 MB.
+	PartSizeMB int64 `mapstructure:"part_size_mb"`
+}
+
+// Worker configures the transcode worker process.
+type Worker struct {
+	// HealthPort serves GET /healthz with the broker connection state. The
+	// endpoint is disabled when empty.
+	HealthPort string `mapstructure:"health_port"`
+	// Concurrency is how many jobs a worker transcodes at once. It is also
+	// the RabbitMQ prefetch count, so unstarted jobs stay on the broker.
+	Concurrency int `mapstructure:"concurrency"`
+	// LeaseDuration is how long a job stays owned by its worker without a
+	// heartbeat.
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+	// HeartbeatInterval is how often a running job renews its lease and
+	// checks for cancel requests, each time a write to MongoDB. It defaults
+	// to a third of LeaseDuration and must be shorter than the lease.
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	// MaxLostLeases is how often a job may lose its worker before it is
+	// marked failed instead of being dispatched again.
+	MaxLostLeases int `mapstructure:"max_lost_leases"`
+	// ReapInterval is how often the API looks for jobs with expired leases,
+	// for stale pending jobs and for direct uploads abandoned an hour past
+	// their URL expiry.
+	ReapInterval time.Duration `mapstructure:"reap_interval"`
+	// PendingTimeout is how long a job may stay pending before its message
+	// is considered lost and the job is dispatched again. It defaults to
+	// 30 minutes; a duplicate message is dropped once a worker claimed the
+	// job.
+	PendingTimeout time.Duration `mapstructure:"pending_timeout"`
+}
+
+// Scratch configures the per-job working directories of the worker.
+type Scratch struct {
+	// Root is the directory job directories are created in. A directory
+	// under the system temp directory is used when empty.
+	Root string `mapstructure:"root"`
+	// QuotaMB caps the total size of all job directories, 0 for unlimited.
+	QuotaMB int64 `mapstructure:"quota_mb"`
+	// MinFreeMB is the free space that must remain on the filesystem when
+	// a job directory is created.
+	MinFreeMB int64 `mapstructure:"min_free_mb"`
+	// StaleAfter is the age after which leftover directories are removed
+	// on startup even if their owner process may still be alive.
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+}
+
+// Transcode holds the transcoding settings shared by the API and the worker.
+type Transcode struct {
+	// DefaultLadder is the ladder used when an upload does not pick one.
+	DefaultLadder string `mapstructure:"default_ladder"`
+	// Ladders maps a ladder name (e.g. "default", "mobile") to its renditions,
+	// ordered from the highest to the lowest quality.
+	Ladders map[string][]Rendition `mapstructure:"ladders"`
+	// JobTimeout is the wall-clock limit for encoding one job, 0 for none.
+	JobTimeout time.Duration `mapstructure:"job_timeout"`
+	// KillGracePeriod is how long ffmpeg may take to exit after SIGTERM
+	// before it is killed.
+	KillGracePeriod time.Duration `mapstructure:"kill_grace_period"`
+	// ProgressInterval is the minimum time between two progress writes to
+	// the transcode job document.
+	ProgressInterval time.Duration `mapstructure:"progress_interval"`
+	// Encryption configures HLS content encryption.
+	Encryption Encryption `mapstructure:"encryption"`
+	// Thumbnails configures the poster and thumbnail images.
+	Thumbnails Thumbnails `mapstructure:"thumbnails"`
+	// Trickplay configures the seek-bar preview sprite sheets.
+	Trickplay Trickplay `mapstructure:"trickplay"`
+}
+
+// Trickplay configures the sprite sheets and WebVTT track players use for
+// seek-bar previews.
+type Trickplay struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the time between two preview frames.
+	Interval time.Duration `mapstructure:"interval"`
+	// Width is the tile width in pixels; the height keeps the aspect ratio.
+	Width int `mapstructure:"width"`
+	// Columns and Rows are the tile layout of one sprite sheet.
+	Columns int `mapstructure:"columns"`
+	Rows    int `mapstructure:"rows"`
+	// Quality is the JPEG quality from 1 to 100.
+	Quality int `mapstructure:"quality"`
+	// ImagePlaylist also publishes an HLS image playlist, referenced from
+	// the master playlist with EXT-X-IMAGE-STREAM-INF.
+	ImagePlaylist bool `mapstructure:"image_playlist"`
+}
+
+// Thumbnails configures the poster and thumbnail images generated with each
+// transcode.
+type Thumbnails struct {
+	Enabled bool `mapstructure:"enabled"`
+	// PosterAt is the timestamp of the poster frame. When PosterAuto is set,
+	// or PosterAt is past the end of the video, ffmpeg picks the most
+	// representative frame near the start instead.
+	PosterAt   time.Duration `mapstructure:"poster_at"`
+	PosterAuto bool          `mapstructure:"poster_auto"`
+	// Count is the number of evenly spaced thumbnails, 0 for the poster only.
+	Count int `mapstructure:"count"`
+	// Sizes are the bounding boxes every image is rendered at.
+	Sizes []ImageSize `mapstructure:"sizes"`
+	// Formats lists the image formats, "jpeg" and/or "webp".
+	Formats []string `mapstructure:"formats"`
+	// Quality is the image quality from 1 to 100.
+	Quality int `mapstructure:"quality"`
+}
+
+// ImageSize is an image bounding box; a zero height keeps the aspect ratio.
+type ImageSize struct {
+	Width  int `mapstructure:"width"`
+	Height int `mapstructure:"height"`
+}
+
+// Encryption configures HLS content encryption and key delivery.
+type Encryption struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Method is "AES-128" (default) or "SAMPLE-AES", which encrypts with
+	// cbcs through Bento4's mp4encrypt.
+	Method string `mapstructure:"method"`
+	// MasterKey is the base64 encoded 32 byte key that encrypts the content
+	// keys stored in MongoDB.
+	MasterKey string `mapstructure:"master_key"`
+	// KeyURL is the public base URL of the API, used to build the key URI
+	// written to the playlists. A relative URI is used when empty.
+	KeyURL string `mapstructure:"key_url"`
+	// AccessTokens are static bearer tokens the key endpoint accepts besides
+	// playback tokens and the credentials of callers who may access the
+	// video.
+	AccessTokens []string `mapstructure:"access_tokens"`
+}
+
+// Rendition describes one output quality profile of a ladder.
+type Rendition struct {
+	Name            string `mapstructure:"name"`              // e.g. "1080p"
+	Width           int    `mapstructure:"width"`             // target width in pixels
+	Height          int    `mapstructure:"height"`            // target height in pixels
+	VideoCodec      string `mapstructure:"video_codec"`       // h264 (default), hevc, vp9 or av1
+	Encoder         string `mapstructure:"encoder"`           // optional encoder override, e.g. "libaom-av1"
+	Preset          string `mapstructure:"preset"`            // optional encoder preset, e.g. "veryfast"
+	VideoBitrate    string `mapstructure:"video_bitrate"`     // e.g. "5000k"
+	MaxRate         string `mapstructure:"maxrate"`           // optional VBV max rate, e.g. "5350k"
+	BufSize         string `mapstructure:"bufsize"`           // optional VBV buffer size, e.g. "7500k"
+	Profile         string `mapstructure:"profile"`           // encoder profile, e.g. "main", "high"
+	Level           string `mapstructure:"level"`             // encoder level, e.g. "4.0"
+	AudioBitrate    string `mapstructure:"audio_bitrate"`     // e.g. "128k"
+	AudioChannels   int    `mapstructure:"audio_channels"`    // e.g. 2; 0 keeps the source layout
+	AudioSampleRate int    `mapstructure:"audio_sample_rate"` // e.g. 48000; 0 keeps the source rate
 }
 
 type RabbitMQ struct {
 	DSN   string `mapstructure:"dsn"`
 	Queue string `mapstructure:"queue"`
+	// MaxAttempts is how many times a job message is delivered before it is
+	// moved to the dead-letter queue.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// RetryBaseDelay is the wait before the first retry; each further retry
+	// doubles it up to RetryMaxDelay.
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay"`
+	// OutboxInterval is how often the API polls the outbox for job messages
+	// to publish.
+	OutboxInterval time.Duration `mapstructure:"outbox_interval"`
+	// OutboxBatchSize is the most messages published per poll.
+	OutboxBatchSize int `mapstructure:"outbox_batch_size"`
+}
+
+// Storage selects the object storage backend.
+type Storage struct {
+	// Driver is "s3" (default) for the S3 settings or "fs" for local files.
+	Driver string    `mapstructure:"driver"`
+	FS     FSStorage `mapstructure:"fs"`
+}
+
+// FSStorage configures the local filesystem storage backend.
+type FSStorage struct {
+	// Root is the directory holding one subdirectory per bucket.
+	Root string `mapstructure:"root"`
+	// BaseURL is the public URL of the REST server presigned URLs point to.
+	BaseURL string `mapstructure:"base_url"`
+	// SigningKey is the HMAC key of the presigned URLs.
+	SigningKey string `mapstructure:"signing_key"`
 }
 
 type S3 struct {
@@ -48,6 +296,9 @@ func LoadConfig() (*Config, error) {
 	v.SetConfigType("yaml")
 	v.SetConfigFile("config/config.yaml")
 
+	// Authentication is only off when a config turns it off explicitly
+	v.SetDefault("auth.enabled", true)
+
 	if err := v.ReadInConfig(); err != nil {
 		return nil, err
 	}
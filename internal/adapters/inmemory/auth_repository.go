@@ -0,0 +1,83 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"media-svc/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuthRepository keeps API keys in memory, copied on the way in and out
+// like MediaRepository.
+type AuthRepository struct {
+	mu   sync.RWMutex
+	keys map[primitive.ObjectID]models.APIKey
+}
+
+func NewAuthRepository() *AuthRepository {
+	return &AuthRepository{
+		keys: make(map[primitive.ObjectID]models.APIKey),
+	}
+}
+
+func (r *AuthRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.BeforeCreate()
+	for _, k := range r.keys {
+		if k.ID == key.ID || k.Hash == key.Hash {
+			return ErrDuplicateKey
+		}
+	}
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *AuthRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			return &k, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *AuthRepository) ListAPIKeys(ctx context.Context, ownerID string) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*models.APIKey
+	for _, k := range r.keys {
+		if ownerID != "" && k.OwnerID != ownerID {
+			continue
+		}
+		k := k
+		out = append(out, &k)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID.Hex() > out[j].ID.Hex()
+	})
+	return out, nil
+}
+
+func (r *AuthRepository) RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok || k.RevokedAt != nil {
+		return false, nil
+	}
+	k.RevokedAt = &at
+	k.UpdatedAt = time.Now().UTC()
+	r.keys[id] = k
+	return true, nil
+}
@@ -0,0 +1,780 @@
+// Package inmemory provides in-memory implementations of the repository,
+// broker and storage adapters for tests and local experiments.
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"media-svc/internal/adapters/mongodb/media"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrDuplicateKey mirrors the unique index violations of the MongoDB
+// repository.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// MediaRepository keeps media, transcode jobs and media keys in memory.
+// Stored documents are copied on the way in and out, so callers cannot
+// change them without an update, as with a real database.
+type MediaRepository struct {
+	mu     sync.RWMutex
+	medias map[primitive.ObjectID]models.Media
+	jobs   []models.TranscodeJob
+	keys   map[primitive.ObjectID]models.MediaKey
+	outbox []models.OutboxMessage
+	tus    map[primitive.ObjectID]models.TusUpload
+}
+
+func NewMediaRepository() *MediaRepository {
+	return &MediaRepository{
+		medias: make(map[primitive.ObjectID]models.Media),
+		keys:   make(map[primitive.ObjectID]models.MediaKey),
+		tus:    make(map[primitive.ObjectID]models.TusUpload),
+	}
+}
+
+func (r *MediaRepository) CreateMedia(ctx context.Context, media *models.Media) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media.BeforeCreate()
+	if _, ok := r.medias[media.ID]; ok {
+		return ErrDuplicateKey
+	}
+	r.medias[media.ID] = *media
+	return nil
+}
+
+// CreateMediaWithOutbox stores both documents or neither.
+func (r *MediaRepository) CreateMediaWithOutbox(ctx context.Context, media *models.Media, msg *models.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media.BeforeCreate()
+	msg.BeforeCreate()
+	if _, ok := r.medias[media.ID]; ok {
+		return ErrDuplicateKey
+	}
+	r.medias[media.ID] = *media
+	r.outbox = append(r.outbox, *msg)
+	return nil
+}
+
+func (r *MediaRepository) GetMedia(ctx context.Context, id string) (*models.Media, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	media, ok := r.medias[oid]
+	if !ok {
+		return nil, nil
+	}
+	return &media, nil
+}
+
+func (r *MediaRepository) GetMediaByStreamPath(ctx context.Context, filePath string) (*models.Media, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, media := range r.medias {
+		if media.TranscodeSource != nil && media.TranscodeSource.FilePath == filePath {
+			return &media, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MediaRepository) UpdateMedia(ctx context.Context, media *models.Media) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.medias[media.ID]; !ok {
+		return nil
+	}
+	media.BeforeUpdate()
+	r.medias[media.ID] = *media
+	return nil
+}
+
+func (r *MediaRepository) UpdateMediaDetails(ctx context.Context, input media.UpdateMediaDetailsInput) (*models.Media, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.medias[input.ID]
+	if !ok {
+		return nil, nil
+	}
+	if input.Name != nil {
+		stored.Name = *input.Name
+	}
+	if input.Description != nil {
+		stored.Description = *input.Description
+	}
+	if input.Tags != nil {
+		stored.Tags = nil
+		if len(*input.Tags) > 0 {
+			stored.Tags = append([]string(nil), *input.Tags...)
+		}
+	}
+	stored.BeforeUpdate()
+	r.medias[input.ID] = stored
+	return &stored, nil
+}
+
+func (r *MediaRepository) SetMediaProbe(ctx context.Context, media *models.Media) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.medias[media.ID]
+	if !ok {
+		return nil
+	}
+	media.BeforeUpdate()
+	stored.Probe, stored.Duration = media.Probe, media.Duration
+	stored.Width, stored.Height = media.Width, media.Height
+	stored.UpdatedAt = media.UpdatedAt
+	r.medias[media.ID] = stored
+	return nil
+}
+
+// DeleteMedia removes a media with its jobs, key and tus upload.
+func (r *MediaRepository) DeleteMedia(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.medias, id)
+	jobs := r.jobs[:0]
+	for _, job := range r.jobs {
+		if job.MediaID != id {
+			jobs = append(jobs, job)
+		}
+	}
+	r.jobs = jobs
+	for keyID, key := range r.keys {
+		if key.MediaID == id {
+			delete(r.keys, keyID)
+		}
+	}
+	for uploadID, upload := range r.tus {
+		if upload.MediaID == id.Hex() {
+			delete(r.tus, uploadID)
+		}
+	}
+	return nil
+}
+
+// ListMedia applies the same filters as the MongoDB repository, newest
+// first.
+func (r *MediaRepository) ListMedia(ctx context.Context, input media.ListMediaInput) ([]*models.Media, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*models.Media
+	for _, m := range r.medias {
+		if !matchesListInput(m, input) {
+			continue
+		}
+		m := m
+		out = append(out, &m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID.Hex() > out[j].ID.Hex()
+	})
+	return out, nil
+}
+
+func matchesListInput(m models.Media, input media.ListMediaInput) bool {
+	if m.Upload != nil {
+		return false
+	}
+	if input.Keyword != "" && m.Name != input.Keyword {
+		return false
+	}
+	if input.OwnerID != "" && m.OwnerID != input.OwnerID {
+		return false
+	}
+	if input.VideoCodec != "" || input.HDR != nil {
+		var streams []models.VideoStreamInfo
+		if m.Probe != nil {
+			streams = m.Probe.VideoStreams
+		}
+		codecMatch, hdr := input.VideoCodec == "", false
+		for _, s := range streams {
+			if s.Codec == input.VideoCodec {
+				codecMatch = true
+			}
+			if s.HDRFormat != "" {
+				hdr = true
+			}
+		}
+		if !codecMatch {
+			return false
+		}
+		if input.HDR != nil && *input.HDR != hdr {
+			return false
+		}
+	}
+	if input.MinHeight > 0 && m.Height < input.MinHeight {
+		return false
+	}
+	if input.MinDuration > 0 && m.Duration < input.MinDuration {
+		return false
+	}
+	if input.MaxDuration > 0 && m.Duration > input.MaxDuration {
+		return false
+	}
+	return true
+}
+
+func (r *MediaRepository) CreateTranscodeJob(ctx context.Context, transcode *models.TranscodeJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transcode.BeforeCreate()
+	if r.attemptTaken(transcode) {
+		return media.ErrAttemptTaken
+	}
+	r.jobs = append(r.jobs, *transcode)
+	return nil
+}
+
+// attemptTaken mirrors the unique (media_id, attempt) index.
+func (r *MediaRepository) attemptTaken(job *models.TranscodeJob) bool {
+	for _, existing := range r.jobs {
+		if existing.MediaID == job.MediaID && existing.Attempt == job.Attempt {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTranscodeJobByMediaID returns the latest attempt of the media.
+func (r *MediaRepository) GetTranscodeJobByMediaID(ctx context.Context, mediaId string) (*models.TranscodeJob, error) {
+	jobs, err := r.ListTranscodeJobsByMediaID(ctx, mediaId)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return jobs[len(jobs)-1], nil
+}
+
+func (r *MediaRepository) GetTranscodeJob(ctx context.Context, id string) (*models.TranscodeJob, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, job := range r.jobs {
+		if job.ID == oid {
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListTranscodeJobsByMediaID returns the attempts of the media, oldest
+// first.
+func (r *MediaRepository) ListTranscodeJobsByMediaID(ctx context.Context, mediaId string) ([]*models.TranscodeJob, error) {
+	oid, err := primitive.ObjectIDFromHex(mediaId)
+	if err != nil {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*models.TranscodeJob
+	for _, job := range r.jobs {
+		if job.MediaID == oid {
+			job := job
+			out = append(out, &job)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Attempt < out[j].Attempt
+	})
+	return out, nil
+}
+
+// GetTranscodeJobByMessageID returns the latest job of the media started
+// by the message.
+func (r *MediaRepository) GetTranscodeJobByMessageID(ctx context.Context, mediaId, messageId string) (*models.TranscodeJob, error) {
+	oid, err := primitive.ObjectIDFromHex(mediaId)
+	if err != nil {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.jobs) - 1; i >= 0; i-- {
+		if job := r.jobs[i]; job.MediaID == oid && job.MessageID == messageId {
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MediaRepository) UpdateTranscodeJob(ctx context.Context, transcode *models.TranscodeJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.jobs {
+		if r.jobs[i].ID == transcode.ID {
+			transcode.BeforeUpdate()
+			r.jobs[i] = *transcode
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *MediaRepository) UpdateTranscodeJobProgress(ctx context.Context, input media.UpdateTranscodeJobProgressInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.jobs {
+		if r.jobs[i].ID == input.JobID {
+			r.jobs[i].Stage = input.Stage
+			r.jobs[i].Progress = input.Progress
+			r.jobs[i].ETASeconds = input.ETASeconds
+			if input.StageTimings != nil {
+				r.jobs[i].StageTimings = append([]models.StageTiming(nil), input.StageTimings...)
+			}
+			r.jobs[i].BeforeUpdate()
+			return nil
+		}
+	}
+	return nil
+}
+
+// TranscodeJobs returns copies of all jobs in creation order.
+func (r *MediaRepository) TranscodeJobs() []models.TranscodeJob {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]models.TranscodeJob(nil), r.jobs...)
+}
+
+func (r *MediaRepository) CreateMediaKey(ctx context.Context, key *models.MediaKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[key.MediaID]; ok {
+		return ErrDuplicateKey
+	}
+	key.BeforeCreate()
+	r.keys[key.MediaID] = *key
+	return nil
+}
+
+func (r *MediaRepository) GetMediaKeyByMediaID(ctx context.Context, mediaId string) (*models.MediaKey, error) {
+	oid, err := primitive.ObjectIDFromHex(mediaId)
+	if err != nil {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[oid]
+	if !ok {
+		return nil, nil
+	}
+	return &key, nil
+}
+
+// ListPendingOutbox returns due pending messages, oldest first.
+func (r *MediaRepository) ListPendingOutbox(ctx context.Context, limit int64) ([]*models.OutboxMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var out []*models.OutboxMessage
+	for _, msg := range r.outbox {
+		if msg.Status != string(models.OutboxStatusPending) || msg.NextAttemptAt.After(now) {
+			continue
+		}
+		msg := msg
+		out = append(out, &msg)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].NextAttemptAt.Before(out[j].NextAttemptAt)
+	})
+	if limit > 0 && int64(len(out)) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *MediaRepository) MarkOutboxSent(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.outbox {
+		if r.outbox[i].ID == id {
+			now := time.Now().UTC()
+			r.outbox[i].Status = string(models.OutboxStatusSent)
+			r.outbox[i].SentAt = &now
+			r.outbox[i].UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (r *MediaRepository) MarkOutboxFailed(ctx context.Context, id primitive.ObjectID, errMsg string, nextAttemptAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.outbox {
+		if r.outbox[i].ID == id {
+			r.outbox[i].Attempts++
+			r.outbox[i].LastError = errMsg
+			r.outbox[i].NextAttemptAt = nextAttemptAt.UTC()
+			r.outbox[i].UpdatedAt = time.Now().UTC()
+		}
+	}
+	return nil
+}
+
+// Outbox returns copies of all outbox messages in creation order.
+func (r *MediaRepository) Outbox() []models.OutboxMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]models.OutboxMessage(nil), r.outbox...)
+}
+
+func (r *MediaRepository) ClaimTranscodeJob(ctx context.Context, jobID, workerID string, leaseUntil time.Time) (*models.TranscodeJob, error) {
+	return r.updateJob(jobID, func(job *models.TranscodeJob) bool {
+		if job.Status != types.TranscodeJobStatusPending.String() {
+			return false
+		}
+		now := time.Now().UTC()
+		job.Status = types.TranscodeJobStatusProcessing.String()
+		job.WorkerID = workerID
+		job.LeaseUntil = &leaseUntil
+		job.StartedAt = &now
+		return true
+	})
+}
+
+func (r *MediaRepository) RenewTranscodeJobLease(ctx context.Context, jobID, workerID string, leaseUntil time.Time) (*models.TranscodeJob, error) {
+	return r.updateJob(jobID, func(job *models.TranscodeJob) bool {
+		if job.Status != types.TranscodeJobStatusProcessing.String() || job.WorkerID != workerID {
+			return false
+		}
+		job.LeaseUntil = &leaseUntil
+		return true
+	})
+}
+
+func (r *MediaRepository) ListExpiredTranscodeJobs(ctx context.Context, now time.Time, limit int64) ([]*models.TranscodeJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*models.TranscodeJob
+	for _, job := range r.jobs {
+		if leaseExpired(job, now) && (limit <= 0 || int64(len(out)) < limit) {
+			job := job
+			out = append(out, &job)
+		}
+	}
+	return out, nil
+}
+
+func (r *MediaRepository) RequeueExpiredTranscodeJob(ctx context.Context, jobID primitive.ObjectID, now time.Time, msg *models.OutboxMessage) (bool, error) {
+	job, err := r.updateJob(jobID.Hex(), func(job *models.TranscodeJob) bool {
+		if !leaseExpired(*job, now) {
+			return false
+		}
+		job.Status = types.TranscodeJobStatusPending.String()
+		job.LeaseUntil = nil
+		job.WorkerID = ""
+		job.LostLeases++
+		return true
+	})
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msg.BeforeCreate()
+	r.outbox = append(r.outbox, *msg)
+	return true, nil
+}
+
+func (r *MediaRepository) ListStalePendingTranscodeJobs(ctx context.Context, before time.Time, limit int64) ([]*models.TranscodeJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*models.TranscodeJob
+	for _, job := range r.jobs {
+		if pendingStale(job, before) && (limit <= 0 || int64(len(out)) < limit) {
+			job := job
+			out = append(out, &job)
+		}
+	}
+	return out, nil
+}
+
+func (r *MediaRepository) RequeueStalePendingTranscodeJob(ctx context.Context, jobID primitive.ObjectID, before time.Time, msg *models.OutboxMessage) (bool, error) {
+	job, err := r.updateJob(jobID.Hex(), func(job *models.TranscodeJob) bool {
+		return pendingStale(*job, before)
+	})
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msg.BeforeCreate()
+	r.outbox = append(r.outbox, *msg)
+	return true, nil
+}
+
+func (r *MediaRepository) FailExpiredTranscodeJob(ctx context.Context, jobID primitive.ObjectID, now time.Time, errMsg string) (bool, error) {
+	job, err := r.updateJob(jobID.Hex(), func(job *models.TranscodeJob) bool {
+		if !leaseExpired(*job, now) {
+			return false
+		}
+		job.Status = types.TranscodeJobStatusError.String()
+		job.Error = errMsg
+		job.FinishedAt = &now
+		job.LeaseUntil = nil
+		job.LostLeases++
+		return true
+	})
+	return job != nil, err
+}
+
+func (r *MediaRepository) ListExpiredMediaUploads(ctx context.Context, before time.Time, limit int64) ([]*models.Media, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*models.Media
+	for _, media := range r.medias {
+		if uploadExpired(media, before) && (limit <= 0 || int64(len(out)) < limit) {
+			media := media
+			out = append(out, &media)
+		}
+	}
+	return out, nil
+}
+
+func (r *MediaRepository) DeleteExpiredMediaUpload(ctx context.Context, id primitive.ObjectID, before time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media, ok := r.medias[id]
+	if !ok || !uploadExpired(media, before) {
+		return false, nil
+	}
+	delete(r.medias, id)
+	return true, nil
+}
+
+func uploadExpired(media models.Media, before time.Time) bool {
+	return media.Upload != nil && media.Upload.ExpiresAt.Before(before)
+}
+
+// CompleteMediaUpload stores both changes or neither.
+func (r *MediaRepository) CompleteMediaUpload(ctx context.Context, media *models.Media, msg *models.OutboxMessage) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.medias[media.ID]
+	if !ok || stored.Upload == nil {
+		return false, nil
+	}
+	msg.BeforeCreate()
+	stored.Size = media.Size
+	stored.ContentType = media.ContentType
+	stored.Upload = nil
+	stored.BeforeUpdate()
+	r.medias[media.ID] = stored
+	r.outbox = append(r.outbox, *msg)
+	return true, nil
+}
+
+// CreateTranscodeJobWithOutbox stores both documents or neither.
+func (r *MediaRepository) CreateTranscodeJobWithOutbox(ctx context.Context, job *models.TranscodeJob, msg *models.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job.BeforeCreate()
+	msg.BeforeCreate()
+	if r.attemptTaken(job) {
+		return media.ErrAttemptTaken
+	}
+	r.jobs = append(r.jobs, *job)
+	r.outbox = append(r.outbox, *msg)
+	return nil
+}
+
+func (r *MediaRepository) RequestTranscodeJobCancel(ctx context.Context, job *models.TranscodeJob) (*models.TranscodeJob, error) {
+	return r.updateJob(job.ID.Hex(), func(job *models.TranscodeJob) bool {
+		if job.Status != types.TranscodeJobStatusProcessing.String() {
+			return false
+		}
+		job.CancelRequested = true
+		return true
+	})
+}
+
+func (r *MediaRepository) CancelPendingTranscodeJob(ctx context.Context, job *models.TranscodeJob) (*models.TranscodeJob, error) {
+	return r.updateJob(job.ID.Hex(), func(job *models.TranscodeJob) bool {
+		if job.Status != types.TranscodeJobStatusPending.String() {
+			return false
+		}
+		now := time.Now().UTC()
+		job.Status = types.TranscodeJobStatusCancelled.String()
+		job.CancelRequested = true
+		job.FinishedAt = &now
+		return true
+	})
+}
+
+func (r *MediaRepository) CreateTusUpload(ctx context.Context, upload *models.TusUpload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload.BeforeCreate()
+	if _, ok := r.tus[upload.ID]; ok {
+		return ErrDuplicateKey
+	}
+	r.tus[upload.ID] = *upload
+	return nil
+}
+
+func (r *MediaRepository) GetTusUpload(ctx context.Context, id string) (*models.TusUpload, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	upload, ok := r.tus[oid]
+	if !ok {
+		return nil, nil
+	}
+	return &upload, nil
+}
+
+func (r *MediaRepository) UpdateTusUploadOffset(ctx context.Context, upload *models.TusUpload, prevOffset int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tus[upload.ID]
+	if !ok || stored.Offset != prevOffset || stored.LockID != upload.LockID {
+		return false, nil
+	}
+	upload.BeforeUpdate()
+	stored.Offset = upload.Offset
+	stored.Parts = upload.Parts
+	stored.TailPath = upload.TailPath
+	stored.LockID = ""
+	stored.LockedUntil = nil
+	stored.UpdatedAt = upload.UpdatedAt
+	r.tus[upload.ID] = stored
+	return true, nil
+}
+
+func (r *MediaRepository) LockTusUpload(ctx context.Context, id primitive.ObjectID, offset int64, lockID string, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tus[id]
+	if !ok || stored.Offset != offset {
+		return false, nil
+	}
+	if stored.LockID != "" && stored.LockID != lockID && !stored.LockedUntil.Before(time.Now()) {
+		return false, nil
+	}
+	stored.LockID = lockID
+	stored.LockedUntil = &until
+	r.tus[id] = stored
+	return true, nil
+}
+
+func (r *MediaRepository) UnlockTusUpload(ctx context.Context, id primitive.ObjectID, lockID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.tus[id]; ok && stored.LockID == lockID {
+		stored.LockID = ""
+		stored.LockedUntil = nil
+		r.tus[id] = stored
+	}
+	return nil
+}
+
+func (r *MediaRepository) SetTusUploadMedia(ctx context.Context, id primitive.ObjectID, mediaID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if upload, ok := r.tus[id]; ok {
+		upload.MediaID = mediaID
+		upload.BeforeUpdate()
+		r.tus[id] = upload
+	}
+	return nil
+}
+
+func (r *MediaRepository) DeleteTusUpload(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.tus, id)
+	return nil
+}
+
+// updateJob applies fn to the job with jobID and returns a copy of the
+// result, or nil if the job does not exist or fn declined the update.
+func (r *MediaRepository) updateJob(jobID string, fn func(job *models.TranscodeJob) bool) (*models.TranscodeJob, error) {
+	oid, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.jobs {
+		if r.jobs[i].ID != oid {
+			continue
+		}
+		job := r.jobs[i]
+		if !fn(&job) {
+			return nil, nil
+		}
+		job.BeforeUpdate()
+		r.jobs[i] = job
+		return &job, nil
+	}
+	return nil, nil
+}
+
+func pendingStale(job models.TranscodeJob, before time.Time) bool {
+	return job.Status == types.TranscodeJobStatusPending.String() && job.UpdatedAt.Before(before)
+}
+
+func leaseExpired(job models.TranscodeJob, now time.Time) bool {
+	return job.Status == types.TranscodeJobStatusProcessing.String() && (job.LeaseUntil == nil || job.LeaseUntil.Before(now))
+}
@@ -0,0 +1,42 @@
+package inmemory
+
+import "sync"
+
+// Publisher records published messages per queue.
+type Publisher struct {
+	mu       sync.Mutex
+	messages map[string][][]byte
+	err      error
+}
+
+func NewPublisher() *Publisher {
+	return &Publisher{messages: make(map[string][][]byte)}
+}
+
+func (p *Publisher) Publish(queue string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.messages[queue] = append(p.messages[queue], append([]byte(nil), data...))
+	return nil
+}
+
+// FailWith makes subsequent publishes return err, or succeed again when err
+// is nil.
+func (p *Publisher) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = err
+}
+
+// Messages returns the messages published to queue in order.
+func (p *Publisher) Messages(queue string) [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([][]byte(nil), p.messages[queue]...)
+}
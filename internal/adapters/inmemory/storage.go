@@ -0,0 +1,302 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"media-svc/internal/adapters/minio"
+	"media-svc/internal/utils"
+)
+
+type object struct {
+	data        []byte
+	etag        string
+	contentType string
+	modified    time.Time
+}
+
+// Storage is a minio.StorageAdapter keeping objects in memory.
+type Storage struct {
+	bucket string
+
+	mu        sync.RWMutex
+	objects   map[string]object
+	multipart map[string]map[int]object // parts by upload ID
+}
+
+func NewStorage(bucket string) *Storage {
+	return &Storage{
+		bucket:    bucket,
+		objects:   make(map[string]object),
+		multipart: make(map[string]map[int]object),
+	}
+}
+
+var _ minio.StorageAdapter = (*Storage)(nil)
+
+func (s *Storage) UploadDir(ctx context.Context, srcDir, targetDir string) (string, error) {
+	err := filepath.WalkDir(srcDir, func(fullPath string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(srcDir, fullPath)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			return err
+		}
+		s.put(path.Join(filepath.ToSlash(targetDir), filepath.ToSlash(relPath)), data)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return targetDir, nil
+}
+
+func (s *Storage) PutObject(ctx context.Context, objectName string, reader io.Reader, size int64) (string, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", objectName, err)
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return "", fmt.Errorf("failed to upload %s: read %d of %d bytes", objectName, len(data), size)
+	}
+	s.put(objectName, data)
+	return objectName, nil
+}
+
+func (s *Storage) put(objectName string, data []byte) {
+	sum := md5.Sum(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[objectName] = object{
+		data:        data,
+		etag:        hex.EncodeToString(sum[:]),
+		contentType: utils.DetectContentTypeByFileName(objectName),
+		modified:    time.Now().UTC(),
+	}
+}
+
+func (s *Storage) get(objectName string) (object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[objectName]
+	if !ok {
+		return object{}, fmt.Errorf("object %s: %w", objectName, os.ErrNotExist)
+	}
+	return obj, nil
+}
+
+func (s *Storage) GetObject(ctx context.Context, objectName string) ([]byte, error) {
+	obj, err := s.get(objectName)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), obj.data...), nil
+}
+
+func (s *Storage) RemoveObject(ctx context.Context, objectName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, objectName)
+	return nil
+}
+
+func (s *Storage) RemovePrefix(ctx context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	for name := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			delete(s.objects, name)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetObjectReader(ctx context.Context, objectName string) (io.ReadSeekCloser, minio.ObjectInfo, error) {
+	obj, err := s.get(objectName)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+	return readSeekNopCloser{bytes.NewReader(obj.data)}, obj.info(objectName), nil
+}
+
+func (s *Storage) StatObject(ctx context.Context, objectName string) (minio.ObjectInfo, error) {
+	obj, err := s.get(objectName)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	return obj.info(objectName), nil
+}
+
+func (s *Storage) DownloadToFile(ctx context.Context, objectName, localPath string) (minio.ObjectInfo, error) {
+	obj, err := s.get(objectName)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	if err := os.WriteFile(localPath, obj.data, 0644); err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	return obj.info(objectName), nil
+}
+
+func (s *Storage) PresignPutObject(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	return s.presign("PUT", objectName, expiry), nil
+}
+
+func (s *Storage) PresignGetObject(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	return s.presign("GET", objectName, expiry), nil
+}
+
+func (s *Storage) presign(method, objectName string, expiry time.Duration) string {
+	return fmt.Sprintf("memory://%s/%s?method=%s&expires=%d", s.bucket, objectName, method, time.Now().Add(expiry).Unix())
+}
+
+func (s *Storage) NewMultipartUpload(ctx context.Context, objectName string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploadID := fmt.Sprintf("upload-%d", len(s.multipart)+1)
+	s.multipart[uploadID] = make(map[int]object)
+	return uploadID, nil
+}
+
+func (s *Storage) PresignUploadPart(ctx context.Context, objectName, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	return fmt.Sprintf("%s&uploadId=%s&partNumber=%d", s.presign("PUT", objectName, expiry), uploadID, partNumber), nil
+}
+
+// UploadPart stores a part of a multipart upload, as a client does with a
+// presigned part URL.
+func (s *Storage) UploadPart(uploadID string, partNumber int, data []byte) error {
+	sum := md5.Sum(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts, ok := s.multipart[uploadID]
+	if !ok {
+		return fmt.Errorf("upload %s: %w", uploadID, os.ErrNotExist)
+	}
+	parts[partNumber] = object{data: data, etag: hex.EncodeToString(sum[:]), modified: time.Now().UTC()}
+	return nil
+}
+
+func (s *Storage) PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (minio.UploadedPart, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return minio.UploadedPart{}, fmt.Errorf("failed to upload part %d of %s: %w", partNumber, objectName, err)
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return minio.UploadedPart{}, fmt.Errorf("failed to upload part %d of %s: read %d of %d bytes", partNumber, objectName, len(data), size)
+	}
+	if err := s.UploadPart(uploadID, partNumber, data); err != nil {
+		return minio.UploadedPart{}, err
+	}
+	sum := md5.Sum(data)
+	return minio.UploadedPart{PartNumber: partNumber, ETag: hex.EncodeToString(sum[:]), Size: int64(len(data))}, nil
+}
+
+func (s *Storage) ListUploadedParts(ctx context.Context, objectName, uploadID string) ([]minio.UploadedPart, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parts, ok := s.multipart[uploadID]
+	if !ok {
+		return nil, fmt.Errorf("upload %s: %w", uploadID, os.ErrNotExist)
+	}
+	out := make([]minio.UploadedPart, 0, len(parts))
+	for n, part := range parts {
+		out = append(out, minio.UploadedPart{PartNumber: n, ETag: part.etag, Size: int64(len(part.data))})
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].PartNumber < out[b].PartNumber })
+	return out, nil
+}
+
+func (s *Storage) CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []minio.UploadedPart) error {
+	s.mu.Lock()
+	uploaded, ok := s.multipart[uploadID]
+	delete(s.multipart, uploadID)
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("upload %s: %w", uploadID, os.ErrNotExist)
+	}
+
+	var data []byte
+	for _, p := range parts {
+		part, ok := uploaded[p.PartNumber]
+		if !ok {
+			return fmt.Errorf("part %d of upload %s: %w", p.PartNumber, uploadID, os.ErrNotExist)
+		}
+		data = append(data, part.data...)
+	}
+	s.put(objectName, data)
+
+	// Multipart ETags are not the MD5 of the content, as in S3
+	s.mu.Lock()
+	obj := s.objects[objectName]
+	obj.etag = fmt.Sprintf("%s-%d", obj.etag, len(parts))
+	s.objects[objectName] = obj
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Storage) AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.multipart, uploadID)
+	return nil
+}
+
+// Keys returns the names of all stored objects, sorted.
+func (s *Storage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readSeekNopCloser is a stored object opened for reading.
+type readSeekNopCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekNopCloser) Close() error { return nil }
+
+func (o object) info(objectName string) minio.ObjectInfo {
+	return minio.ObjectInfo{
+		Key:          objectName,
+		Size:         int64(len(o.data)),
+		ETag:         o.etag,
+		ContentType:  o.contentType,
+		LastModified: o.modified,
+	}
+}
@@ -0,0 +1,320 @@
+// Package localfs implements minio.StorageAdapter on the local filesystem,
+// for development and CI stacks without an S3 server. Each bucket is a
+// directory under the configured root, and presigned URLs are HMAC-signed
+// links to the REST server, which serves them with NewServer.
+package localfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"media-svc/config"
+	"media-svc/internal/adapters/minio"
+	"media-svc/internal/utils"
+)
+
+type impl struct {
+	dir    string // bucket directory
+	bucket string
+	signer *Signer
+}
+
+// New returns a filesystem storage for bucketName, creating its directory
+// if needed.
+func New(cfg *config.Config, bucketName string) (minio.StorageAdapter, error) {
+	if err := validBucket(bucketName); err != nil {
+		return nil, err
+	}
+	signer, err := NewSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(cfg.Storage.FS.Root, bucketName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bucket directory: %w", err)
+	}
+
+	return &impl{
+		dir:    dir,
+		bucket: bucketName,
+		signer: signer,
+	}, nil
+}
+
+// objectPath maps an object name to its file. Names are cleaned as rooted
+// paths so they cannot escape the bucket directory.
+func (i *impl) objectPath(objectName string) (string, error) {
+	return objectPath(i.dir, objectName)
+}
+
+func objectPath(dir, objectName string) (string, error) {
+	key := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(objectName)), "/")
+	if key == "" {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	return filepath.Join(dir, filepath.FromSlash(key)), nil
+}
+
+func validBucket(bucket string) error {
+	if bucket == "" || bucket == "." || bucket == ".." || strings.ContainsAny(bucket, `/\`) {
+		return fmt.Errorf("invalid bucket name %q", bucket)
+	}
+	return nil
+}
+
+func (i *impl) UploadDir(ctx context.Context, srcDir, targetDir string) (string, error) {
+	err := filepath.WalkDir(srcDir, func(fullPath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(srcDir, fullPath)
+		if err != nil {
+			return err
+		}
+		objectName := filepath.ToSlash(relPath)
+		if targetDir != "" {
+			objectName = path.Join(filepath.ToSlash(targetDir), objectName)
+		}
+
+		file, err := os.Open(fullPath)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", fullPath, err)
+		}
+		defer file.Close()
+
+		if _, err := i.PutObject(ctx, objectName, file, -1); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return targetDir, nil
+}
+
+func (i *impl) PutObject(ctx context.Context, objectName string, reader io.Reader, size int64) (string, error) {
+	dst, err := i.objectPath(objectName)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := writeAtomic(dst, &contextReader{ctx: ctx, r: reader}, size); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", objectName, err)
+	}
+	return objectName, nil
+}
+
+func (i *impl) GetObject(ctx context.Context, objectName string) ([]byte, error) {
+	reader, _, err := i.GetObjectReader(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", objectName, err)
+	}
+	return data, nil
+}
+
+func (i *impl) RemoveObject(ctx context.Context, objectName string) error {
+	dst, err := i.objectPath(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !isNotExist(err) {
+		return fmt.Errorf("failed to remove object %s: %w", objectName, err)
+	}
+	return nil
+}
+
+func (i *impl) RemovePrefix(ctx context.Context, prefix string) error {
+	dir, err := i.objectPath(prefix)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove prefix %s: %w", prefix, err)
+	}
+	return nil
+}
+
+func (i *impl) GetObjectReader(ctx context.Context, objectName string) (io.ReadSeekCloser, minio.ObjectInfo, error) {
+	src, err := i.objectPath(objectName)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, fmt.Errorf("failed to get object %s: %w", objectName, err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, minio.ObjectInfo{}, fmt.Errorf("failed to get object %s: %w", objectName, err)
+	}
+
+	return file, objectInfo(objectName, stat), nil
+}
+
+func (i *impl) StatObject(ctx context.Context, objectName string) (minio.ObjectInfo, error) {
+	src, err := i.objectPath(objectName)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+
+	stat, err := os.Stat(src)
+	if err != nil {
+		return minio.ObjectInfo{}, fmt.Errorf("failed to stat object %s: %w", objectName, err)
+	}
+	return objectInfo(objectName, stat), nil
+}
+
+// DownloadToFile copies an object to localPath through a temporary file,
+// checking the size and the MD5 of the copy against the object read.
+func (i *impl) DownloadToFile(ctx context.Context, objectName, localPath string) (minio.ObjectInfo, error) {
+	reader, info, err := i.GetObjectReader(ctx, objectName)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	defer reader.Close()
+
+	if _, err := writeAtomic(localPath, &contextReader{ctx: ctx, r: reader}, info.Size); err != nil {
+		return minio.ObjectInfo{}, fmt.Errorf("failed to download %s: %w", objectName, err)
+	}
+	return info, nil
+}
+
+func (i *impl) PresignPutObject(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	return i.signer.SignURL("PUT", i.bucket, objectName, expiry)
+}
+
+func (i *impl) PresignGetObject(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	return i.signer.SignURL("GET", i.bucket, objectName, expiry)
+}
+
+// objectInfo describes a stored file. The ETag is derived from the size and
+// modification time, like the weak validators of static file servers, so
+// it changes whenever the object is rewritten without hashing the content.
+func objectInfo(objectName string, stat os.FileInfo) minio.ObjectInfo {
+	return minio.ObjectInfo{
+		Key:          objectName,
+		Size:         stat.Size(),
+		ETag:         fmt.Sprintf("%x-%x", stat.ModTime().UnixNano(), stat.Size()),
+		ContentType:  contentType(objectName),
+		LastModified: stat.ModTime(),
+	}
+}
+
+func contentType(objectName string) string {
+	if ct := utils.DetectContentTypeByFileName(objectName); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// writeAtomic writes r to a temporary file next to dst and renames it into
+// place, so readers never see a partially written object. The file is read
+// back and compared with the MD5 of what was received before the rename,
+// and with a size of 0 or more it must also be exactly size bytes, so a
+// short or corrupted write never replaces an existing object.
+func writeAtomic(dst string, r io.Reader, size int64) (int64, error) {
+	dir := filepath.Dir(dst)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	hash := md5.New()
+	written, err := io.Copy(tmp, io.TeeReader(r, hash))
+	if err != nil {
+		cleanup()
+		return 0, err
+	}
+	if size >= 0 && written != size {
+		cleanup()
+		return written, fmt.Errorf("%w: wrote %d of %d bytes", minio.ErrChecksumMismatch, written, size)
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return 0, err
+	}
+	if err := verifyFile(tmp, hash.Sum(nil)); err != nil {
+		cleanup()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	return written, nil
+}
+
+// verifyFile checks that the content of file has the MD5 sum want.
+func verifyFile(file *os.File, want []byte) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if got := hash.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: wrote MD5 %x, received %x", minio.ErrChecksumMismatch, got, want)
+	}
+	return nil
+}
+
+// contextReader stops a copy once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// isNotExist reports whether err is a missing object.
+func isNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist)
+}
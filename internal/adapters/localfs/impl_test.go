@@ -0,0 +1,100 @@
+package localfs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"media-svc/config"
+	"media-svc/internal/adapters/minio"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	cfg := &config.Config{}
+	cfg.Storage.FS.Root = t.TempDir()
+	cfg.Storage.FS.SigningKey = "test-key"
+	return cfg
+}
+
+func TestPutObjectSizeMismatchKeepsObject(t *testing.T) {
+	ctx := context.Background()
+	storage, err := New(testConfig(t), "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := storage.PutObject(ctx, "videos/a.mp4", strings.NewReader("original"), 8); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	_, err = storage.PutObject(ctx, "videos/a.mp4", strings.NewReader("short"), 8)
+	if !errors.Is(err, minio.ErrChecksumMismatch) {
+		t.Fatalf("PutObject error = %v, want a size mismatch", err)
+	}
+
+	data, err := storage.GetObject(ctx, "videos/a.mp4")
+	if err != nil || string(data) != "original" {
+		t.Errorf("object = %q, %v, want the previous content", data, err)
+	}
+}
+
+func TestDownloadToFile(t *testing.T) {
+	ctx := context.Background()
+	storage, err := New(testConfig(t), "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.PutObject(ctx, "videos/a.mp4", strings.NewReader("video bytes"), -1); err != nil {
+		t.Fatal(err)
+	}
+
+	localPath := filepath.Join(t.TempDir(), "source.mp4")
+	info, err := storage.DownloadToFile(ctx, "videos/a.mp4", localPath)
+	if err != nil {
+		t.Fatalf("DownloadToFile: %v", err)
+	}
+	data, _ := os.ReadFile(localPath)
+	if string(data) != "video bytes" || info.Size != int64(len(data)) {
+		t.Errorf("downloaded %q, info %+v", data, info)
+	}
+}
+
+func TestServerPutTooLarge(t *testing.T) {
+	cfg := testConfig(t)
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.maxSize = 1 << 10
+	signer, _ := NewSigner(cfg)
+	signed, err := signer.SignURL("PUT", "media", "videos/a.mp4", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(signed)
+
+	req := httptest.NewRequest(http.MethodPut, "/media/videos/a.mp4?"+u.RawQuery, &endlessReader{})
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Storage.FS.Root, "media", "videos", "a.mp4")); !os.IsNotExist(err) {
+		t.Errorf("oversized object stored")
+	}
+}
+
+// endlessReader is a body larger than any limit.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -0,0 +1,146 @@
+package localfs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"media-svc/internal/adapters/minio"
+)
+
+// multipartDir holds the parts of unfinished multipart uploads, one
+// directory per upload, inside the bucket directory. Parts are uploaded to
+// presigned URLs of the keys .multipart/{upload id}/{part number}.
+const multipartDir = ".multipart"
+
+func (i *impl) NewMultipartUpload(ctx context.Context, objectName string) (string, error) {
+	if _, err := i.objectPath(objectName); err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	dir, err := i.partsDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to start multipart upload of %s: %w", objectName, err)
+	}
+	return uploadID, nil
+}
+
+func (i *impl) PresignUploadPart(ctx context.Context, objectName, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	if _, err := i.partsDir(uploadID); err != nil {
+		return "", err
+	}
+	return i.signer.SignURL("PUT", i.bucket, path.Join(multipartDir, uploadID, strconv.Itoa(partNumber)), expiry)
+}
+
+func (i *impl) PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (minio.UploadedPart, error) {
+	dir, err := i.partsDir(uploadID)
+	if err != nil {
+		return minio.UploadedPart{}, err
+	}
+
+	dst := filepath.Join(dir, strconv.Itoa(partNumber))
+	if _, err := writeAtomic(dst, &contextReader{ctx: ctx, r: reader}, size); err != nil {
+		return minio.UploadedPart{}, fmt.Errorf("failed to upload part %d of %s: %w", partNumber, objectName, err)
+	}
+
+	stat, err := os.Stat(dst)
+	if err != nil {
+		return minio.UploadedPart{}, err
+	}
+	info := objectInfo(dst, stat)
+	return minio.UploadedPart{PartNumber: partNumber, ETag: info.ETag, Size: info.Size}, nil
+}
+
+func (i *impl) ListUploadedParts(ctx context.Context, objectName, uploadID string) ([]minio.UploadedPart, error) {
+	dir, err := i.partsDir(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts of %s: %w", objectName, err)
+	}
+
+	var parts []minio.UploadedPart
+	for _, entry := range entries {
+		// Skips temporary files of parts still being written
+		n, err := strconv.Atoi(entry.Name())
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list parts of %s: %w", objectName, err)
+		}
+		info := objectInfo(entry.Name(), stat)
+		parts = append(parts, minio.UploadedPart{PartNumber: n, ETag: info.ETag, Size: info.Size})
+	}
+	sort.Slice(parts, func(a, b int) bool { return parts[a].PartNumber < parts[b].PartNumber })
+	return parts, nil
+}
+
+// CompleteMultipartUpload concatenates parts into the object and removes
+// the upload directory.
+func (i *impl) CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []minio.UploadedPart) error {
+	dir, err := i.partsDir(uploadID)
+	if err != nil {
+		return err
+	}
+	dst, err := i.objectPath(objectName)
+	if err != nil {
+		return err
+	}
+
+	readers := make([]io.Reader, 0, len(parts))
+	for _, p := range parts {
+		file, err := os.Open(filepath.Join(dir, strconv.Itoa(p.PartNumber)))
+		if err != nil {
+			return fmt.Errorf("failed to complete multipart upload of %s: %w", objectName, err)
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+
+	if _, err := writeAtomic(dst, &contextReader{ctx: ctx, r: io.MultiReader(readers...)}, -1); err != nil {
+		return fmt.Errorf("failed to complete multipart upload of %s: %w", objectName, err)
+	}
+	return os.RemoveAll(dir)
+}
+
+func (i *impl) AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error {
+	dir, err := i.partsDir(uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to abort multipart upload of %s: %w", objectName, err)
+	}
+	return nil
+}
+
+// partsDir returns the directory of an upload. Upload IDs are hex strings
+// created by NewMultipartUpload, so they cannot escape the bucket.
+func (i *impl) partsDir(uploadID string) (string, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", fmt.Errorf("invalid upload id %q", uploadID)
+	}
+	return filepath.Join(i.dir, multipartDir, uploadID), nil
+}
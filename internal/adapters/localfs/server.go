@@ -0,0 +1,107 @@
+package localfs
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"media-svc/config"
+)
+
+// maxPutSize is the largest object a presigned PUT accepts, the single PUT
+// limit of S3.
+const maxPutSize = 5 << 30
+
+// Server serves presigned GET and PUT requests for objects of the
+// filesystem storage. Request paths are /{bucket}/{object}, relative to
+// RoutePrefix.
+type Server struct {
+	root    string
+	signer  *Signer
+	maxSize int64 // largest accepted PUT body
+}
+
+// NewServer returns the handler for presigned URLs created by the
+// filesystem storage.
+func NewServer(cfg *config.Config) (*Server, error) {
+	signer, err := NewSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		root:    cfg.Storage.FS.Root,
+		signer:  signer,
+		maxSize: maxPutSize,
+	}, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, objectName, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !ok || validBucket(bucket) != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodPut {
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	if err := s.signer.Verify(method, bucket, objectName, query.Get("expires"), query.Get("signature")); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	dst, err := objectPath(filepath.Join(s.root, bucket), objectName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if method == http.MethodPut {
+		body := http.MaxBytesReader(w, r.Body, s.maxSize)
+		if _, err := writeAtomic(dst, body, r.ContentLength); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "object too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			log.Printf("localfs: write %s/%s: %v", bucket, objectName, err)
+			http.Error(w, "write failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	file, err := os.Open(dst)
+	if err != nil {
+		if isNotExist(err) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "read failed", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	info := objectInfo(objectName, stat)
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("ETag", `"`+info.ETag+`"`)
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), file)
+}
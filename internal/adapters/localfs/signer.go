@@ -0,0 +1,84 @@
+package localfs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"media-svc/config"
+)
+
+// RoutePrefix is the path the REST server mounts NewServer on.
+const RoutePrefix = "/v1/storage"
+
+var (
+	// ErrSignatureInvalid is returned for a presigned URL whose signature
+	// does not match.
+	ErrSignatureInvalid = errors.New("invalid signature")
+	// ErrSignatureExpired is returned for a presigned URL past its expiry.
+	ErrSignatureExpired = errors.New("signature expired")
+)
+
+// Signer creates and checks presigned object URLs.
+type Signer struct {
+	key     []byte
+	baseURL string
+}
+
+// NewSigner returns the signer configured in storage.fs.
+func NewSigner(cfg *config.Config) (*Signer, error) {
+	if cfg.Storage.FS.SigningKey == "" {
+		return nil, fmt.Errorf("storage.fs.signing_key is required for the fs storage driver")
+	}
+	return &Signer{
+		key:     []byte(cfg.Storage.FS.SigningKey),
+		baseURL: strings.TrimSuffix(cfg.Storage.FS.BaseURL, "/"),
+	}, nil
+}
+
+// SignURL returns a URL that grants method on the object until expiry.
+func (s *Signer) SignURL(method, bucket, objectName string, expiry time.Duration) (string, error) {
+	if err := validBucket(bucket); err != nil {
+		return "", err
+	}
+	key := strings.TrimPrefix(path.Clean("/"+objectName), "/")
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.signature(method, bucket, key, expires))
+
+	u := url.URL{Path: path.Join(RoutePrefix, bucket, key)}
+	return s.baseURL + u.EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Verify checks the expires and signature query values of a request for
+// method on the object.
+func (s *Signer) Verify(method, bucket, objectName, expires, signature string) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	key := strings.TrimPrefix(path.Clean("/"+objectName), "/")
+	expected := s.signature(method, bucket, key, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureInvalid
+	}
+	if time.Now().Unix() > unix {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+func (s *Signer) signature(method, bucket, key, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(method + "\n" + bucket + "\n" + key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,214 @@
+package minio
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// ErrChecksumMismatch is returned when a downloaded object does not match
+// the size or checksum reported by storage.
+var ErrChecksumMismatch = errors.New("object checksum mismatch")
+
+// downloadAttempts is the number of ranged GETs DownloadToFile makes before
+// giving up on an object.
+const downloadAttempts = 5
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	ContentType  string
+	LastModified time.Time
+}
+
+func toObjectInfo(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}
+}
+
+func (i *impl) StatObject(ctx context.Context, objectName string) (ObjectInfo, error) {
+	info, err := i.client.StatObject(ctx, i.bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat object %s: %w", objectName, notExist(err))
+	}
+	return toObjectInfo(info), nil
+}
+
+// notExist adds os.ErrNotExist to the error of a missing object, the way the
+// other adapters report it.
+func notExist(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return fmt.Errorf("%w: %w", os.ErrNotExist, err)
+	}
+	return err
+}
+
+// GetObjectReader opens an object for streaming. Seeking moves to a ranged
+// GET. The caller must close the returned reader.
+func (i *impl) GetObjectReader(ctx context.Context, objectName string) (io.ReadSeekCloser, ObjectInfo, error) {
+	obj, err := i.client.GetObject(ctx, i.bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, ObjectInfo{}, fmt.Errorf("failed to get object %s: %w", objectName, err)
+	}
+
+	// The request is only sent on the first read or stat.
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, ObjectInfo{}, fmt.Errorf("failed to get object %s: %w", objectName, notExist(err))
+	}
+
+	return obj, toObjectInfo(info), nil
+}
+
+// DownloadToFile streams an object to localPath without buffering it in
+// memory. Transfers interrupted within the call resume with ranged GETs
+// pinned to the object's ETag. A partial file of the same object version
+// at localPath is continued too, but the transcode worker downloads into a
+// new scratch directory per attempt, so its retries start over. The result
+// is verified against the object size and, for single-part uploads, the
+// MD5 ETag before it is moved to localPath.
+func (i *impl) DownloadToFile(ctx context.Context, objectName, localPath string) (ObjectInfo, error) {
+	info, err := i.StatObject(ctx, objectName)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	partPath := localPath + ".part"
+	etagPath := partPath + ".etag"
+
+	// Only continue a partial file of the same object version.
+	if stored, err := os.ReadFile(etagPath); err != nil || string(stored) != info.ETag {
+		os.Remove(partPath)
+		if err := os.WriteFile(etagPath, []byte(info.ETag), 0644); err != nil {
+			return ObjectInfo{}, fmt.Errorf("failed to write %s: %w", etagPath, err)
+		}
+	}
+
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to open %s: %w", partPath, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat %s: %w", partPath, err)
+	}
+	offset := stat.Size()
+	if offset > info.Size {
+		if err := file.Truncate(0); err != nil {
+			return ObjectInfo{}, fmt.Errorf("failed to truncate %s: %w", partPath, err)
+		}
+		offset = 0
+	}
+
+	for attempt := 1; offset < info.Size; attempt++ {
+		n, err := i.downloadRange(ctx, objectName, info.ETag, offset, file)
+		offset += n
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil || attempt >= downloadAttempts {
+			return ObjectInfo{}, fmt.Errorf("failed to download %s at byte %d: %w", objectName, offset, err)
+		}
+		log.Printf("download %s interrupted at byte %d (attempt %d): %v", objectName, offset, attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return ObjectInfo{}, ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to close %s: %w", partPath, err)
+	}
+
+	if err := verifyDownload(partPath, info); err != nil {
+		os.Remove(partPath)
+		os.Remove(etagPath)
+		return ObjectInfo{}, err
+	}
+
+	if err := os.Rename(partPath, localPath); err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to move %s: %w", partPath, err)
+	}
+	os.Remove(etagPath)
+
+	return info, nil
+}
+
+// downloadRange copies the object from offset to w and returns the number
+// of bytes written.
+func (i *impl) downloadRange(ctx context.Context, objectName, etag string, offset int64, w io.Writer) (int64, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetMatchETag(etag); err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		if err := opts.SetRange(offset, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	obj, err := i.client.GetObject(ctx, i.bucket, objectName, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer obj.Close()
+
+	return io.Copy(w, obj)
+}
+
+// verifyDownload checks the size of a downloaded file and, when the ETag
+// is the MD5 of the content, its checksum. Multipart ETags are not content
+// hashes, so only the size is checked for them.
+func verifyDownload(path string, info ObjectInfo) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	etag := strings.Trim(info.ETag, `"`)
+	if len(etag) != md5.Size*2 || strings.Contains(etag, "-") {
+		stat, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if stat.Size() != info.Size {
+			return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrChecksumMismatch, info.Key, stat.Size(), info.Size)
+		}
+		return nil
+	}
+
+	hash := md5.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if size != info.Size {
+		return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrChecksumMismatch, info.Key, size, info.Size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, etag) {
+		return fmt.Errorf("%w: %s has MD5 %s, expected %s", ErrChecksumMismatch, info.Key, sum, etag)
+	}
+	return nil
+}
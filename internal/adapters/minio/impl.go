@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -101,6 +102,8 @@ func getContentType(filename string) string {
 		return "application/vnd.apple.mpegurl"
 	case ".ts":
 		return "video/MP2T"
+	case ".vtt":
+		return "text/vtt"
 	default:
 		if ct := mime.TypeByExtension(ext); ct != "" {
 			return ct
@@ -197,6 +200,48 @@ func (i *impl) GetObject(ctx context.Context, objectName string) ([]byte, error)
 	return data, nil
 }
 
+func (i *impl) RemoveObject(ctx context.Context, objectName string) error {
+	if err := i.client.RemoveObject(ctx, i.bucket, objectName, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to remove object %s: %w", objectName, err)
+	}
+	return nil
+}
+
+// RemovePrefix removes every object under prefix. Listing stops at the
+// first error, but the removal results are drained either way, so the
+// goroutines of the client end.
+func (i *impl) RemovePrefix(ctx context.Context, prefix string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	objects := make(chan minio.ObjectInfo)
+	var listErr error
+	go func() {
+		defer close(objects)
+		for object := range i.client.ListObjects(ctx, i.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				listErr = fmt.Errorf("failed to list objects under %s: %w", prefix, object.Err)
+				return
+			}
+			objects <- object
+		}
+	}()
+
+	var removeErr error
+	for err := range i.client.RemoveObjects(ctx, i.bucket, objects, minio.RemoveObjectsOptions{}) {
+		if removeErr == nil {
+			removeErr = fmt.Errorf("failed to remove object %s: %w", err.ObjectName, err.Err)
+		}
+	}
+	// The listing goroutine is done once RemoveObjects has consumed every
+	// object and closed its results
+	if removeErr != nil {
+		return removeErr
+	}
+	return listErr
+}
+
 func (i *impl) PresignPutObject(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
 	url, err := i.client.PresignedPutObject(ctx, i.bucket, objectName, expiry)
 	if err != nil {
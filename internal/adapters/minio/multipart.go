@@ -0,0 +1,102 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// UploadedPart is a part of a multipart upload that reached storage.
+type UploadedPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+// NewMultipartUpload starts a multipart upload of objectName and returns
+// its upload ID. Clients upload the parts to presigned URLs.
+func (i *impl) NewMultipartUpload(ctx context.Context, objectName string) (string, error) {
+	core := minio.Core{Client: i.client}
+	uploadID, err := core.NewMultipartUpload(ctx, i.bucket, objectName, minio.PutObjectOptions{
+		ContentType: getContentType(objectName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload of %s: %w", objectName, err)
+	}
+	return uploadID, nil
+}
+
+func (i *impl) PresignUploadPart(ctx context.Context, objectName, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("partNumber", strconv.Itoa(partNumber))
+	params.Set("uploadId", uploadID)
+
+	u, err := i.client.Presign(ctx, http.MethodPut, i.bucket, objectName, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign part %d of %s: %w", partNumber, objectName, err)
+	}
+	return u.String(), nil
+}
+
+// PutObjectPart uploads a part of a multipart upload from the server, for
+// uploads the server receives itself.
+func (i *impl) PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (UploadedPart, error) {
+	core := minio.Core{Client: i.client}
+	part, err := core.PutObjectPart(ctx, i.bucket, objectName, uploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return UploadedPart{}, fmt.Errorf("failed to upload part %d of %s: %w", partNumber, objectName, err)
+	}
+	return UploadedPart{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size}, nil
+}
+
+// ListUploadedParts returns the parts uploaded so far, ordered by part
+// number.
+func (i *impl) ListUploadedParts(ctx context.Context, objectName, uploadID string) ([]UploadedPart, error) {
+	core := minio.Core{Client: i.client}
+
+	var (
+		parts  []UploadedPart
+		marker int
+	)
+	for {
+		res, err := core.ListObjectParts(ctx, i.bucket, objectName, uploadID, marker, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list parts of %s: %w", objectName, err)
+		}
+		for _, p := range res.ObjectParts {
+			parts = append(parts, UploadedPart{PartNumber: p.PartNumber, ETag: p.ETag, Size: p.Size})
+		}
+		if !res.IsTruncated {
+			return parts, nil
+		}
+		marker = res.NextPartNumberMarker
+	}
+}
+
+// CompleteMultipartUpload assembles parts into the object.
+func (i *impl) CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []UploadedPart) error {
+	core := minio.Core{Client: i.client}
+
+	complete := make([]minio.CompletePart, 0, len(parts))
+	for _, p := range parts {
+		complete = append(complete, minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+	if _, err := core.CompleteMultipartUpload(ctx, i.bucket, objectName, uploadID, complete, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload of %s: %w", objectName, err)
+	}
+	return nil
+}
+
+func (i *impl) AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error {
+	core := minio.Core{Client: i.client}
+	if err := core.AbortMultipartUpload(ctx, i.bucket, objectName, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload of %s: %w", objectName, err)
+	}
+	return nil
+}
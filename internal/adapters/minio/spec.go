@@ -10,6 +10,19 @@ type StorageAdapter interface {
 	UploadDir(ctx context.Context, srcDir, targetDir string) (string, error)
 	PutObject(ctx context.Context, objectName string, reader io.Reader, size int64) (string, error)
 	GetObject(ctx context.Context, objectName string) ([]byte, error)
+	RemoveObject(ctx context.Context, objectName string) error
+	// RemovePrefix removes every object under the directory prefix.
+	RemovePrefix(ctx context.Context, prefix string) error
+	GetObjectReader(ctx context.Context, objectName string) (io.ReadSeekCloser, ObjectInfo, error)
+	StatObject(ctx context.Context, objectName string) (ObjectInfo, error)
+	DownloadToFile(ctx context.Context, objectName, localPath string) (ObjectInfo, error)
 	PresignPutObject(ctx context.Context, objectName string, expiry time.Duration) (string, error)
 	PresignGetObject(ctx context.Context, objectName string, expiry time.Duration) (string, error)
+
+	NewMultipartUpload(ctx context.Context, objectName string) (string, error)
+	PresignUploadPart(ctx context.Context, objectName, uploadID string, partNumber int, expiry time.Duration) (string, error)
+	PutObjectPart(ctx context.Context, objectName, uploadID string, partNumber int, reader io.Reader, size int64) (UploadedPart, error)
+	ListUploadedParts(ctx context.Context, objectName, uploadID string) ([]UploadedPart, error)
+	CompleteMultipartUpload(ctx context.Context, objectName, uploadID string, parts []UploadedPart) error
+	AbortMultipartUpload(ctx context.Context, objectName, uploadID string) error
 }
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (repo *AuthRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	key.BeforeCreate()
+	return repo.apiKeyCol.InsertOne(ctx, *key)
+}
+
+// GetAPIKeyByHash returns the key with the given hash, revoked or not, or
+// nil.
+func (repo *AuthRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+
+	key, err := repo.apiKeyCol.FindOne(ctx, bson.M{
+		"hash": hash,
+	}, options.FindOne())
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns the keys of an owner, or of every owner when ownerID
+// is empty, newest first.
+func (repo *AuthRepository) ListAPIKeys(ctx context.Context, ownerID string) ([]*models.APIKey, error) {
+	filter := bson.M{}
+	if ownerID != "" {
+		filter["owner_id"] = ownerID
+	}
+	return repo.apiKeyCol.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": -1}), nil)
+}
+
+// RevokeAPIKey marks a key revoked. It returns false if there is no such
+// key or it was already revoked.
+func (repo *AuthRepository) RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+
+	_, err := repo.apiKeyCol.FindOneAndUpdate(ctx, bson.M{
+		"_id":        id,
+		"revoked_at": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{
+			"revoked_at": at,
+			"updated_at": time.Now().UTC(),
+		},
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
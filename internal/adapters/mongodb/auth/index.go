@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	IndexAPIKeyHash  = "api_key_hash"
+	IndexAPIKeyOwner = "api_key_owner_id"
+)
+
+func GetAPIKeyIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"hash": 1,
+			},
+			Options: options.Index().SetName(IndexAPIKeyHash).SetUnique(true),
+		},
+		{
+			Keys: bson.M{
+				"owner_id": 1,
+			},
+			Options: options.Index().SetName(IndexAPIKeyOwner),
+		},
+	}
+}
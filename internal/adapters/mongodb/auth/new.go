@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"media-svc/internal/models"
+
+	mongodb "github.com/dtome123/go-mongo-generic"
+)
+
+type AuthRepository struct {
+	db        *mongodb.Database
+	apiKeyCol mongodb.Collection[models.APIKey]
+}
+
+func NewAuthRepository(db *mongodb.Database) *AuthRepository {
+
+	apiKeyCol := mongodb.NewCollection[models.APIKey](db)
+	apiKeyCol.EnsureIndexes(GetAPIKeyIndexes())
+
+	return &AuthRepository{
+		db:        db,
+		apiKeyCol: apiKeyCol,
+	}
+}
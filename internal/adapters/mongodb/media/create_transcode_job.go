@@ -2,13 +2,30 @@ package media
 
 import (
 	"context"
+	"errors"
 	"media-svc/internal/models"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrAttemptTaken is returned when another job of the media already has the
+// attempt number, e.g. because two attempts were started at the same time.
+var ErrAttemptTaken = errors.New("transcode job attempt already taken")
+
 func (repo *MediaRepository) CreateTranscodeJob(ctx context.Context, transcode *models.TranscodeJob) error {
 
 	transcode.BeforeCreate()
 
 	err := repo.transcodeJobCol.InsertOne(ctx, *transcode)
+	return attemptConflict(err)
+}
+
+// attemptConflict maps a violation of the unique (media_id, attempt) index to
+// ErrAttemptTaken.
+func attemptConflict(err error) error {
+	if mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), IndexTranscodeJobAttempt) {
+		return ErrAttemptTaken
+	}
 	return err
 }
@@ -0,0 +1,31 @@
+package media
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeleteMedia removes a media together with its transcode jobs, content
+// key and the tus upload it was created from, in one transaction.
+func (repo *MediaRepository) DeleteMedia(ctx context.Context, id primitive.ObjectID) error {
+
+	return repo.db.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if err := repo.mediaCol.Delete(sc, bson.M{"_id": id}, options.Delete()); err != nil {
+			return nil, err
+		}
+		if err := repo.transcodeJobCol.Delete(sc, bson.M{"media_id": id}, options.Delete()); err != nil {
+			return nil, err
+		}
+		if err := repo.mediaKeyCol.Delete(sc, bson.M{"media_id": id}, options.Delete()); err != nil {
+			return nil, err
+		}
+		if err := repo.tusUploadCol.Delete(sc, bson.M{"media_id": id.Hex()}, options.Delete()); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+}
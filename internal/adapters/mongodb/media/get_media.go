@@ -14,7 +14,8 @@ func (svc *MediaRepository) GetMedia(ctx context.Context, id string) (*models.Me
 
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, err
+		// A malformed id matches no document
+		return nil, nil
 	}
 
 	media, err := svc.mediaCol.FindOne(ctx, bson.M{
@@ -31,3 +32,22 @@ func (svc *MediaRepository) GetMedia(ctx context.Context, id string) (*models.Me
 
 	return media, nil
 }
+
+// GetMediaByStreamPath returns the media whose transcode output has the
+// master playlist filePath, or nil.
+func (svc *MediaRepository) GetMediaByStreamPath(ctx context.Context, filePath string) (*models.Media, error) {
+
+	media, err := svc.mediaCol.FindOne(ctx, bson.M{
+		"transcode_source.file_path": filePath,
+	}, options.FindOne().SetHint(IndexMediaStreamPath))
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return media, nil
+}
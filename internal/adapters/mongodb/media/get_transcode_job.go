@@ -10,16 +10,40 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// GetTranscodeJobByMediaID returns the latest attempt of the media, or nil if
+// it has never been transcoded.
 func (svc *MediaRepository) GetTranscodeJobByMediaID(ctx context.Context, mediaId string) (*models.TranscodeJob, error) {
 
 	oid, err := primitive.ObjectIDFromHex(mediaId)
 	if err != nil {
-		return nil, err
+		return nil, nil
 	}
 
 	model, err := svc.transcodeJobCol.FindOne(ctx, bson.M{
 		"media_id": oid,
-	}, options.FindOne().SetHint(IndexTranscodeJobMediaID))
+	}, options.FindOne().SetSort(bson.D{{Key: "attempt", Value: -1}, {Key: "_id", Value: -1}}).SetHint(IndexTranscodeJobAttempt))
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return model, nil
+}
+
+func (svc *MediaRepository) GetTranscodeJob(ctx context.Context, id string) (*models.TranscodeJob, error) {
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	model, err := svc.transcodeJobCol.FindOne(ctx, bson.M{
+		"_id": oid,
+	}, options.FindOne().SetHint("_id_"))
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -31,3 +55,22 @@ func (svc *MediaRepository) GetTranscodeJobByMediaID(ctx context.Context, mediaI
 
 	return model, nil
 }
+
+// ListTranscodeJobsByMediaID returns every attempt of the media, oldest
+// first.
+func (svc *MediaRepository) ListTranscodeJobsByMediaID(ctx context.Context, mediaId string) ([]*models.TranscodeJob, error) {
+
+	oid, err := primitive.ObjectIDFromHex(mediaId)
+	if err != nil {
+		return nil, nil
+	}
+
+	jobs, err := svc.transcodeJobCol.Find(ctx, bson.M{
+		"media_id": oid,
+	}, options.Find().SetSort(bson.D{{Key: "attempt", Value: 1}, {Key: "_id", Value: 1}}).SetHint(IndexTranscodeJobAttempt), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,36 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTranscodeJobByMessageID returns the latest job of the media started by
+// the broker message messageId, or nil if there is none.
+func (svc *MediaRepository) GetTranscodeJobByMessageID(ctx context.Context, mediaId, messageId string) (*models.TranscodeJob, error) {
+
+	oid, err := primitive.ObjectIDFromHex(mediaId)
+	if err != nil {
+		return nil, nil
+	}
+
+	model, err := svc.transcodeJobCol.FindOne(ctx, bson.M{
+		"media_id":   oid,
+		"message_id": messageId,
+	}, options.FindOne().SetSort(bson.M{"_id": -1}).SetHint(IndexTranscodeJobMessage))
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return model, nil
+}
@@ -8,10 +8,45 @@ import (
 
 const (
 	IndexTranscodeJobMediaID = "transcode_job_media_id"
+	IndexMediaVideoCodec     = "media_video_codec"
+	IndexMediaStreamPath     = "media_transcode_source_file_path"
+	IndexMediaOwnerID        = "media_owner_id"
+	IndexMediaKeyMediaID     = "media_key_media_id"
+	IndexOutboxPending       = "outbox_status_next_attempt_at"
+	IndexTranscodeJobMessage = "transcode_job_media_id_message_id"
+	IndexTranscodeJobAttempt = "transcode_job_media_id_attempt"
+	IndexTranscodeJobLease   = "transcode_job_status_lease_until"
+	IndexTranscodeJobPending = "transcode_job_status_updated_at"
+	IndexMediaUploadExpiry   = "media_upload_expires_at"
 )
 
 func GetMediaIndexes() []mongo.IndexModel {
-	return []mongo.IndexModel{}
+	return []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"probe.video_streams.codec": 1,
+			},
+			Options: options.Index().SetName(IndexMediaVideoCodec),
+		},
+		{
+			Keys: bson.M{
+				"transcode_source.file_path": 1,
+			},
+			Options: options.Index().SetName(IndexMediaStreamPath),
+		},
+		{
+			Keys: bson.M{
+				"owner_id": 1,
+			},
+			Options: options.Index().SetName(IndexMediaOwnerID),
+		},
+		{
+			Keys: bson.M{
+				"upload.expires_at": 1,
+			},
+			Options: options.Index().SetName(IndexMediaUploadExpiry).SetSparse(true),
+		},
+	}
 }
 
 func GetTranscodeJobIndexes() []mongo.IndexModel {
@@ -22,5 +57,56 @@ func GetTranscodeJobIndexes() []mongo.IndexModel {
 			},
 			Options: options.Index().SetName(IndexTranscodeJobMediaID),
 		},
+		{
+			Keys: bson.D{
+				{Key: "media_id", Value: 1},
+				{Key: "message_id", Value: 1},
+			},
+			Options: options.Index().SetName(IndexTranscodeJobMessage),
+		},
+		{
+			Keys: bson.D{
+				{Key: "media_id", Value: 1},
+				{Key: "attempt", Value: 1},
+			},
+			Options: options.Index().SetName(IndexTranscodeJobAttempt).SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "lease_until", Value: 1},
+			},
+			Options: options.Index().SetName(IndexTranscodeJobLease),
+		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "updated_at", Value: 1},
+			},
+			Options: options.Index().SetName(IndexTranscodeJobPending),
+		},
+	}
+}
+
+func GetMediaKeyIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"media_id": 1,
+			},
+			Options: options.Index().SetName(IndexMediaKeyMediaID).SetUnique(true),
+		},
+	}
+}
+
+func GetOutboxIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "next_attempt_at", Value: 1},
+			},
+			Options: options.Index().SetName(IndexOutboxPending),
+		},
 	}
 }
@@ -9,15 +9,48 @@ import (
 )
 
 type ListMediaInput struct {
-	Keyword string
+	Keyword     string
+	VideoCodec  string  // Only media whose source has a video stream with this codec
+	HDR         *bool   // Only HDR (true) or SDR (false) sources
+	MinHeight   int     // Only media at least this tall
+	MinDuration float64 // Only media at least this long, in seconds
+	MaxDuration float64 // Only media at most this long, in seconds
+	OwnerID     string  // Only media of this owner
 }
 
 func (svc *MediaRepository) ListMedia(ctx context.Context, input ListMediaInput) ([]*models.Media, error) {
 
-	filter := bson.M{}
+	// Uploads that were not completed have nothing to show yet
+	filter := bson.M{"upload": bson.M{"$exists": false}}
 	if input.Keyword != "" {
 		filter["name"] = input.Keyword
 	}
+	if input.OwnerID != "" {
+		filter["owner_id"] = input.OwnerID
+	}
+	if input.VideoCodec != "" {
+		filter["probe.video_streams.codec"] = input.VideoCodec
+	}
+	if input.HDR != nil {
+		if *input.HDR {
+			filter["probe.video_streams.hdr_format"] = bson.M{"$exists": true}
+		} else {
+			filter["probe.video_streams.hdr_format"] = bson.M{"$exists": false}
+		}
+	}
+	if input.MinHeight > 0 {
+		filter["height"] = bson.M{"$gte": input.MinHeight}
+	}
+	duration := bson.M{}
+	if input.MinDuration > 0 {
+		duration["$gte"] = input.MinDuration
+	}
+	if input.MaxDuration > 0 {
+		duration["$lte"] = input.MaxDuration
+	}
+	if len(duration) > 0 {
+		filter["duration"] = duration
+	}
 
 	medias, err := svc.mediaCol.Find(
 		ctx,
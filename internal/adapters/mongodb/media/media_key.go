@@ -0,0 +1,41 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (repo *MediaRepository) CreateMediaKey(ctx context.Context, key *models.MediaKey) error {
+
+	key.BeforeCreate()
+
+	err := repo.mediaKeyCol.InsertOne(ctx, *key)
+	return err
+}
+
+func (repo *MediaRepository) GetMediaKeyByMediaID(ctx context.Context, mediaId string) (*models.MediaKey, error) {
+
+	oid, err := primitive.ObjectIDFromHex(mediaId)
+	if err != nil {
+		return nil, nil
+	}
+
+	model, err := repo.mediaKeyCol.FindOne(ctx, bson.M{
+		"media_id": oid,
+	}, options.FindOne().SetHint(IndexMediaKeyMediaID))
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return model, nil
+}
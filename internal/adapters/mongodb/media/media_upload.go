@@ -0,0 +1,78 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CompleteMediaUpload removes the pending upload of a media, stores the
+// size and content type found in storage, and inserts the message that
+// starts its transcode, in one transaction. It returns false if the upload
+// was already completed.
+func (repo *MediaRepository) CompleteMediaUpload(ctx context.Context, media *models.Media, msg *models.OutboxMessage) (bool, error) {
+
+	media.BeforeUpdate()
+	msg.BeforeCreate()
+
+	completed := false
+	err := repo.db.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := repo.mediaCol.FindOneAndUpdate(sc, bson.M{
+			"_id":    media.ID,
+			"upload": bson.M{"$exists": true},
+		}, bson.M{
+			"$set": bson.M{
+				"size":         media.Size,
+				"content_type": media.ContentType,
+				"updated_at":   time.Now().UTC(),
+			},
+			"$unset": bson.M{"upload": ""},
+		})
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if err := repo.outboxCol.InsertOne(sc, *msg); err != nil {
+			return nil, err
+		}
+		completed = true
+		return nil, nil
+	})
+	return completed, err
+}
+
+// ListExpiredMediaUploads returns up to limit media whose pending upload
+// expired before the given time.
+func (repo *MediaRepository) ListExpiredMediaUploads(ctx context.Context, before time.Time, limit int64) ([]*models.Media, error) {
+
+	medias, err := repo.mediaCol.Find(ctx, bson.M{
+		"upload.expires_at": bson.M{"$lt": before.UTC()},
+	}, options.Find().SetLimit(limit).SetHint(IndexMediaUploadExpiry), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return medias, nil
+}
+
+// DeleteExpiredMediaUpload removes a media whose pending upload expired
+// before the given time. It returns false if the upload was completed in
+// the meantime.
+func (repo *MediaRepository) DeleteExpiredMediaUpload(ctx context.Context, id primitive.ObjectID, before time.Time) (bool, error) {
+
+	res, err := repo.mediaCol.GetCollection().DeleteOne(ctx, bson.M{
+		"_id":               id,
+		"upload.expires_at": bson.M{"$lt": before.UTC()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
@@ -6,9 +6,18 @@ import (
 	mongodb "github.com/dtome123/go-mongo-generic"
 )
 
+// MediaRepository stores media and their jobs, keys, outbox messages and
+// uploads. Several of its writes run in multi-document transactions, so the
+// database must be a replica set or sharded cluster; a standalone server
+// fails them with "Transaction numbers are only allowed on a replica set
+// member or mongos".
 type MediaRepository struct {
+	db              *mongodb.Database
 	mediaCol        mongodb.Collection[models.Media]
 	transcodeJobCol mongodb.Collection[models.TranscodeJob]
+	mediaKeyCol     mongodb.Collection[models.MediaKey]
+	outboxCol       mongodb.Collection[models.OutboxMessage]
+	tusUploadCol    mongodb.Collection[models.TusUpload]
 }
 
 func NewMediaRepository(db *mongodb.Database) *MediaRepository {
@@ -19,8 +28,20 @@ func NewMediaRepository(db *mongodb.Database) *MediaRepository {
 	transcodeJobCol := mongodb.NewCollection[models.TranscodeJob](db)
 	transcodeJobCol.EnsureIndexes(GetTranscodeJobIndexes())
 
+	mediaKeyCol := mongodb.NewCollection[models.MediaKey](db)
+	mediaKeyCol.EnsureIndexes(GetMediaKeyIndexes())
+
+	outboxCol := mongodb.NewCollection[models.OutboxMessage](db)
+	outboxCol.EnsureIndexes(GetOutboxIndexes())
+
+	tusUploadCol := mongodb.NewCollection[models.TusUpload](db)
+
 	return &MediaRepository{
+		db:              db,
 		mediaCol:        mediaCol,
 		transcodeJobCol: transcodeJobCol,
+		mediaKeyCol:     mediaKeyCol,
+		outboxCol:       outboxCol,
+		tusUploadCol:    tusUploadCol,
 	}
 }
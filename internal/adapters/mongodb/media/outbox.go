@@ -0,0 +1,73 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateMediaWithOutbox inserts the media and the outbox message in one
+// transaction, so a media never exists without the message that starts its
+// processing. Transactions require a replica set or sharded cluster.
+func (repo *MediaRepository) CreateMediaWithOutbox(ctx context.Context, media *models.Media, msg *models.OutboxMessage) error {
+
+	media.BeforeCreate()
+	msg.BeforeCreate()
+
+	return repo.db.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if err := repo.mediaCol.InsertOne(sc, *media); err != nil {
+			return nil, err
+		}
+		if err := repo.outboxCol.InsertOne(sc, *msg); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+// ListPendingOutbox returns up to limit pending messages that are due,
+// oldest first.
+func (repo *MediaRepository) ListPendingOutbox(ctx context.Context, limit int64) ([]*models.OutboxMessage, error) {
+
+	msgs, err := repo.outboxCol.Find(ctx, bson.M{
+		"status":          models.OutboxStatusPending,
+		"next_attempt_at": bson.M{"$lte": time.Now().UTC()},
+	}, options.Find().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).SetLimit(limit).SetHint(IndexOutboxPending), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+func (repo *MediaRepository) MarkOutboxSent(ctx context.Context, id primitive.ObjectID) error {
+
+	now := time.Now().UTC()
+	return repo.outboxCol.UpdateOne(ctx, bson.M{
+		"_id": id,
+	}, bson.M{"$set": bson.M{
+		"status":     models.OutboxStatusSent,
+		"sent_at":    now,
+		"updated_at": now,
+	}})
+}
+
+// MarkOutboxFailed records a failed publish attempt and when to retry it.
+func (repo *MediaRepository) MarkOutboxFailed(ctx context.Context, id primitive.ObjectID, errMsg string, nextAttemptAt time.Time) error {
+
+	return repo.outboxCol.UpdateOne(ctx, bson.M{
+		"_id": id,
+	}, bson.M{
+		"$set": bson.M{
+			"last_error":      errMsg,
+			"next_attempt_at": nextAttemptAt.UTC(),
+			"updated_at":      time.Now().UTC(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+}
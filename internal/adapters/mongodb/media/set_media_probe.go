@@ -0,0 +1,25 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SetMediaProbe stores the probe of a media and the duration and size taken
+// from it, leaving the other fields to concurrent writers.
+func (repo *MediaRepository) SetMediaProbe(ctx context.Context, media *models.Media) error {
+
+	media.BeforeUpdate()
+	return repo.mediaCol.UpdateOne(ctx, bson.M{
+		"_id": media.ID,
+	}, bson.M{"$set": bson.M{
+		"probe":      media.Probe,
+		"duration":   media.Duration,
+		"width":      media.Width,
+		"height":     media.Height,
+		"updated_at": media.UpdatedAt,
+	}}, options.Update().SetHint("_id_"))
+}
@@ -0,0 +1,59 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateTranscodeJobWithOutbox inserts a pending job and the message that
+// dispatches it in one transaction.
+func (repo *MediaRepository) CreateTranscodeJobWithOutbox(ctx context.Context, job *models.TranscodeJob, msg *models.OutboxMessage) error {
+
+	job.BeforeCreate()
+	msg.BeforeCreate()
+
+	err := repo.db.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if err := repo.transcodeJobCol.InsertOne(sc, *job); err != nil {
+			return nil, err
+		}
+		if err := repo.outboxCol.InsertOne(sc, *msg); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	return attemptConflict(err)
+}
+
+// RequestTranscodeJobCancel flags a processing job so its worker stops it
+// on the next heartbeat. It returns nil if the job is not processing.
+func (repo *MediaRepository) RequestTranscodeJobCancel(ctx context.Context, job *models.TranscodeJob) (*models.TranscodeJob, error) {
+
+	return findOneAndUpdateJob(ctx, repo, bson.M{
+		"_id":    job.ID,
+		"status": types.TranscodeJobStatusProcessing.String(),
+	}, bson.M{"$set": bson.M{
+		"cancel_requested": true,
+		"updated_at":       time.Now().UTC(),
+	}})
+}
+
+// CancelPendingTranscodeJob cancels a job no worker has claimed yet. It
+// returns nil if the job is not pending.
+func (repo *MediaRepository) CancelPendingTranscodeJob(ctx context.Context, job *models.TranscodeJob) (*models.TranscodeJob, error) {
+
+	now := time.Now().UTC()
+	return findOneAndUpdateJob(ctx, repo, bson.M{
+		"_id":    job.ID,
+		"status": types.TranscodeJobStatusPending.String(),
+	}, bson.M{"$set": bson.M{
+		"status":           types.TranscodeJobStatusCancelled.String(),
+		"cancel_requested": true,
+		"finished_at":      now,
+		"updated_at":       now,
+	}})
+}
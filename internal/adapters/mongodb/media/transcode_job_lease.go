@@ -0,0 +1,197 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ClaimTranscodeJob hands a pending job to workerID until leaseUntil. It
+// returns nil if the job is not pending, e.g. because another worker
+// claimed it first.
+func (repo *MediaRepository) ClaimTranscodeJob(ctx context.Context, jobID, workerID string, leaseUntil time.Time) (*models.TranscodeJob, error) {
+
+	oid, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return findOneAndUpdateJob(ctx, repo, bson.M{
+		"_id":    oid,
+		"status": types.TranscodeJobStatusPending.String(),
+	}, bson.M{"$set": bson.M{
+		"status":      types.TranscodeJobStatusProcessing.String(),
+		"worker_id":   workerID,
+		"lease_until": leaseUntil.UTC(),
+		"started_at":  now,
+		"updated_at":  now,
+	}})
+}
+
+// RenewTranscodeJobLease extends the lease of a job still processed by
+// workerID and returns the job, so the worker also sees cancel requests. It
+// returns nil once the job was taken away from the worker.
+func (repo *MediaRepository) RenewTranscodeJobLease(ctx context.Context, jobID, workerID string, leaseUntil time.Time) (*models.TranscodeJob, error) {
+
+	oid, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return findOneAndUpdateJob(ctx, repo, bson.M{
+		"_id":       oid,
+		"status":    types.TranscodeJobStatusProcessing.String(),
+		"worker_id": workerID,
+	}, bson.M{"$set": bson.M{
+		"lease_until": leaseUntil.UTC(),
+		"updated_at":  time.Now().UTC(),
+	}})
+}
+
+// ListExpiredTranscodeJobs returns up to limit processing jobs whose lease
+// ended before now or that have no lease at all.
+func (repo *MediaRepository) ListExpiredTranscodeJobs(ctx context.Context, now time.Time, limit int64) ([]*models.TranscodeJob, error) {
+
+	jobs, err := repo.transcodeJobCol.Find(ctx, expiredLeaseFilter(now),
+		options.Find().SetLimit(limit).SetHint(IndexTranscodeJobLease), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// RequeueExpiredTranscodeJob moves a job whose lease expired back to
+// pending and stores msg in the outbox to dispatch it again, in one
+// transaction. It returns false if the lease was renewed or the job was
+// already handled.
+func (repo *MediaRepository) RequeueExpiredTranscodeJob(ctx context.Context, jobID primitive.ObjectID, now time.Time, msg *models.OutboxMessage) (bool, error) {
+
+	msg.BeforeCreate()
+
+	requeued := false
+	err := repo.db.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		filter := expiredLeaseFilter(now)
+		filter["_id"] = jobID
+		job, err := findOneAndUpdateJob(sc, repo, filter, bson.M{
+			"$set": bson.M{
+				"status":     types.TranscodeJobStatusPending.String(),
+				"updated_at": time.Now().UTC(),
+			},
+			"$unset": bson.M{"lease_until": "", "worker_id": ""},
+			"$inc":   bson.M{"lost_leases": 1},
+		})
+		if err != nil || job == nil {
+			return nil, err
+		}
+		if err := repo.outboxCol.InsertOne(sc, *msg); err != nil {
+			return nil, err
+		}
+		requeued = true
+		return nil, nil
+	})
+	return requeued, err
+}
+
+// FailExpiredTranscodeJob marks a job whose lease expired as failed. It
+// returns false if the lease was renewed or the job was already handled.
+func (repo *MediaRepository) FailExpiredTranscodeJob(ctx context.Context, jobID primitive.ObjectID, now time.Time, errMsg string) (bool, error) {
+
+	filter := expiredLeaseFilter(now)
+	filter["_id"] = jobID
+	job, err := findOneAndUpdateJob(ctx, repo, filter, bson.M{
+		"$set": bson.M{
+			"status":      types.TranscodeJobStatusError.String(),
+			"error":       errMsg,
+			"finished_at": now.UTC(),
+			"updated_at":  time.Now().UTC(),
+		},
+		"$unset": bson.M{"lease_until": ""},
+		"$inc":   bson.M{"lost_leases": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+	return job != nil, nil
+}
+
+// ListStalePendingTranscodeJobs returns up to limit pending jobs not updated
+// since before, e.g. because their message was lost.
+func (repo *MediaRepository) ListStalePendingTranscodeJobs(ctx context.Context, before time.Time, limit int64) ([]*models.TranscodeJob, error) {
+
+	jobs, err := repo.transcodeJobCol.Find(ctx, stalePendingFilter(before),
+		options.Find().SetLimit(limit).SetHint(IndexTranscodeJobPending), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// RequeueStalePendingTranscodeJob touches a stale pending job and stores msg
+// in the outbox to dispatch it again, in one transaction. It returns false
+// if the job was claimed or updated in the meantime.
+func (repo *MediaRepository) RequeueStalePendingTranscodeJob(ctx context.Context, jobID primitive.ObjectID, before time.Time, msg *models.OutboxMessage) (bool, error) {
+
+	msg.BeforeCreate()
+
+	requeued := false
+	err := repo.db.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		filter := stalePendingFilter(before)
+		filter["_id"] = jobID
+		job, err := findOneAndUpdateJob(sc, repo, filter, bson.M{
+			"$set": bson.M{"updated_at": time.Now().UTC()},
+		})
+		if err != nil || job == nil {
+			return nil, err
+		}
+		if err := repo.outboxCol.InsertOne(sc, *msg); err != nil {
+			return nil, err
+		}
+		requeued = true
+		return nil, nil
+	})
+	return requeued, err
+}
+
+// stalePendingFilter matches pending jobs not updated since before.
+func stalePendingFilter(before time.Time) bson.M {
+	return bson.M{
+		"status":     types.TranscodeJobStatusPending.String(),
+		"updated_at": bson.M{"$lt": before.UTC()},
+	}
+}
+
+// expiredLeaseFilter matches processing jobs nobody renews: the lease ended,
+// or the job has none, e.g. because it started before leases existed.
+func expiredLeaseFilter(now time.Time) bson.M {
+	return bson.M{
+		"status": types.TranscodeJobStatusProcessing.String(),
+		"$or": bson.A{
+			bson.M{"lease_until": bson.M{"$lt": now.UTC()}},
+			bson.M{"lease_until": nil},
+		},
+	}
+}
+
+// findOneAndUpdateJob applies update to the job matching filter and returns
+// the updated job, or nil if none matched.
+func findOneAndUpdateJob(ctx context.Context, repo *MediaRepository, filter, update bson.M) (*models.TranscodeJob, error) {
+
+	job, err := repo.transcodeJobCol.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (repo *MediaRepository) CreateTusUpload(ctx context.Context, upload *models.TusUpload) error {
+
+	upload.BeforeCreate()
+
+	return repo.tusUploadCol.InsertOne(ctx, *upload)
+}
+
+func (repo *MediaRepository) GetTusUpload(ctx context.Context, id string) (*models.TusUpload, error) {
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	upload, err := repo.tusUploadCol.FindOne(ctx, bson.M{"_id": oid}, options.FindOne())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return upload, nil
+}
+
+// LockTusUpload claims an upload still at offset for the request lockID
+// until the given time, or extends the claim the request already holds. It
+// returns false if another request holds an unexpired lock or moved the
+// offset.
+func (repo *MediaRepository) LockTusUpload(ctx context.Context, id primitive.ObjectID, offset int64, lockID string, until time.Time) (bool, error) {
+
+	now := time.Now().UTC()
+	_, err := repo.tusUploadCol.FindOneAndUpdate(ctx, bson.M{
+		"_id":    id,
+		"offset": offset,
+		"$or": bson.A{
+			bson.M{"lock_id": bson.M{"$exists": false}},
+			bson.M{"lock_id": lockID},
+			bson.M{"locked_until": bson.M{"$lt": now}},
+		},
+	}, bson.M{"$set": bson.M{
+		"lock_id":      lockID,
+		"locked_until": until.UTC(),
+		"updated_at":   now,
+	}})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// UnlockTusUpload releases the lock of the request lockID, if it still
+// holds it.
+func (repo *MediaRepository) UnlockTusUpload(ctx context.Context, id primitive.ObjectID, lockID string) error {
+
+	return repo.tusUploadCol.UpdateOne(ctx, bson.M{
+		"_id":     id,
+		"lock_id": lockID,
+	}, bson.M{"$unset": bson.M{"lock_id": "", "locked_until": ""}})
+}
+
+// UpdateTusUploadOffset stores the progress of an upload that was at
+// prevOffset and releases the lock of the request that wrote it. It returns
+// false if the request no longer holds the lock.
+func (repo *MediaRepository) UpdateTusUploadOffset(ctx context.Context, upload *models.TusUpload, prevOffset int64) (bool, error) {
+
+	upload.BeforeUpdate()
+
+	set := bson.M{
+		"offset":     upload.Offset,
+		"parts":      upload.Parts,
+		"updated_at": upload.UpdatedAt,
+	}
+	unset := bson.M{"lock_id": "", "locked_until": ""}
+	if upload.TailPath != "" {
+		set["tail_path"] = upload.TailPath
+	} else {
+		unset["tail_path"] = ""
+	}
+
+	_, err := repo.tusUploadCol.FindOneAndUpdate(ctx, bson.M{
+		"_id":     upload.ID,
+		"offset":  prevOffset,
+		"lock_id": upload.LockID,
+	}, bson.M{"$set": set, "$unset": unset})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// SetTusUploadMedia records the media created from a finished upload.
+func (repo *MediaRepository) SetTusUploadMedia(ctx context.Context, id primitive.ObjectID, mediaID string) error {
+
+	return repo.tusUploadCol.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"media_id":   mediaID,
+		"updated_at": time.Now().UTC(),
+	}})
+}
+
+func (repo *MediaRepository) DeleteTusUpload(ctx context.Context, id primitive.ObjectID) error {
+
+	return repo.tusUploadCol.Delete(ctx, bson.M{"_id": id}, options.Delete())
+}
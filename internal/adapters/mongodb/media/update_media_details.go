@@ -0,0 +1,64 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpdateMediaDetailsInput holds the descriptive fields of a media to change.
+// Nil fields are left as they are; an empty description or tag list clears
+// the field.
+type UpdateMediaDetailsInput struct {
+	ID          primitive.ObjectID
+	Name        *string
+	Description *string
+	Tags        *[]string
+}
+
+// UpdateMediaDetails sets only the given descriptive fields of a media, so
+// it does not overwrite what the worker writes meanwhile, and returns the
+// updated media or nil if it does not exist.
+func (repo *MediaRepository) UpdateMediaDetails(ctx context.Context, input UpdateMediaDetailsInput) (*models.Media, error) {
+
+	set := bson.M{"updated_at": time.Now().UTC()}
+	unset := bson.M{}
+	if input.Name != nil {
+		set["name"] = *input.Name
+	}
+	if input.Description != nil {
+		if *input.Description == "" {
+			unset["description"] = ""
+		} else {
+			set["description"] = *input.Description
+		}
+	}
+	if input.Tags != nil {
+		if len(*input.Tags) == 0 {
+			unset["tags"] = ""
+		} else {
+			set["tags"] = *input.Tags
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	media, err := repo.mediaCol.FindOneAndUpdate(ctx, bson.M{
+		"_id": input.ID,
+	}, update, options.FindOneAndUpdate().SetReturnDocument(options.After).SetHint("_id_"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return media, nil
+}
@@ -8,12 +8,27 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// UpdateTranscodeJob replaces the job with the given ID. The lease and worker
+// are omitted from the document when empty, so they are unset explicitly to
+// release the job.
 func (repo *MediaRepository) UpdateTranscodeJob(ctx context.Context, transcode *models.TranscodeJob) error {
 
 	transcode.BeforeUpdate()
 
+	update := bson.M{"$set": transcode}
+	unset := bson.M{}
+	if transcode.LeaseUntil == nil {
+		unset["lease_until"] = ""
+	}
+	if transcode.WorkerID == "" {
+		unset["worker_id"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
 	err := repo.transcodeJobCol.UpdateOne(ctx, bson.M{
-		"media_id": transcode.MediaID,
-	}, bson.M{"$set": transcode}, options.Update())
+		"_id": transcode.ID,
+	}, update, options.Update().SetHint("_id_"))
 	return err
 }
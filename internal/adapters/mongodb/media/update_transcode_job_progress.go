@@ -0,0 +1,39 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UpdateTranscodeJobProgressInput struct {
+	JobID        primitive.ObjectID
+	Stage        string
+	Progress     float64
+	ETASeconds   int64
+	StageTimings []models.StageTiming // Written only when set
+}
+
+// UpdateTranscodeJobProgress sets only the progress fields of a job, so it
+// can run alongside other updates without overwriting them.
+func (repo *MediaRepository) UpdateTranscodeJobProgress(ctx context.Context, input UpdateTranscodeJobProgressInput) error {
+
+	set := bson.M{
+		"stage":       input.Stage,
+		"progress":    input.Progress,
+		"eta_seconds": input.ETASeconds,
+		"updated_at":  time.Now().UTC(),
+	}
+	if input.StageTimings != nil {
+		set["stage_timings"] = input.StageTimings
+	}
+
+	err := repo.transcodeJobCol.UpdateOne(ctx, bson.M{
+		"_id": input.JobID,
+	}, bson.M{"$set": set}, options.Update().SetHint("_id_"))
+	return err
+}
@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"media-svc/internal/services"
+	"sync"
+	"time"
+)
+
+// Relay periodically publishes pending outbox messages to the broker.
+type Relay struct {
+	svc       *services.Service
+	interval  time.Duration
+	batchSize int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRelay creates a relay polling every interval and publishing up to
+// batchSize messages per round.
+func NewRelay(svc *services.Service, interval time.Duration, batchSize int) *Relay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Relay{
+		svc:       svc,
+		interval:  interval,
+		batchSize: batchSize,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start launches the relay goroutine
+func (r *Relay) Start() {
+	r.wg.Add(1)
+	go r.run()
+	log.Printf("Outbox relay started, polling every %s", r.interval)
+}
+
+// Stop stops the relay and waits for the current round to finish
+func (r *Relay) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Relay) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		// Drain full batches without waiting for the next tick
+		for {
+			sent, err := r.svc.GetMediaSvc().PublishOutbox(r.ctx, r.batchSize)
+			if err != nil && r.ctx.Err() == nil {
+				log.Printf("outbox relay: %v", err)
+			}
+			if err != nil || sent < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package reaper
+
+import (
+	"context"
+	"log"
+	"media-svc/internal/services"
+	"sync"
+	"time"
+)
+
+// Reaper periodically recovers transcode jobs whose worker stopped renewing
+// the job lease or whose message never reached a worker, and removes direct
+// uploads that were never completed.
+type Reaper struct {
+	svc      *services.Service
+	interval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a reaper looking for expired leases every interval
+func New(svc *services.Service, interval time.Duration) *Reaper {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Reaper{
+		svc:      svc,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start launches the reaper goroutine
+func (r *Reaper) Start() {
+	r.wg.Add(1)
+	go r.run()
+	log.Printf("Job reaper started, checking leases every %s", r.interval)
+}
+
+// Stop stops the reaper and waits for the current round to finish
+func (r *Reaper) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Reaper) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := r.svc.GetMediaSvc().ReapExpiredJobs(r.ctx); err != nil && r.ctx.Err() == nil {
+			log.Printf("job reaper: %v", err)
+		}
+		if _, err := r.svc.GetMediaSvc().ReapStalePendingJobs(r.ctx); err != nil && r.ctx.Err() == nil {
+			log.Printf("pending job reaper: %v", err)
+		}
+		if _, err := r.svc.GetMediaSvc().ReapExpiredUploads(r.ctx); err != nil && r.ctx.Err() == nil {
+			log.Printf("upload reaper: %v", err)
+		}
+	}
+}
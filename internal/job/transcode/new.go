@@ -2,14 +2,24 @@ package transcode
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"media-svc/internal/services"
 	"media-svc/internal/services/media"
 	"media-svc/internal/types"
+	"media-svc/pkgs/rabbitmq"
+	"media-svc/pkgs/transcoder"
+	"os"
 	"sync"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrStopped is returned by Process for jobs not started before Stop.
+var ErrStopped = errors.New("orchestrator stopped")
+
 // Job holds information about a transcoding job
 type Job struct {
 	MediaID    string
@@ -23,41 +33,51 @@ type Job struct {
 }
 
 type transcodeResult struct {
-	sourcePath string
-	renditions []types.Rendition
+	sourcePath       string
+	ladder           string
+	encryptionMethod string
+	renditions       []types.Rendition
+	poster           []types.Image
+	thumbnails       []types.Image
+	trickplay        *types.Trickplay
 }
 
-// Orchestrator manages transcoding jobs and worker pool
+// Orchestrator runs transcoding jobs on a fixed pool of workers. Callers
+// hand a job over with Process and get its outcome back, so the message
+// that carried the job can be acked or nacked once the job has finished.
 type Orchestrator struct {
-	svc        *services.Service              // service to handle media operations
-	jobCh      chan media.TranscodeVideoInput // channel for job queue
-	jobs       map[string]*Job                // map of jobs keyed by FilePath
-	mu         sync.RWMutex                   // mutex to protect jobs map
-	workers    int
-	ctx        context.Context
-	cancel     context.CancelFunc
-	started    bool
-	wg         sync.WaitGroup
-	queueDepth int
-
-	successChan chan *Job
-	errorChan   chan *Job
+	svc     *services.Service // service to handle media operations
+	jobCh   chan request      // unbuffered: a job is only taken by an idle worker
+	jobs    map[string]*Job   // latest job per media ID
+	mu      sync.RWMutex      // mutex to protect jobs map
+	workers int
+	name    string // host and process prefix of the worker IDs
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	wg      sync.WaitGroup
 }
 
-// New creates an orchestrator with specified number of workers and queue depth
-func New(svc *services.Service, workers int, queueDepth int) *Orchestrator {
+type request struct {
+	input media.TranscodeVideoInput
+	done  chan error
+}
+
+// New creates an orchestrator running at most workers jobs at a time
+func New(svc *services.Service, workers int) *Orchestrator {
+	if workers <= 0 {
+		workers = 1
+	}
+	host, _ := os.Hostname()
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Orchestrator{
-		svc:        svc,
-		jobCh:      make(chan media.TranscodeVideoInput, queueDepth),
-		jobs:       make(map[string]*Job),
-		workers:    workers,
-		ctx:        ctx,
-		cancel:     cancel,
-		queueDepth: queueDepth,
-
-		successChan: make(chan *Job, queueDepth),
-		errorChan:   make(chan *Job, queueDepth),
+		svc:     svc,
+		jobCh:   make(chan request),
+		jobs:    make(map[string]*Job),
+		workers: workers,
+		name:    fmt.Sprintf("%s-%d", host, os.Getpid()),
+		ctx:     ctx,
+		cancel:  cancel,
 	}
 }
 
@@ -78,33 +98,44 @@ func (o *Orchestrator) Start() {
 	log.Printf("Orchestrator started with %d workers", o.workers)
 }
 
-// Stop signals workers to stop and waits for them to finish
+// Stop signals workers to stop, interrupting transcodes in progress, and
+// waits for them to finish
 func (o *Orchestrator) Stop() {
 	o.cancel()
 	o.wg.Wait()
 }
 
-// AddJob adds a new transcoding job to the queue if not already present
-func (o *Orchestrator) AddJob(in media.TranscodeVideoInput) {
+// Process runs a job on the next idle worker and waits for it to finish.
+// It returns nil when the job succeeded or was a duplicate, the job error
+// otherwise, and ErrStopped when the orchestrator stopped first. Errors that
+// retrying cannot fix are wrapped with rabbitmq.Permanent.
+func (o *Orchestrator) Process(ctx context.Context, in media.TranscodeVideoInput) error {
 	o.mu.Lock()
-	if _, exists := o.jobs[in.MediaID]; !exists {
-		o.jobs[in.MediaID] = &Job{
-			MediaID:   in.MediaID,
-			Status:    types.TranscodeJobStatusPending.String(),
-			CreatedAt: time.Now(),
-		}
+	o.jobs[in.MediaID] = &Job{
+		MediaID:   in.MediaID,
+		Status:    types.TranscodeJobStatusPending.String(),
+		CreatedAt: time.Now(),
 	}
 	o.mu.Unlock()
 
-	// Blocking send to job channel; consider non-blocking or queue full logic if needed
-	o.jobCh <- in
+	req := request{input: in, done: make(chan error, 1)}
+	select {
+	case o.jobCh <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-o.ctx.Done():
+		return ErrStopped
+	}
+
+	// The worker always answers, also when it is interrupted
+	return <-req.done
 }
 
 // GetJobStatus returns a copy of the job status or nil if job not found
-func (o *Orchestrator) GetJobStatus(filePath string) *Job {
+func (o *Orchestrator) GetJobStatus(mediaID string) *Job {
 	o.mu.RLock()
 	defer o.mu.RUnlock()
-	if job, ok := o.jobs[filePath]; ok {
+	if job, ok := o.jobs[mediaID]; ok {
 		// Return a copy to avoid race conditions
 		copyJob := *job
 		return &copyJob
@@ -117,70 +148,21 @@ func (o *Orchestrator) worker(id int) {
 	defer o.wg.Done()
 	log.Printf("worker-%d started", id)
 
-	go o.processSuccess()
-	go o.processError()
-	go o.progressJob()
-
 	for {
 		select {
 		case <-o.ctx.Done():
 			log.Printf("worker-%d stopping (context cancelled)", id)
 			return
-		}
-
-	}
-}
-
-func (o *Orchestrator) processSuccess() {
-	for {
-		select {
-		case <-o.ctx.Done():
-			return
-		case msgs := <-o.successChan:
-			o.svc.GetMediaSvc().UpdateTranscodeJobSuccess(context.Background(), media.UpdateTranscodeJobSuccessInput{
-				MediaID:    msgs.MediaID,
-				OutputPath: msgs.Result.sourcePath,
-				Renditions: msgs.Result.renditions,
-			})
-		}
-	}
-}
-
-func (o *Orchestrator) onSuccess(job *Job) {
-	o.successChan <- job
-}
-
-func (o *Orchestrator) processError() {
-	for {
-		select {
-		case <-o.ctx.Done():
-			return
-		case msgs := <-o.errorChan:
-			o.svc.GetMediaSvc().UpdateTranscodeJobError(context.Background(), media.UpdateTranscodeJobErrorInput{
-				MediaID: msgs.MediaID,
-				Err:     msgs.Error,
-			})
-		}
-	}
-}
-
-func (o *Orchestrator) onError(job *Job) {
-	o.errorChan <- job
-}
-
-func (o *Orchestrator) progressJob() {
-	for {
-		select {
-		case <-o.ctx.Done():
-			return
-		case input := <-o.jobCh:
-			o.handleJob(input)
+		case req := <-o.jobCh:
+			req.input.WorkerID = fmt.Sprintf("%s/worker-%d", o.name, id)
+			req.done <- o.handleJob(req.input)
 		}
 	}
 }
 
-// handleJob executes the transcoding job by calling the media service
-func (o *Orchestrator) handleJob(input media.TranscodeVideoInput) {
+// handleJob executes the transcoding job by calling the media service and
+// records its outcome
+func (o *Orchestrator) handleJob(input media.TranscodeVideoInput) error {
 	// Mark job as processing
 	o.mu.Lock()
 	job := o.jobs[input.MediaID]
@@ -195,29 +177,136 @@ func (o *Orchestrator) handleJob(input media.TranscodeVideoInput) {
 	job.StartedAt = time.Now()
 	o.mu.Unlock()
 
-	// Call the TranscodeVideo service method
+	// Pick the job record ID up front so the outcome updates this attempt
+	if input.JobID == "" {
+		input.JobID = primitive.NewObjectID().Hex()
+	}
 
-	result, err := o.svc.GetMediaSvc().TranscodeVideo(context.Background(), input)
+	// Call the TranscodeVideo service method; cancelling the orchestrator
+	// stops ffmpeg for jobs in progress.
+	result, err := o.transcode(input)
+	if result.JobID != "" {
+		input.JobID = result.JobID
+	}
 
-	o.mu.Lock()
-	defer o.mu.Unlock()
+	if errors.Is(err, media.ErrDuplicateJob) {
+		log.Printf("skipping duplicate job message %s for %s", input.MessageID, input.MediaID)
+		return nil
+	}
+	if errors.Is(err, media.ErrLeaseLost) {
+		// The reaper dispatched the job again; its new worker records it
+		log.Printf("job %s for %s taken over by another worker: %v", input.JobID, input.MediaID, err)
+		return nil
+	}
 
+	status := o.errorStatus(err)
+	o.mu.Lock()
+	job.DoneAt = time.Now()
 	if err != nil {
-		job.Status = types.TranscodeJobStatusError.String()
+		job.Status = status.String()
 		job.Error = err.Error()
-		job.DoneAt = time.Now()
-		o.onError(job)
+	} else {
+		job.Status = types.TranscodeJobStatusDone.String()
+		job.Result = transcodeResult{
+			sourcePath:       result.Path,
+			ladder:           result.Ladder,
+			encryptionMethod: result.EncryptionMethod,
+			renditions:       result.Renditions,
+			poster:           result.Poster,
+			thumbnails:       result.Thumbnails,
+			trickplay:        result.Trickplay,
+		}
+	}
+	snapshot := *job
+	o.mu.Unlock()
+
+	// Record the outcome even when the orchestrator is stopping
+	if err != nil {
+		update := media.UpdateTranscodeJobErrorInput{
+			MediaID: input.MediaID,
+			JobID:   input.JobID,
+			Err:     err.Error(),
+			Status:  status,
+		}
+		var exitErr *transcoder.ExitError
+		if errors.As(err, &exitErr) {
+			update.ExitCode = &exitErr.ExitCode
+			update.StderrTail = exitErr.StderrTail
+		}
+		if updateErr := o.svc.GetMediaSvc().UpdateTranscodeJobError(context.Background(), update); updateErr != nil {
+			log.Printf("record transcode error for %s: %v", input.MediaID, updateErr)
+		}
+		if errors.Is(err, media.ErrJobCancelled) {
+			// Cancelled on request: done with this message
+			log.Printf("transcode cancelled on request for %s", input.MediaID)
+			return nil
+		}
+		if status == types.TranscodeJobStatusCancelled || status == types.TranscodeJobStatusPending {
+			log.Printf("transcode %s for %s: %v", status, input.MediaID, err)
+			return err
+		}
 		log.Printf("transcode failed for %s: %v", input.MediaID, err)
-		return
+		if isPermanent(err) {
+			return rabbitmq.Permanent(err)
+		}
+		return err
 	}
 
-	job.Status = types.TranscodeJobStatusDone.String()
-	job.DoneAt = time.Now()
-	job.Result = transcodeResult{
-		sourcePath: result.Path,
-		renditions: result.Renditions,
+	if err := o.svc.GetMediaSvc().UpdateTranscodeJobSuccess(context.Background(), media.UpdateTranscodeJobSuccessInput{
+		MediaID:          input.MediaID,
+		JobID:            input.JobID,
+		OutputPath:       snapshot.Result.sourcePath,
+		Ladder:           snapshot.Result.ladder,
+		EncryptionMethod: snapshot.Result.encryptionMethod,
+		Renditions:       snapshot.Result.renditions,
+		Poster:           snapshot.Result.poster,
+		Thumbnails:       snapshot.Result.thumbnails,
+		Trickplay:        snapshot.Result.trickplay,
+	}); err != nil {
+		// The output is uploaded but not recorded; let the message retry
+		return fmt.Errorf("record transcode result: %w", err)
 	}
-	o.onSuccess(job)
 	log.Printf("transcode done for %s", input.MediaID)
+	return nil
+}
+
+// transcode runs one job, turning a panic into a job error so the worker
+// keeps running. The service's deferred cleanup has run by the time the
+// panic is recovered.
+func (o *Orchestrator) transcode(input media.TranscodeVideoInput) (result media.TranscodeVideoOutput, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("transcode panicked: %v", r)
+		}
+	}()
+	return o.svc.GetMediaSvc().TranscodeVideo(o.ctx, input)
+}
+
+// errorStatus maps a transcode error to the job status it should be recorded
+// with. A job interrupted by Stop goes back to pending, as its message is
+// requeued and the next delivery takes the job up again.
+func (o *Orchestrator) errorStatus(err error) types.TranscodeJobStatus {
+	if err == nil {
+		return types.TranscodeJobStatusDone
+	}
+	if errors.Is(err, media.ErrJobCancelled) {
+		return types.TranscodeJobStatusCancelled
+	}
+	var timeoutErr *transcoder.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return types.TranscodeJobStatusTimeout
+	}
+	var canceledErr *transcoder.CanceledError
+	if errors.As(err, &canceledErr) || errors.Is(err, context.Canceled) {
+		if o.ctx.Err() != nil {
+			return types.TranscodeJobStatusPending
+		}
+		return types.TranscodeJobStatusCancelled
+	}
+	return types.TranscodeJobStatusError
+}
 
+// isPermanent reports whether retrying the job cannot succeed
+func isPermanent(err error) bool {
+	return errors.Is(err, media.ErrLadderNotFound) || errors.Is(err, media.ErrInvalidOptions) || errors.Is(err, media.ErrMediaNotFound)
 }
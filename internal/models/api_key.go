@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey is a credential of a machine client. Only the SHA-256 hash of the
+// key is stored; the key itself is shown once when it is created.
+type APIKey struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`                                 // What the key is used for
+	Hash      string             `bson:"hash" json:"-"`                                    // Hex SHA-256 of the key
+	Prefix    string             `bson:"prefix" json:"prefix"`                             // Start of the key, to recognize it
+	OwnerID   string             `bson:"owner_id" json:"owner_id"`                         // Principal the key acts as
+	Roles     []string           `bson:"roles,omitempty" json:"roles,omitempty"`           // Roles of the key, e.g. "admin"
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"` // When the key was revoked
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func (coll APIKey) CollectionName() string {
+	return "api_keys"
+}
+
+func (coll *APIKey) BeforeCreate() {
+
+	if coll.ID.IsZero() {
+		coll.ID = primitive.NewObjectID()
+	}
+
+	coll.CreatedAt = time.Now().UTC()
+	coll.UpdatedAt = time.Now().UTC()
+}
@@ -21,6 +21,11 @@ type Media struct {
 	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`                                 // Timestamp when the media was created
 	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`                                 // Timestamp when the media was last updated
 	TranscodeSource *TranscodeSource   `bson:"transcode_source,omitempty" json:"transcode_source,omitempty"` // Optional transcode source only for video
+	Probe           *MediaProbe        `bson:"probe,omitempty" json:"probe,omitempty"`                       // Source metadata extracted with ffprobe
+	Poster          []MediaImage       `bson:"poster,omitempty" json:"poster,omitempty"`                     // Poster frame in every configured size and format
+	Thumbnails      []MediaImage       `bson:"thumbnails,omitempty" json:"thumbnails,omitempty"`             // Evenly spaced thumbnails in every configured size and format
+	Upload          *MediaUpload       `bson:"upload,omitempty" json:"upload,omitempty"`                     // Direct upload still in progress, removed once completed
+	OwnerID         string             `bson:"owner_id,omitempty" json:"owner_id,omitempty"`                 // Principal that uploaded the media
 }
 
 func (coll Media) CollectionName() string {
@@ -41,15 +46,102 @@ func (coll *Media) BeforeUpdate() {
 	coll.UpdatedAt = time.Now().UTC()
 }
 
+// MediaUpload is a direct-to-storage upload the client has not completed
+// yet. The media is not transcoded or listed until then.
+type MediaUpload struct {
+	Ladder    string    `bson:"ladder,omitempty" json:"ladder,omitempty"`       // Rendition ladder to transcode with once completed
+	Checksum  string    `bson:"checksum,omitempty" json:"checksum,omitempty"`   // Expected hex MD5 of the file
+	UploadID  string    `bson:"upload_id,omitempty" json:"upload_id,omitempty"` // Storage multipart upload ID, empty for a single PUT
+	PartSize  int64     `bson:"part_size,omitempty" json:"part_size,omitempty"` // Size of every part but the last
+	Parts     int       `bson:"parts,omitempty" json:"parts,omitempty"`         // Number of parts
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`                   // When the presigned URLs expire
+}
+
 type TranscodeSource struct {
-	FilePath   string      `bson:"file_path" json:"file_path"`
-	Renditions []Rendition `bson:"renditions" json:"renditions"`
+	FilePath         string      `bson:"file_path" json:"file_path"`
+	Ladder           string      `bson:"ladder,omitempty" json:"ladder,omitempty"`                       // Name of the rendition ladder that produced the output
+	EncryptionMethod string      `bson:"encryption_method,omitempty" json:"encryption_method,omitempty"` // HLS encryption method, empty when unencrypted
+	Renditions       []Rendition `bson:"renditions" json:"renditions"`
+	Trickplay        *Trickplay  `bson:"trickplay,omitempty" json:"trickplay,omitempty"` // Seek-bar preview sprite sheets
+}
+
+// Trickplay references the sprite sheets and WebVTT track used for seek-bar
+// previews. Paths are object keys in the stream bucket.
+type Trickplay struct {
+	VTTPath      string   `bson:"vtt_path" json:"vtt_path"`
+	PlaylistPath string   `bson:"playlist_path,omitempty" json:"playlist_path,omitempty"` // HLS image playlist
+	Sprites      []string `bson:"sprites" json:"sprites"`
+	Interval     float64  `bson:"interval" json:"interval"` // Seconds between two tiles
+	TileWidth    int      `bson:"tile_width" json:"tile_width"`
+	TileHeight   int      `bson:"tile_height" json:"tile_height"`
+	Columns      int      `bson:"columns" json:"columns"`
+	Rows         int      `bson:"rows" json:"rows"`
+}
+
+// MediaImage is a poster or thumbnail stored in the stream bucket.
+type MediaImage struct {
+	Key    string  `bson:"key" json:"key"`       // Object key in the stream bucket
+	Width  int     `bson:"width" json:"width"`   // Width in pixels
+	Height int     `bson:"height" json:"height"` // Height in pixels
+	Format string  `bson:"format" json:"format"` // jpeg or webp
+	Time   float64 `bson:"time" json:"time"`     // Position of the frame in seconds
 }
 
 type Rendition struct {
 	Name         string `bson:"name" json:"name"`
 	Width        int    `bson:"width" json:"width"`
 	Height       int    `bson:"height" json:"height"`
+	VideoCodec   string `bson:"video_codec,omitempty" json:"video_codec,omitempty"` // h264, hevc, vp9 or av1
+	Codecs       string `bson:"codecs,omitempty" json:"codecs,omitempty"`           // RFC 6381 CODECS value of the variant
 	VideoBitrate string `bson:"video_bitrate" json:"video_bitrate"`
 	AudioBitrate string `bson:"audio_bitrate" json:"audio_bitrate"`
 }
+
+type MediaProbe struct {
+	FormatName      string               `bson:"format_name" json:"format_name"`                               // Container format, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	FormatLongName  string               `bson:"format_long_name,omitempty" json:"format_long_name,omitempty"` // Human readable container format
+	Duration        float64              `bson:"duration" json:"duration"`                                     // Container duration in seconds
+	BitRate         int64                `bson:"bit_rate,omitempty" json:"bit_rate,omitempty"`                 // Overall bitrate in bits per second
+	VideoStreams    []VideoStreamInfo    `bson:"video_streams,omitempty" json:"video_streams,omitempty"`
+	AudioStreams    []AudioStreamInfo    `bson:"audio_streams,omitempty" json:"audio_streams,omitempty"`
+	SubtitleStreams []SubtitleStreamInfo `bson:"subtitle_streams,omitempty" json:"subtitle_streams,omitempty"`
+	ProbedAt        time.Time            `bson:"probed_at" json:"probed_at"`
+}
+
+type VideoStreamInfo struct {
+	Index          int     `bson:"index" json:"index"`
+	Codec          string  `bson:"codec" json:"codec"`                                         // e.g. "h264", "hevc"
+	Profile        string  `bson:"profile,omitempty" json:"profile,omitempty"`                 // e.g. "High"
+	Level          int     `bson:"level,omitempty" json:"level,omitempty"`                     // e.g. 41
+	Width          int     `bson:"width" json:"width"`                                         // Coded width in pixels
+	Height         int     `bson:"height" json:"height"`                                       // Coded height in pixels
+	PixelFormat    string  `bson:"pixel_format,omitempty" json:"pixel_format,omitempty"`       // e.g. "yuv420p10le"
+	FrameRate      float64 `bson:"frame_rate,omitempty" json:"frame_rate,omitempty"`           // Frames per second
+	BitRate        int64   `bson:"bit_rate,omitempty" json:"bit_rate,omitempty"`               // Bits per second
+	Rotation       int     `bson:"rotation,omitempty" json:"rotation,omitempty"`               // Display rotation in degrees
+	Language       string  `bson:"language,omitempty" json:"language,omitempty"`               // Language tag
+	ColorRange     string  `bson:"color_range,omitempty" json:"color_range,omitempty"`         // e.g. "tv"
+	ColorSpace     string  `bson:"color_space,omitempty" json:"color_space,omitempty"`         // e.g. "bt2020nc"
+	ColorTransfer  string  `bson:"color_transfer,omitempty" json:"color_transfer,omitempty"`   // e.g. "smpte2084"
+	ColorPrimaries string  `bson:"color_primaries,omitempty" json:"color_primaries,omitempty"` // e.g. "bt2020"
+	HDRFormat      string  `bson:"hdr_format,omitempty" json:"hdr_format,omitempty"`           // hdr10, hlg, dolby_vision; empty for SDR
+}
+
+type AudioStreamInfo struct {
+	Index         int    `bson:"index" json:"index"`
+	Codec         string `bson:"codec" json:"codec"`                                       // e.g. "aac", "opus"
+	Profile       string `bson:"profile,omitempty" json:"profile,omitempty"`               // e.g. "LC"
+	SampleRate    int    `bson:"sample_rate,omitempty" json:"sample_rate,omitempty"`       // Hz
+	Channels      int    `bson:"channels,omitempty" json:"channels,omitempty"`             // Channel count
+	ChannelLayout string `bson:"channel_layout,omitempty" json:"channel_layout,omitempty"` // e.g. "stereo"
+	BitRate       int64  `bson:"bit_rate,omitempty" json:"bit_rate,omitempty"`             // Bits per second
+	Language      string `bson:"language,omitempty" json:"language,omitempty"`             // Language tag
+}
+
+type SubtitleStreamInfo struct {
+	Index    int    `bson:"index" json:"index"`
+	Codec    string `bson:"codec" json:"codec"`                           // e.g. "mov_text", "subrip"
+	Language string `bson:"language,omitempty" json:"language,omitempty"` // Language tag
+	Title    string `bson:"title,omitempty" json:"title,omitempty"`
+	Forced   bool   `bson:"forced,omitempty" json:"forced,omitempty"`
+}
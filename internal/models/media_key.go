@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MediaKey is the content encryption key of a media. The key itself is
+// stored encrypted with the service master key.
+type MediaKey struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MediaID      primitive.ObjectID `bson:"media_id" json:"media_id"`     // Reference to the encrypted media
+	Method       string             `bson:"method" json:"method"`         // AES-128 or SAMPLE-AES
+	EncryptedKey []byte             `bson:"encrypted_key" json:"-"`       // Content key sealed with AES-256-GCM
+	Nonce        []byte             `bson:"nonce" json:"-"`               // GCM nonce of EncryptedKey
+	KeyID        []byte             `bson:"key_id,omitempty" json:"-"`    // SAMPLE-AES key ID
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"` // Key creation time
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"` // Last update time
+}
+
+func (coll MediaKey) CollectionName() string {
+	return "media_keys"
+}
+
+func (coll *MediaKey) BeforeCreate() {
+
+	if coll.ID.IsZero() {
+		coll.ID = primitive.NewObjectID()
+	}
+
+	coll.CreatedAt = time.Now().UTC()
+	coll.UpdatedAt = time.Now().UTC()
+}
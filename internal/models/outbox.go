@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	OutboxStatusSent    OutboxStatus = "sent"
+)
+
+// OutboxMessage is a broker message written in the same transaction as the
+// change that produced it, and published later by the outbox relay.
+type OutboxMessage struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Queue         string             `bson:"queue" json:"queue"`                               // Destination queue
+	Payload       []byte             `bson:"payload" json:"payload"`                           // Message body
+	Status        string             `bson:"status" json:"status"`                             // pending, sent
+	Attempts      int                `bson:"attempts" json:"attempts"`                         // Failed publish attempts so far
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"` // Error of the last failed attempt
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`           // Earliest time of the next publish attempt
+	SentAt        *time.Time         `bson:"sent_at,omitempty" json:"sent_at,omitempty"`       // When the broker confirmed the message
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`                     // Message creation time
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`                     // Last update time
+}
+
+func (coll OutboxMessage) CollectionName() string {
+	return "outbox"
+}
+
+func (coll *OutboxMessage) BeforeCreate() {
+
+	if coll.ID.IsZero() {
+		coll.ID = primitive.NewObjectID()
+	}
+	if coll.Status == "" {
+		coll.Status = string(OutboxStatusPending)
+	}
+
+	coll.CreatedAt = time.Now().UTC()
+	coll.UpdatedAt = time.Now().UTC()
+	if coll.NextAttemptAt.IsZero() {
+		coll.NextAttemptAt = coll.CreatedAt
+	}
+}
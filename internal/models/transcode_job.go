@@ -14,19 +14,57 @@ const (
 	TranscodeStatusFailed  TranscodeStatus = "failed"
 )
 
+// TranscodeJob is one attempt at transcoding a media. Every retry creates a
+// new job with the next attempt number, so the documents of a media form its
+// transcode history.
 type TranscodeJob struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	MediaID    primitive.ObjectID `bson:"media_id" json:"media_id"`                           // Reference to the original media
-	Status     string             `bson:"status" json:"status"`                               // pending, processing, success, failed
-	OutputPath string             `bson:"output_path,omitempty" json:"output_path,omitempty"` // Folder or key where HLS/DASH is stored
-	Error      string             `bson:"error,omitempty" json:"error,omitempty"`             // Error message if failed
-	StartedAt  *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`   // When processing started
-	FinishedAt *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"` // When processing finished
-	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`                       // Job creation time
-	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`                       // Last update time
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MediaID         primitive.ObjectID `bson:"media_id" json:"media_id"`                                     // Reference to the original media
+	Attempt         int                `bson:"attempt" json:"attempt"`                                       // 1 for the first attempt of the media, incremented per retry
+	MessageID       string             `bson:"message_id,omitempty" json:"message_id,omitempty"`             // Broker message that started the job
+	WorkerID        string             `bson:"worker_id,omitempty" json:"worker_id,omitempty"`               // Worker that ran the attempt
+	Ladder          string             `bson:"ladder,omitempty" json:"ladder,omitempty"`                     // Rendition ladder used
+	Options         *TranscodeOptions  `bson:"options,omitempty" json:"options,omitempty"`                   // Codec settings overriding the ladder
+	Status          string             `bson:"status" json:"status"`                                         // pending, processing, success, failed
+	OutputPath      string             `bson:"output_path,omitempty" json:"output_path,omitempty"`           // Folder or key where HLS/DASH is stored
+	Error           string             `bson:"error,omitempty" json:"error,omitempty"`                       // Error message if failed
+	ExitCode        *int               `bson:"exit_code,omitempty" json:"exit_code,omitempty"`               // ffmpeg exit code if it failed
+	StderrTail      string             `bson:"stderr_tail,omitempty" json:"stderr_tail,omitempty"`           // End of the ffmpeg stderr if it failed
+	Stage           string             `bson:"stage,omitempty" json:"stage,omitempty"`                       // downloading, probing, encoding, uploading, completed
+	StageTimings    []StageTiming      `bson:"stage_timings,omitempty" json:"stage_timings,omitempty"`       // When each stage started and finished
+	Progress        float64            `bson:"progress" json:"progress"`                                     // Percent complete of the current stage, 0-100
+	ETASeconds      int64              `bson:"eta_seconds,omitempty" json:"eta_seconds,omitempty"`           // Estimated seconds remaining in the current stage
+	LeaseUntil      *time.Time         `bson:"lease_until,omitempty" json:"lease_until,omitempty"`           // The worker owns the job until then, renewed by its heartbeat
+	LostLeases      int                `bson:"lost_leases,omitempty" json:"lost_leases,omitempty"`           // Times the lease expired, e.g. because the worker crashed
+	CancelRequested bool               `bson:"cancel_requested,omitempty" json:"cancel_requested,omitempty"` // Set to ask the worker to stop the job
+	StartedAt       *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`             // When processing started
+	FinishedAt      *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`           // When processing finished
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`                                 // Job creation time
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`                                 // Last update time
 
 }
 
+// TranscodeOptions are the codec settings a job overrides in its ladder.
+type TranscodeOptions struct {
+	VideoCodec string `bson:"video_codec,omitempty" json:"video_codec,omitempty"` // h264, hevc, vp9 or av1
+	Preset     string `bson:"preset,omitempty" json:"preset,omitempty"`
+	Profile    string `bson:"profile,omitempty" json:"profile,omitempty"`
+}
+
+// StageTiming records how long a job spent in one stage.
+type StageTiming struct {
+	Stage      string     `bson:"stage" json:"stage"`
+	StartedAt  time.Time  `bson:"started_at" json:"started_at"`
+	FinishedAt *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// FinishStage closes the timing of the stage in progress, if any.
+func (coll *TranscodeJob) FinishStage(at time.Time) {
+	if n := len(coll.StageTimings); n > 0 && coll.StageTimings[n-1].FinishedAt == nil {
+		coll.StageTimings[n-1].FinishedAt = &at
+	}
+}
+
 func (coll TranscodeJob) CollectionName() string {
 	return "transcode_jobs"
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TusUpload is a resumable upload received with the tus protocol. Its bytes
+// go to a storage multipart upload in parts of PartSize; the bytes after the
+// last full part are kept in the TailPath object until the next request
+// completes the part. Offset is therefore Parts*PartSize plus the tail size.
+type TusUpload struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Path        string             `bson:"path" json:"path"`                                     // Object key of the assembled file
+	Length      int64              `bson:"length" json:"length"`                                 // Upload-Length declared at creation
+	Offset      int64              `bson:"offset" json:"offset"`                                 // Bytes received so far
+	Metadata    map[string]string  `bson:"metadata,omitempty" json:"metadata,omitempty"`         // Decoded Upload-Metadata
+	Filename    string             `bson:"filename" json:"filename"`                             // Name of the uploaded file
+	ContentType string             `bson:"content_type,omitempty" json:"content_type,omitempty"` // MIME type of the uploaded file
+	Ladder      string             `bson:"ladder,omitempty" json:"ladder,omitempty"`             // Rendition ladder to transcode with once finished
+	MultipartID string             `bson:"multipart_id" json:"multipart_id"`                     // Storage multipart upload ID
+	PartSize    int64              `bson:"part_size" json:"part_size"`                           // Size of every part but the last
+	Parts       int                `bson:"parts" json:"parts"`                                   // Full parts stored so far
+	TailPath    string             `bson:"tail_path,omitempty" json:"tail_path,omitempty"`       // Object holding the bytes after the last part
+	MediaID     string             `bson:"media_id,omitempty" json:"media_id,omitempty"`         // Media created once the upload finished
+	OwnerID     string             `bson:"owner_id,omitempty" json:"owner_id,omitempty"`         // Principal that created the upload
+	LockID      string             `bson:"lock_id,omitempty" json:"-"`                           // Request writing at the offset, if any
+	LockedUntil *time.Time         `bson:"locked_until,omitempty" json:"-"`                      // When the lock of a stopped request lapses
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func (coll TusUpload) CollectionName() string {
+	return "tus_uploads"
+}
+
+func (coll *TusUpload) BeforeCreate() {
+
+	if coll.ID.IsZero() {
+		coll.ID = primitive.NewObjectID()
+	}
+
+	coll.CreatedAt = time.Now().UTC()
+	coll.UpdatedAt = time.Now().UTC()
+}
+
+func (coll *TusUpload) BeforeUpdate() {
+	coll.UpdatedAt = time.Now().UTC()
+}
+
+// TailSize is the number of received bytes not yet stored as a part.
+func (coll *TusUpload) TailSize() int64 {
+	return coll.Offset - int64(coll.Parts)*coll.PartSize
+}
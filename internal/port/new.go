@@ -3,6 +3,8 @@ package port
 import (
 	"log"
 	"media-svc/config"
+	"media-svc/internal/job/outbox"
+	"media-svc/internal/job/reaper"
 	"media-svc/internal/port/rest"
 	"media-svc/internal/services"
 	"media-svc/pkgs/rabbitmq"
@@ -17,13 +19,18 @@ type Server struct {
 	rabbitClient *rabbitmq.Publisher
 }
 
-func NewServer(cfg *config.Config, db *mongodb.Database, rabbitClient *rabbitmq.Publisher) *Server {
+func NewServer(cfg *config.Config, db *mongodb.Database, rabbitClient *rabbitmq.Publisher) (*Server, error) {
+
+	svc, err := services.NewService(cfg, db, rabbitClient)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Server{
 		cfg:          cfg,
 		rabbitClient: rabbitClient,
-		svc:          services.NewService(cfg, db, rabbitClient),
-	}
+		svc:          svc,
+	}, nil
 }
 
 func (s *Server) Run() {
@@ -34,6 +41,16 @@ func (s *Server) Run() {
 		}
 	}()
 
+	// Publish job messages written to the outbox by uploads
+	relay := outbox.NewRelay(s.svc, s.cfg.RabbitMQ.OutboxInterval, s.cfg.RabbitMQ.OutboxBatchSize)
+	relay.Start()
+	defer relay.Stop()
+
+	// Dispatch again the jobs of workers that died mid-transcode
+	jobReaper := reaper.New(s.svc, s.cfg.Worker.ReapInterval)
+	jobReaper.Start()
+	defer jobReaper.Stop()
+
 	restSvr := rest.NewRestServer(s.cfg, s.svc)
 
 	// Run HTTP in parallel
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/auth"
+	"media-svc/internal/services/media"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries an API key. A key may also be sent as a bearer
+// token.
+const APIKeyHeader = "X-API-Key"
+
+// AuthHeaders are the request headers authentication needs besides
+// Authorization.
+var AuthHeaders = []string{APIKeyHeader}
+
+// Authenticate is the middleware of the API routes. It accepts a JWT or an
+// API key and attaches the caller's principal to the request context, where
+// the media service finds it. It does nothing when authentication is
+// disabled.
+func (s *impl) Authenticate(c *gin.Context) {
+	if !s.cfg.Auth.Enabled {
+		return
+	}
+
+	principal, err := s.principal(c)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			unauthorized(c, "Invalid credentials")
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Authentication failed"})
+		return
+	}
+	if principal == nil {
+		unauthorized(c, "Authentication required")
+		return
+	}
+
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+}
+
+// principal authenticates the JWT or API key of a request. It returns nil
+// without an error when the request carries no credential.
+func (s *impl) principal(c *gin.Context) (*auth.Principal, error) {
+	credential := c.GetHeader(APIKeyHeader)
+	if credential == "" {
+		credential, _ = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if credential == "" {
+		return nil, nil
+	}
+
+	services := s.svc.GetAuthSvc()
+	if auth.IsAPIKey(credential) {
+		return services.AuthenticateAPIKey(c, credential)
+	}
+	return services.AuthenticateJWT(c, credential)
+}
+
+func unauthorized(c *gin.Context, msg string) {
+	c.Header("WWW-Authenticate", `Bearer realm="media-svc"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+}
+
+// accessDenied responds 403 if err is media.ErrForbidden and reports whether
+// it did.
+func accessDenied(c *gin.Context, err error) bool {
+	if !errors.Is(err, media.ErrForbidden) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	return true
+}
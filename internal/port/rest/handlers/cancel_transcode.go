@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/media"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CancelTranscodeRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+func (s *impl) CancelTranscode(c *gin.Context) {
+
+	var req CancelTranscodeRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	job, err := services.CancelTranscode(c, req.VideoID)
+	if err != nil {
+		if accessDenied(c, err) {
+			return
+		}
+		if errors.Is(err, media.ErrMediaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		if errors.Is(err, media.ErrNoActiveJob) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cancel failed"})
+		return
+	}
+
+	// A processing job stops once its worker sees the request
+	c.JSON(http.StatusAccepted, toTranscodeJob(job))
+}
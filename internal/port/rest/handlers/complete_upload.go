@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/media"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CompleteUploadRequest struct {
+	UploadID string `uri:"upload_id"`
+}
+
+func (s *impl) CompleteUpload(c *gin.Context) {
+
+	var req CompleteUploadRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	res, err := services.CompleteUpload(c, req.UploadID)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrMediaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		case errors.Is(err, media.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, media.ErrUploadNotPending), errors.Is(err, media.ErrUploadIncomplete):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, media.ErrUploadMismatch):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Complete upload failed"})
+		}
+		return
+	}
+
+	// The transcode is dispatched, like after UploadVideo
+	c.JSON(http.StatusAccepted, Media{
+		ID:          res.ID.Hex(),
+		Name:        res.Name,
+		Description: res.Description,
+		Path:        res.Path,
+		Size:        res.Size,
+		ContentType: res.ContentType,
+	})
+}
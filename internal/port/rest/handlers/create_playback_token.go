@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/media"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreatePlaybackTokenRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+type CreatePlaybackTokenBody struct {
+	ExpiresIn    int    `json:"expires_in" binding:"gte=0"` // Seconds, 0 for the configured default
+	IP           string `json:"ip" binding:"omitempty,ip"`
+	BindIP       bool   `json:"bind_ip"` // Bind the token to the IP of this request
+	Referrer     string `json:"referrer"`
+	MaxRendition string `json:"max_rendition"`
+	// The token only plays renditions matching these, e.g. for a tier
+	MaxHeight  int      `json:"max_height" binding:"gte=0"`
+	MaxBitrate string   `json:"max_bitrate"` // e.g. "3000k"
+	Codecs     []string `json:"codecs"`
+	Renditions []string `json:"renditions"`
+}
+
+type CreatePlaybackTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	HLSURL    string    `json:"hls_url"`
+	DASHURL   string    `json:"dash_url,omitempty"`
+}
+
+// CreatePlaybackToken issues a signed token that authorizes streaming one
+// video, and returns the playback URLs carrying it.
+func (s *impl) CreatePlaybackToken(c *gin.Context) {
+
+	var req CreatePlaybackTokenRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The body is optional: without one the token has the default expiry
+	// and no bindings
+	var body CreatePlaybackTokenBody
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	maxBitrate, err := media.ParseBitrate(body.MaxBitrate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.BindIP && body.IP == "" {
+		body.IP = c.ClientIP()
+	}
+
+	services := s.svc.GetMediaSvc()
+	res, err := services.IssuePlaybackToken(c, media.IssuePlaybackTokenInput{
+		MediaID:      req.VideoID,
+		TTL:          time.Duration(body.ExpiresIn) * time.Second,
+		IP:           body.IP,
+		Referrer:     body.Referrer,
+		MaxRendition: body.MaxRendition,
+		Filter: media.ManifestFilter{
+			MaxHeight:  body.MaxHeight,
+			MaxBitrate: maxBitrate,
+			Codecs:     body.Codecs,
+			Renditions: body.Renditions,
+		},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrMediaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		case errors.Is(err, media.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, media.ErrMediaNotReady):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, media.ErrRenditionNotFound), errors.Is(err, media.ErrNoMatchingRenditions):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, media.ErrPlaybackDisabled):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Create playback token failed"})
+		}
+		return
+	}
+
+	resp := CreatePlaybackTokenResponse{
+		Token:     res.Token,
+		ExpiresAt: res.ExpiresAt,
+		HLSURL:    streamURL(res.HLSPath, res.Token),
+	}
+	if res.DASHPath != "" {
+		resp.DASHURL = streamURL(res.DASHPath, res.Token)
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// streamURL returns the stream endpoint URL of a file with a playback token.
+func streamURL(filePath, token string) string {
+	return "/v1/videos/stream/" + filePath + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"media-svc/internal/services/media"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTusUpload implements the tus creation extension. Uploads must
+// declare their length.
+func (s *impl) CreateTusUpload(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader(HeaderUploadLength), 10, 64)
+	if err != nil || length <= 0 {
+		c.String(http.StatusBadRequest, "invalid %s", HeaderUploadLength)
+		return
+	}
+	metadata, err := parseTusMetadata(c.GetHeader(HeaderUploadMetadata))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	upload, err := services.CreateTusUpload(c, media.CreateTusUploadInput{
+		Length:   length,
+		Metadata: metadata,
+	})
+	if err != nil {
+		tusUploadError(c, err)
+		return
+	}
+
+	c.Header("Location", path.Join(c.Request.URL.Path, upload.ID.Hex()))
+	c.Status(http.StatusCreated)
+}
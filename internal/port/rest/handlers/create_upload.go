@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/media"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateUploadRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+	ContentType string `json:"content_type"`
+	Checksum    string `json:"checksum" binding:"omitempty,len=32,hexadecimal"` // Hex MD5 of the file
+	Ladder      string `json:"ladder"`
+}
+
+type UploadPart struct {
+	PartNumber int    `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+type CreateUploadResponse struct {
+	ID        string       `json:"id"`
+	Method    string       `json:"method"`
+	URL       string       `json:"url,omitempty"`
+	Parts     []UploadPart `json:"parts,omitempty"`
+	PartSize  int64        `json:"part_size,omitempty"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// CreateUpload starts a direct-to-storage upload. The client PUTs the file,
+// or each part of it, to the returned URLs and then calls CompleteUpload.
+func (s *impl) CreateUpload(c *gin.Context) {
+
+	var req CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	res, err := services.CreateUpload(c, media.CreateUploadInput{
+		Filename:    req.Filename,
+		Size:        req.Size,
+		ContentType: req.ContentType,
+		Checksum:    req.Checksum,
+		Ladder:      req.Ladder,
+	})
+	if err != nil {
+		if errors.Is(err, media.ErrLadderNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Create upload failed"})
+		return
+	}
+
+	upload := res.Media.Upload
+	resp := CreateUploadResponse{
+		ID:        res.Media.ID.Hex(),
+		Method:    http.MethodPut,
+		URL:       res.URL,
+		PartSize:  upload.PartSize,
+		ExpiresAt: upload.ExpiresAt,
+	}
+	for _, p := range res.Parts {
+		resp.Parts = append(resp.Parts, UploadPart{PartNumber: p.PartNumber, URL: p.URL})
+	}
+	c.JSON(http.StatusCreated, resp)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/media"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeleteMediaRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+// DeleteMedia removes a media with its files and transcode output. A media
+// that is still being transcoded has to be cancelled first.
+func (s *impl) DeleteMedia(c *gin.Context) {
+
+	var req DeleteMediaRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	if err := services.DeleteMedia(c, req.VideoID); err != nil {
+		switch {
+		case errors.Is(err, media.ErrMediaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		case errors.Is(err, media.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, media.ErrJobInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Delete failed"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
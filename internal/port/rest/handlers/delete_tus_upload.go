@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteTusUpload implements the tus termination extension.
+func (s *impl) DeleteTusUpload(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	var req TusUploadRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	if err := services.DeleteTusUpload(c, req.UploadID); err != nil {
+		tusUploadError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -17,13 +17,24 @@ func (s *impl) GetMedia(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// The route shares its wildcard, video_id, with the other video routes
+	if req.MediaID == "" {
+		req.MediaID = c.Param("video_id")
+	}
 
 	services := s.svc.GetMediaSvc()
 	media, err := services.GetMedia(c, req.MediaID)
 	if err != nil {
+		if accessDenied(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload failed"})
 		return
 	}
+	if media == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
 
 	c.JSON(http.StatusOK, Media{
 		ID:          media.ID.Hex(),
@@ -32,5 +43,10 @@ func (s *impl) GetMedia(c *gin.Context) {
 		Path:        media.Path,
 		Size:        media.Size,
 		ContentType: media.ContentType,
+		Duration:    media.Duration,
+		Width:       media.Width,
+		Height:      media.Height,
+		Probe:       media.Probe,
+		OwnerID:     media.OwnerID,
 	})
 }
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"media-svc/internal/services/auth"
+	"media-svc/internal/services/media"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetMediaKeyRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+// GetMediaKey serves the HLS content key of an encrypted video to
+// authorized players.
+func (s *impl) GetMediaKey(c *gin.Context) {
+
+	var req GetMediaKeyRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !s.keyRequestAuthorized(c, req.VideoID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	key, err := services.GetMediaKey(c, req.VideoID)
+	if err != nil {
+		if errors.Is(err, media.ErrMediaKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Get key failed"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/octet-stream", key)
+}
+
+// keyRequestAuthorized checks the credential of a key request. Players send
+// a playback token of the video, in the Authorization header or the "token"
+// query parameter when they cannot set headers. The configured access
+// tokens are accepted too, as is, with authentication enabled, the JWT or
+// API key of a caller who may access the video.
+func (s *impl) keyRequestAuthorized(c *gin.Context, videoID string) bool {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Query("token")
+	}
+
+	for _, allowed := range s.cfg.Transcode.Encryption.AccessTokens {
+		if token != "" && allowed != "" && subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+			return true
+		}
+	}
+
+	if token != "" && s.cfg.Playback.Secret != "" {
+		_, err := s.svc.GetMediaSvc().VerifyPlaybackToken(c, media.VerifyPlaybackTokenInput{
+			Token:    token,
+			MediaID:  videoID,
+			IP:       c.ClientIP(),
+			Referrer: requestReferrer(c),
+		})
+		if err == nil {
+			return true
+		}
+	}
+
+	if s.cfg.Auth.Enabled {
+		principal, err := s.principal(c)
+		if err != nil || principal == nil {
+			return false
+		}
+		ctx := auth.WithPrincipal(c.Request.Context(), principal)
+		found, err := s.svc.GetMediaSvc().GetMedia(ctx, videoID)
+		return err == nil && found != nil
+	}
+	return false
+}
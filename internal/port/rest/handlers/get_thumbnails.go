@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"media-svc/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetThumbnailsRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+type Image struct {
+	URL    string  `json:"url"`
+	Key    string  `json:"key"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	Format string  `json:"format"`
+	Time   float64 `json:"time"`
+}
+
+type GetThumbnailsResponse struct {
+	Poster     []Image `json:"poster"`
+	Thumbnails []Image `json:"thumbnails"`
+}
+
+func (s *impl) GetThumbnails(c *gin.Context) {
+
+	var req GetThumbnailsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	media, err := services.GetMedia(c, req.VideoID)
+	if err != nil {
+		if accessDenied(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Get thumbnails failed"})
+		return
+	}
+	if media == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetThumbnailsResponse{
+		Poster:     toImages(media.Poster),
+		Thumbnails: toImages(media.Thumbnails),
+	})
+}
+
+// toImages maps stored images to responses served by the stream endpoint.
+func toImages(images []models.MediaImage) []Image {
+	out := make([]Image, 0, len(images))
+	for _, img := range images {
+		out = append(out, Image{
+			URL:    "/v1/videos/stream/" + img.Key,
+			Key:    img.Key,
+			Width:  img.Width,
+			Height: img.Height,
+			Format: img.Format,
+			Time:   img.Time,
+		})
+	}
+	return out
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"media-svc/internal/services/media"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,8 +13,11 @@ type GetVideoStatusRequest struct {
 }
 
 type GetVideoStatusResponse struct {
-	Status          string `json:"status"`
-	TranscodeSource string `json:"transcode_source"`
+	Status          string  `json:"status"`
+	TranscodeSource string  `json:"transcode_source"`
+	Stage           string  `json:"stage,omitempty"`
+	Progress        float64 `json:"progress"`
+	ETASeconds      int64   `json:"eta_seconds"`
 }
 
 func (s *impl) GetVideoStatus(c *gin.Context) {
@@ -26,6 +31,13 @@ func (s *impl) GetVideoStatus(c *gin.Context) {
 	services := s.svc.GetMediaSvc()
 	res, err := services.GetVideoStatus(c, req.VideoID)
 	if err != nil {
+		if accessDenied(c, err) {
+			return
+		}
+		if errors.Is(err, media.ErrMediaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload failed"})
 		return
 	}
@@ -33,5 +45,8 @@ func (s *impl) GetVideoStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, GetVideoStatusResponse{
 		Status:          res.Status,
 		TranscodeSource: res.TranscodeSource,
+		Stage:           res.Stage,
+		Progress:        res.Progress,
+		ETASeconds:      res.ETASeconds,
 	})
 }
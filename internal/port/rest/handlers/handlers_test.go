@@ -0,0 +1,64 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"media-svc/config"
+	"media-svc/internal/adapters/inmemory"
+	"media-svc/internal/port/rest/handlers"
+	"media-svc/internal/port/rest/routes"
+	"media-svc/internal/services"
+	"media-svc/internal/services/media"
+	"media-svc/pkgs/scratch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter serves the v1 routes over the in-memory adapters with
+// authentication disabled.
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	mediaService := media.NewService(cfg, inmemory.NewMediaRepository(), inmemory.NewStorage("media"),
+		inmemory.NewStorage("stream"), inmemory.NewPublisher(), scratch.New(t.TempDir()))
+
+	r := gin.New()
+	routes.RegisterV1Routes(r.Group(""), handlers.NewHandler(cfg, services.NewServiceWith(cfg, mediaService, nil)))
+	return r
+}
+
+func TestMalformedVideoID(t *testing.T) {
+	r := newTestRouter(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/v1/videos/not-hex", ""},
+		{http.MethodPatch, "/v1/videos/not-hex", `{"name":"renamed"}`},
+		{http.MethodDelete, "/v1/videos/not-hex", ""},
+		{http.MethodGet, "/v1/videos/not-hex/status", ""},
+		{http.MethodGet, "/v1/videos/not-hex/thumbnails", ""},
+		{http.MethodGet, "/v1/videos/not-hex/jobs", ""},
+		{http.MethodPost, "/v1/videos/not-hex/transcode", ""},
+		{http.MethodPost, "/v1/videos/not-hex/transcode/cancel", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TusUploadRequest struct {
+	UploadID string `uri:"upload_id"`
+}
+
+// HeadTusUpload returns the offset a client resumes an upload from.
+func (s *impl) HeadTusUpload(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	var req TusUploadRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	upload, err := services.GetTusUpload(c, req.UploadID)
+	if err != nil {
+		tusUploadError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header(HeaderUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	c.Header(HeaderUploadLength, strconv.FormatInt(upload.Length, 10))
+	if len(upload.Metadata) > 0 {
+		c.Header(HeaderUploadMetadata, formatTusMetadata(upload.Metadata))
+	}
+	if upload.MediaID != "" {
+		c.Header(HeaderMediaID, upload.MediaID)
+	}
+	c.Status(http.StatusOK)
+}
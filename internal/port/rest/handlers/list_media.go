@@ -8,7 +8,12 @@ import (
 )
 
 type ListMediaRequest struct {
-	MediaID string `uri:"media_id"`
+	Keyword     string  `form:"keyword"`
+	VideoCodec  string  `form:"video_codec"`
+	HDR         *bool   `form:"hdr"`
+	MinHeight   int     `form:"min_height"`
+	MinDuration float64 `form:"min_duration"`
+	MaxDuration float64 `form:"max_duration"`
 }
 
 func (s *impl) ListMedia(c *gin.Context) {
@@ -20,7 +25,14 @@ func (s *impl) ListMedia(c *gin.Context) {
 	}
 
 	services := s.svc.GetMediaSvc()
-	medias, err := services.ListMedia(c, media.ListMediaInput{})
+	medias, err := services.ListMedia(c, media.ListMediaInput{
+		Keyword:     req.Keyword,
+		VideoCodec:  req.VideoCodec,
+		HDR:         req.HDR,
+		MinHeight:   req.MinHeight,
+		MinDuration: req.MinDuration,
+		MaxDuration: req.MaxDuration,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload failed"})
 		return
@@ -36,6 +48,11 @@ func (s *impl) ListMedia(c *gin.Context) {
 			Path:        media.Path,
 			Size:        media.Size,
 			ContentType: media.ContentType,
+			Duration:    media.Duration,
+			Width:       media.Width,
+			Height:      media.Height,
+			Probe:       media.Probe,
+			OwnerID:     media.OwnerID,
 		})
 	}
 
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"media-svc/internal/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ListTranscodeJobsRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+type StageTiming struct {
+	Stage      string     `json:"stage"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+}
+
+type TranscodeJob struct {
+	ID              string                   `json:"id"`
+	Attempt         int                      `json:"attempt"`
+	Status          string                   `json:"status"`
+	Stage           string                   `json:"stage,omitempty"`
+	Ladder          string                   `json:"ladder,omitempty"`
+	Options         *models.TranscodeOptions `json:"options,omitempty"`
+	WorkerID        string                   `json:"worker_id,omitempty"`
+	OutputPath      string                   `json:"output_path,omitempty"`
+	Error           string                   `json:"error,omitempty"`
+	ExitCode        *int                     `json:"exit_code,omitempty"`
+	StderrTail      string                   `json:"stderr_tail,omitempty"`
+	CancelRequested bool                     `json:"cancel_requested,omitempty"`
+	StageTimings    []StageTiming            `json:"stage_timings"`
+	StartedAt       *time.Time               `json:"started_at,omitempty"`
+	FinishedAt      *time.Time               `json:"finished_at,omitempty"`
+	CreatedAt       time.Time                `json:"created_at"`
+}
+
+type ListTranscodeJobsResponse struct {
+	Jobs []TranscodeJob `json:"jobs"`
+}
+
+func (s *impl) ListTranscodeJobs(c *gin.Context) {
+
+	var req ListTranscodeJobsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	media, err := services.GetMedia(c, req.VideoID)
+	if err != nil {
+		if accessDenied(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "List jobs failed"})
+		return
+	}
+	if media == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	jobs, err := services.ListTranscodeJobs(c, req.VideoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "List jobs failed"})
+		return
+	}
+
+	res := ListTranscodeJobsResponse{Jobs: make([]TranscodeJob, 0, len(jobs))}
+	for _, job := range jobs {
+		res.Jobs = append(res.Jobs, toTranscodeJob(job))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func toTranscodeJob(job *models.TranscodeJob) TranscodeJob {
+	timings := make([]StageTiming, 0, len(job.StageTimings))
+	for _, t := range job.StageTimings {
+		timing := StageTiming{
+			Stage:      t.Stage,
+			StartedAt:  t.StartedAt,
+			FinishedAt: t.FinishedAt,
+		}
+		if t.FinishedAt != nil {
+			timing.DurationMs = t.FinishedAt.Sub(t.StartedAt).Milliseconds()
+		}
+		timings = append(timings, timing)
+	}
+
+	return TranscodeJob{
+		ID:              job.ID.Hex(),
+		Attempt:         job.Attempt,
+		Status:          job.Status,
+		Stage:           job.Stage,
+		Ladder:          job.Ladder,
+		Options:         job.Options,
+		WorkerID:        job.WorkerID,
+		OutputPath:      job.OutputPath,
+		Error:           job.Error,
+		ExitCode:        job.ExitCode,
+		StderrTail:      job.StderrTail,
+		CancelRequested: job.CancelRequested,
+		StageTimings:    timings,
+		StartedAt:       job.StartedAt,
+		FinishedAt:      job.FinishedAt,
+		CreatedAt:       job.CreatedAt,
+	}
+}
@@ -1,10 +1,17 @@
 package handlers
 
+import "media-svc/internal/models"
+
 type Media struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Path        string `json:"path"`
-	Size        int64  `json:"size"`
-	ContentType string `json:"content_type"`
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Path        string             `json:"path"`
+	Size        int64              `json:"size"`
+	ContentType string             `json:"content_type"`
+	Duration    float64            `json:"duration,omitempty"`
+	Width       int                `json:"width,omitempty"`
+	Height      int                `json:"height,omitempty"`
+	Probe       *models.MediaProbe `json:"probe,omitempty"`
+	OwnerID     string             `json:"owner_id,omitempty"`
 }
@@ -1,15 +1,18 @@
 package handlers
 
 import (
+	"media-svc/config"
 	"media-svc/internal/services"
 )
 
 type impl struct {
+	cfg *config.Config
 	svc *services.Service
 }
 
-func NewHandler(svc *services.Service) Handler {
+func NewHandler(cfg *config.Config, svc *services.Service) Handler {
 	return &impl{
+		cfg: cfg,
 		svc: svc,
 	}
 }
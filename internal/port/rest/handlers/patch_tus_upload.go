@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"media-svc/internal/services/media"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PatchTusUpload appends the request body to an upload at Upload-Offset.
+func (s *impl) PatchTusUpload(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	var req TusUploadRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if ct, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type")); ct != tusContentType {
+		c.AbortWithStatus(http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader(HeaderUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		c.String(http.StatusBadRequest, "invalid %s", HeaderUploadOffset)
+		return
+	}
+
+	input := media.WriteTusUploadInput{
+		ID:     req.UploadID,
+		Offset: offset,
+		Body:   c.Request.Body,
+	}
+	if header := c.GetHeader(HeaderUploadChecksum); header != "" {
+		algorithm, encoded, _ := strings.Cut(header, " ")
+		checksum, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || algorithm == "" {
+			c.String(http.StatusBadRequest, "invalid %s", HeaderUploadChecksum)
+			return
+		}
+		input.ChecksumAlgorithm = algorithm
+		input.Checksum = checksum
+	}
+
+	services := s.svc.GetMediaSvc()
+	upload, err := services.WriteTusUpload(c, input)
+	if err != nil {
+		tusUploadError(c, err)
+		return
+	}
+
+	c.Header(HeaderUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	if upload.MediaID != "" {
+		c.Header(HeaderMediaID, upload.MediaID)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RetranscodeVideoRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+type RetranscodeVideoBody struct {
+	Ladder  string                  `json:"ladder"`
+	Options *types.TranscodeOptions `json:"options"` // Codec settings overriding the ladder
+}
+
+func (s *impl) RetranscodeVideo(c *gin.Context) {
+
+	var req RetranscodeVideoRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The body is optional: without one the default ladder is used as is
+	var body RetranscodeVideoBody
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	services := s.svc.GetMediaSvc()
+	job, err := services.RetranscodeVideo(c, media.RetranscodeVideoInput{
+		MediaID: req.VideoID,
+		Ladder:  body.Ladder,
+		Options: body.Options,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrMediaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		case errors.Is(err, media.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, media.ErrLadderNotFound), errors.Is(err, media.ErrInvalidOptions):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, media.ErrJobInProgress), errors.Is(err, media.ErrUploadPending):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Transcode failed"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, toTranscodeJob(job))
+}
@@ -3,7 +3,25 @@ package handlers
 import "github.com/gin-gonic/gin"
 
 type Handler interface {
+	Authenticate(c *gin.Context)
 	UploadVideo(c *gin.Context)
 	Stream(c *gin.Context)
 	GetVideoStatus(c *gin.Context)
+	GetMedia(c *gin.Context)
+	ListMedia(c *gin.Context)
+	UpdateMedia(c *gin.Context)
+	DeleteMedia(c *gin.Context)
+	GetMediaKey(c *gin.Context)
+	CreatePlaybackToken(c *gin.Context)
+	GetThumbnails(c *gin.Context)
+	ListTranscodeJobs(c *gin.Context)
+	RetranscodeVideo(c *gin.Context)
+	CancelTranscode(c *gin.Context)
+	CreateUpload(c *gin.Context)
+	CompleteUpload(c *gin.Context)
+	TusOptions(c *gin.Context)
+	CreateTusUpload(c *gin.Context)
+	HeadTusUpload(c *gin.Context)
+	PatchTusUpload(c *gin.Context)
+	DeleteTusUpload(c *gin.Context)
 }
@@ -1,52 +1,222 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
-	"log"
 	"media-svc/internal/services/media"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func (s *impl) Stream(c *gin.Context) {
+// Cache-Control of streamed files. Every transcode attempt writes under a
+// prefix of its own (<media_id>/<job_id>), so a segment URL always serves
+// the same bytes and can be cached for good. Playlists stay short-lived, as
+// they are the entry points a re-transcode moves to the new prefix.
+const (
+	playlistCacheControl = "public, max-age=5"
+	segmentCacheControl  = "public, max-age=31536000, immutable"
+	defaultCacheControl  = "public, max-age=300"
+	// Files served with a playback token are private to its holder, so
+	// shared caches do not hand them out without one.
+	tokenPlaylistCacheControl = "private, max-age=5"
+	tokenSegmentCacheControl  = "private, max-age=31536000, immutable"
+	tokenDefaultCacheControl  = "private, max-age=300"
+)
 
-	filePath := c.Param("file_path")
-	filePath = strings.TrimPrefix(filePath, "/")
+// StreamHeaders are the request headers of ranged and conditional stream
+// requests, for CORS.
+var StreamHeaders = []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since"}
 
-	services := s.svc.GetMediaSvc()
-	presignedURL, err := services.PresignGetStreamObject(c, media.PresignGetObjectInput{
-		FilePath: filePath,
-	})
+// StreamExposedHeaders are the response headers players read from a stream
+// response, for CORS.
+var StreamExposedHeaders = []string{"Accept-Ranges", "Content-Range", "ETag", "Last-Modified"}
+
+// Stream serves a file of a transcoded video from the stream bucket. Range
+// requests get a 206 with the requested bytes, and If-None-Match or
+// If-Modified-Since a 304 when the file has not changed. When playback
+// tokens are configured, the request needs one in the "token" query
+// parameter, and playlists are rewritten to pass it on. The max_height,
+// max_bitrate, codecs and renditions parameters drop variants from the
+// master playlist and DASH manifest.
+func (s *impl) Stream(c *gin.Context) {
+
+	filePath := strings.TrimPrefix(c.Param("file_path"), "/")
+	token := c.Query("token")
+	filter, err := manifestFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload failed"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	resp, err := http.Get(presignedURL)
+	services := s.svc.GetMediaSvc()
+	var claims *media.PlaybackClaims
+	if s.playbackTokenRequired(filePath) {
+		claims, err = services.VerifyPlaybackToken(c, media.VerifyPlaybackTokenInput{
+			Token:    token,
+			FilePath: filePath,
+			IP:       c.ClientIP(),
+			Referrer: requestReferrer(c),
+		})
+		if err != nil {
+			playbackTokenError(c, err)
+			return
+		}
+	}
+
+	reader, info, err := services.OpenStreamObject(c, filePath)
 	if err != nil {
-		c.String(http.StatusBadGateway, "Failed to get object from MinIO: %v", err)
+		if errors.Is(err, media.ErrStreamObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stream failed"})
 		return
 	}
-	defer resp.Body.Close()
+	defer reader.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.String(resp.StatusCode, "MinIO responded with status: %v", resp.Status)
+	header := c.Writer.Header()
+	if media.IsPlaylist(filePath) && (claims != nil || !filter.IsZero()) {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Stream failed"})
+			return
+		}
+		data, err = services.RewritePlaylist(c, media.RewritePlaylistInput{
+			FilePath: filePath,
+			Data:     data,
+			Token:    token,
+			Claims:   claims,
+			Filter:   filter,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, media.ErrMediaNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			case errors.Is(err, media.ErrNoMatchingRenditions):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Stream failed"})
+			}
+			return
+		}
+
+		if info.ContentType != "" {
+			header.Set("Content-Type", info.ContentType)
+		}
+		cacheControl := playlistCacheControl
+		if claims != nil {
+			cacheControl = tokenPlaylistCacheControl
+		}
+		header.Set("Cache-Control", cacheControl)
+		http.ServeContent(c.Writer, c.Request, path.Base(filePath), info.LastModified, bytes.NewReader(data))
 		return
 	}
 
-	// Copy header content-type từ MinIO sang client
-	for k, vv := range resp.Header {
-		for _, v := range vv {
-			c.Writer.Header().Add(k, v)
+	if info.ContentType != "" {
+		header.Set("Content-Type", info.ContentType)
+	}
+	if etag := strings.Trim(info.ETag, `"`); etag != "" {
+		header.Set("ETag", `"`+etag+`"`)
+	}
+	header.Set("Cache-Control", streamCacheControl(filePath, claims != nil))
+
+	http.ServeContent(c.Writer, c.Request, path.Base(filePath), info.LastModified, reader)
+}
+
+// manifestFilter reads the variant filter of a stream request. codecs and
+// renditions are comma separated lists.
+func manifestFilter(c *gin.Context) (media.ManifestFilter, error) {
+	var filter media.ManifestFilter
+	if v := c.Query("max_height"); v != "" {
+		height, err := strconv.Atoi(v)
+		if err != nil || height <= 0 {
+			return filter, fmt.Errorf("invalid max_height %q", v)
+		}
+		filter.MaxHeight = height
+	}
+	if v := c.Query("max_bitrate"); v != "" {
+		bitrate, err := media.ParseBitrate(v)
+		if err != nil || bitrate <= 0 {
+			return filter, fmt.Errorf("invalid max_bitrate %q", v)
+		}
+		filter.MaxBitrate = bitrate
+	}
+	filter.Codecs = queryList(c, "codecs")
+	filter.Renditions = queryList(c, "renditions")
+	return filter, nil
+}
+
+// queryList splits a comma separated query parameter.
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, v := range strings.Split(c.Query(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
 		}
 	}
+	return values
+}
 
-	// Stream dữ liệu về client
-	c.Status(http.StatusOK)
-	_, err = io.Copy(c.Writer, resp.Body)
-	if err != nil {
-		log.Printf("Error streaming object. Error: %v", err)
+// playbackTokenRequired reports whether a stream file is only served with a
+// playback token. Images such as posters and trickplay sprites are public.
+func (s *impl) playbackTokenRequired(filePath string) bool {
+	if s.cfg.Playback.Secret == "" {
+		return false
+	}
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return false
+	default:
+		return true
+	}
+}
+
+// requestReferrer returns the Referer of a request, or its Origin for
+// players that only send that.
+func requestReferrer(c *gin.Context) string {
+	if referrer := c.GetHeader("Referer"); referrer != "" {
+		return referrer
+	}
+	return c.GetHeader("Origin")
+}
+
+// playbackTokenError writes the response for a rejected playback token.
+func playbackTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrPlaybackForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, media.ErrPlaybackTokenMissing), errors.Is(err, media.ErrPlaybackTokenInvalid),
+		errors.Is(err, media.ErrPlaybackTokenExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stream failed"})
+	}
+}
+
+// streamCacheControl returns the Cache-Control of a streamed file by its
+// extension, private when it was served with a playback token.
+func streamCacheControl(filePath string, private bool) string {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".m3u8", ".mpd":
+		if private {
+			return tokenPlaylistCacheControl
+		}
+		return playlistCacheControl
+	case ".ts", ".m4s", ".mp4", ".m4a", ".m4v", ".aac", ".cmfv", ".cmfa":
+		if private {
+			return tokenSegmentCacheControl
+		}
+		return segmentCacheControl
+	default:
+		if private {
+			return tokenDefaultCacheControl
+		}
+		return defaultCacheControl
 	}
 }
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"media-svc/internal/services/media"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tus 1.0 protocol headers, see https://tus.io/protocols/resumable-upload
+const (
+	TusVersion    = "1.0.0"
+	TusExtensions = "creation,termination,checksum"
+
+	HeaderTusResumable         = "Tus-Resumable"
+	HeaderTusVersion           = "Tus-Version"
+	HeaderTusExtension         = "Tus-Extension"
+	HeaderTusMaxSize           = "Tus-Max-Size"
+	HeaderTusChecksumAlgorithm = "Tus-Checksum-Algorithm"
+	HeaderUploadLength         = "Upload-Length"
+	HeaderUploadOffset         = "Upload-Offset"
+	HeaderUploadMetadata       = "Upload-Metadata"
+	HeaderUploadChecksum       = "Upload-Checksum"
+	// HeaderMediaID carries the ID of the media created from a finished
+	// upload. It is not part of tus.
+	HeaderMediaID = "X-Media-Id"
+
+	tusContentType = "application/offset+octet-stream"
+	// statusChecksumMismatch is the tus checksum extension status for a
+	// body that does not match its Upload-Checksum.
+	statusChecksumMismatch = 460
+)
+
+// TusHeaders are the request headers tus clients send, for CORS.
+var TusHeaders = []string{HeaderTusResumable, HeaderUploadLength, HeaderUploadOffset, HeaderUploadMetadata, HeaderUploadChecksum}
+
+// TusExposedHeaders are the response headers tus clients read, for CORS.
+var TusExposedHeaders = []string{"Location", HeaderTusResumable, HeaderTusVersion, HeaderTusExtension, HeaderTusMaxSize,
+	HeaderTusChecksumAlgorithm, HeaderUploadLength, HeaderUploadOffset, HeaderUploadMetadata, HeaderMediaID}
+
+// TusOptions describes the tus server. It is the only tus request that may
+// omit Tus-Resumable.
+func (s *impl) TusOptions(c *gin.Context) {
+	c.Header(HeaderTusResumable, TusVersion)
+	c.Header(HeaderTusVersion, TusVersion)
+	c.Header(HeaderTusExtension, TusExtensions)
+	c.Header(HeaderTusChecksumAlgorithm, strings.Join(media.TusChecksumAlgorithms, ","))
+	if maxSize := s.cfg.Upload.Tus.MaxSizeMB << 20; maxSize > 0 {
+		c.Header(HeaderTusMaxSize, strconv.FormatInt(maxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkTusResumable sets the Tus-Resumable response header and rejects
+// requests of another protocol version.
+func checkTusResumable(c *gin.Context) bool {
+	c.Header(HeaderTusResumable, TusVersion)
+	if c.GetHeader(HeaderTusResumable) != TusVersion {
+		c.Header(HeaderTusVersion, TusVersion)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// parseTusMetadata decodes an Upload-Metadata header: comma separated keys,
+// each followed by a space and its base64 value unless it has none.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("invalid %s", HeaderUploadMetadata)
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value of %q", HeaderUploadMetadata, key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// formatTusMetadata encodes metadata as an Upload-Metadata header, sorted
+// by key.
+func formatTusMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if metadata[key] == "" {
+			pairs = append(pairs, key)
+			continue
+		}
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(metadata[key])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// tusUploadError writes the response of a failed tus request.
+func tusUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrUploadNotFound):
+		c.AbortWithStatus(http.StatusNotFound)
+	case errors.Is(err, media.ErrForbidden):
+		c.AbortWithStatus(http.StatusForbidden)
+	case errors.Is(err, media.ErrOffsetMismatch):
+		c.AbortWithStatus(http.StatusConflict)
+	case errors.Is(err, media.ErrUploadLocked):
+		c.AbortWithStatus(http.StatusLocked)
+	case errors.Is(err, media.ErrUploadTooLarge):
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+	case errors.Is(err, media.ErrChecksumMismatch):
+		c.AbortWithStatus(statusChecksumMismatch)
+	case errors.Is(err, media.ErrUnsupportedChecksum), errors.Is(err, media.ErrLadderNotFound):
+		c.String(http.StatusBadRequest, err.Error())
+	default:
+		c.String(http.StatusInternalServerError, "Upload failed")
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"media-svc/internal/services/media"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateMediaRequest struct {
+	VideoID string `uri:"video_id"`
+}
+
+// UpdateMediaBody holds the fields to change; omitted fields are kept.
+type UpdateMediaBody struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+}
+
+func (s *impl) UpdateMedia(c *gin.Context) {
+
+	var req UpdateMediaRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body UpdateMediaBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services := s.svc.GetMediaSvc()
+	res, err := services.UpdateMedia(c, media.UpdateMediaInput{
+		ID:          req.VideoID,
+		Name:        body.Name,
+		Description: body.Description,
+		Tags:        body.Tags,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrMediaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		case errors.Is(err, media.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Update failed"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, Media{
+		ID:          res.ID.Hex(),
+		Name:        res.Name,
+		Description: res.Description,
+		Path:        res.Path,
+		Size:        res.Size,
+		ContentType: res.ContentType,
+		Duration:    res.Duration,
+		Width:       res.Width,
+		Height:      res.Height,
+		Probe:       res.Probe,
+		OwnerID:     res.OwnerID,
+	})
+}
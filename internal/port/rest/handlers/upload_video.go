@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"media-svc/internal/services/media"
 	"net/http"
 
@@ -15,20 +16,25 @@ func (s *impl) UploadVideo(c *gin.Context) {
 	}
 
 	services := s.svc.GetMediaSvc()
-	media, err := services.UploadVideo(c, media.UploadVideoInput{
-		File: file,
+	res, err := services.UploadVideo(c, media.UploadVideoInput{
+		File:   file,
+		Ladder: c.PostForm("ladder"),
 	})
 	if err != nil {
+		if errors.Is(err, media.ErrLadderNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload failed"})
 		return
 	}
 
 	c.JSON(http.StatusOK, Media{
-		ID:          media.ID.Hex(),
-		Name:        media.Name,
-		Description: media.Description,
-		Path:        media.Path,
-		Size:        media.Size,
-		ContentType: media.ContentType,
+		ID:          res.ID.Hex(),
+		Name:        res.Name,
+		Description: res.Description,
+		Path:        res.Path,
+		Size:        res.Size,
+		ContentType: res.ContentType,
 	})
 }
@@ -3,9 +3,12 @@ package rest
 import (
 	"log"
 	"media-svc/config"
+	"media-svc/internal/adapters/localfs"
 	"media-svc/internal/port/rest/handlers"
 	"media-svc/internal/port/rest/routes"
 	"media-svc/internal/services"
+	"net/http"
+	"slices"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -25,19 +28,31 @@ func NewRestServer(cfg *config.Config, svc *services.Service) *RestServer {
 
 func (s *RestServer) Run() {
 	r := gin.Default()
+	// Let the services read values of the request context, such as the
+	// authenticated principal, through the gin context
+	r.ContextWithFallback = true
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		AllowHeaders:     slices.Concat([]string{"Origin", "Content-Type", "Accept", "Authorization"}, handlers.AuthHeaders, handlers.TusHeaders, handlers.StreamHeaders),
+		ExposeHeaders:    slices.Concat([]string{"Content-Length"}, handlers.TusExposedHeaders, handlers.StreamExposedHeaders),
 		AllowCredentials: true,
 	}))
 
-	handler := handlers.NewHandler(s.svc)
+	handler := handlers.NewHandler(s.cfg, s.svc)
 
 	routes.RegisterV1Routes(r.Group("/"), handler)
 
+	// Presigned URLs of the filesystem storage point back to this server
+	if s.cfg.Storage.Driver == services.StorageDriverFS {
+		storageSvr, err := localfs.NewServer(s.cfg)
+		if err != nil {
+			panic(err)
+		}
+		r.Any(localfs.RoutePrefix+"/*object", gin.WrapH(http.StripPrefix(localfs.RoutePrefix, storageSvr)))
+	}
+
 	log.Println("🚀 REST server running at :", s.cfg.Server.HttpPort)
 
 	err := r.Run(":8080")
@@ -9,11 +9,46 @@ import (
 func RegisterV1Routes(r *gin.RouterGroup, handler handlers.Handler) {
 	v1 := r.Group("v1")
 	v1VideoRoutes(v1, handler)
+	v1UploadRoutes(v1, handler)
+	v1TusRoutes(v1, handler)
 }
 
 func v1VideoRoutes(r *gin.RouterGroup, handler handlers.Handler) {
 	videoRoutes := r.Group("videos")
-	videoRoutes.POST("/upload", handler.UploadVideo)
-	videoRoutes.GET("/:video_id/status", handler.GetVideoStatus)
+	// Players authorize streams and keys with playback tokens instead
 	videoRoutes.GET("/stream/*file_path", handler.Stream)
+	videoRoutes.HEAD("/stream/*file_path", handler.Stream)
+	videoRoutes.GET("/:video_id/key", handler.GetMediaKey)
+
+	authRoutes := videoRoutes.Group("", handler.Authenticate)
+	authRoutes.GET("", handler.ListMedia)
+	authRoutes.POST("/upload", handler.UploadVideo)
+	authRoutes.GET("/:video_id", handler.GetMedia)
+	authRoutes.PATCH("/:video_id", handler.UpdateMedia)
+	authRoutes.DELETE("/:video_id", handler.DeleteMedia)
+	authRoutes.GET("/:video_id/status", handler.GetVideoStatus)
+	authRoutes.POST("/:video_id/playback-token", handler.CreatePlaybackToken)
+	authRoutes.GET("/:video_id/thumbnails", handler.GetThumbnails)
+	authRoutes.GET("/:video_id/jobs", handler.ListTranscodeJobs)
+	authRoutes.POST("/:video_id/transcode", handler.RetranscodeVideo)
+	authRoutes.POST("/:video_id/transcode/cancel", handler.CancelTranscode)
+}
+
+func v1UploadRoutes(r *gin.RouterGroup, handler handlers.Handler) {
+	uploadRoutes := r.Group("uploads", handler.Authenticate)
+	uploadRoutes.POST("", handler.CreateUpload)
+	uploadRoutes.POST("/:upload_id/complete", handler.CompleteUpload)
+}
+
+func v1TusRoutes(r *gin.RouterGroup, handler handlers.Handler) {
+	tusRoutes := r.Group("tus")
+	// Capability discovery is not authenticated
+	tusRoutes.OPTIONS("", handler.TusOptions)
+	tusRoutes.OPTIONS("/:upload_id", handler.TusOptions)
+
+	authRoutes := tusRoutes.Group("", handler.Authenticate)
+	authRoutes.POST("", handler.CreateTusUpload)
+	authRoutes.HEAD("/:upload_id", handler.HeadTusUpload)
+	authRoutes.PATCH("/:upload_id", handler.PatchTusUpload)
+	authRoutes.DELETE("/:upload_id", handler.DeleteTusUpload)
 }
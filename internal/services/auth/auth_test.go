@@ -0,0 +1,114 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"media-svc/config"
+	"media-svc/internal/adapters/inmemory"
+	"media-svc/internal/services/auth"
+)
+
+const testSecret = "test-secret"
+
+func newTestService(t *testing.T) auth.AuthService {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Auth.Enabled = true
+	cfg.Auth.JWT.HS256Secret = testSecret
+	cfg.Auth.JWT.Audience = "media-svc"
+
+	svc, err := auth.NewService(cfg, inmemory.NewAuthRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+// hs256Token signs claims with the test secret.
+func hs256Token(claims map[string]any) string {
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(body)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(signed))
+	return signed + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	svc := newTestService(t)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	p, err := svc.AuthenticateJWT(context.Background(), hs256Token(map[string]any{
+		"sub": "alice", "aud": "media-svc", "exp": exp, "roles": []string{"admin"},
+	}))
+	if err != nil {
+		t.Fatalf("AuthenticateJWT: %v", err)
+	}
+	if p.Subject != "alice" || !p.Admin || p.Method != auth.MethodJWT {
+		t.Errorf("principal = %+v", p)
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+	}{
+		{name: "no subject", claims: map[string]any{"aud": "media-svc", "exp": exp}},
+		{name: "wrong audience", claims: map[string]any{"sub": "alice", "aud": "other", "exp": exp}},
+		{name: "no expiry", claims: map[string]any{"sub": "alice", "aud": "media-svc"}},
+		{name: "expired", claims: map[string]any{"sub": "alice", "aud": "media-svc", "exp": time.Now().Add(-time.Hour).Unix()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.AuthenticateJWT(context.Background(), hs256Token(tt.claims)); !errors.Is(err, auth.ErrInvalidCredentials) {
+				t.Errorf("error = %v, want %v", err, auth.ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := svc.CreateAPIKey(ctx, auth.CreateAPIKeyInput{Name: "ci"}); !errors.Is(err, auth.ErrAPIKeyOwner) {
+		t.Errorf("CreateAPIKey without owner error = %v", err)
+	}
+
+	created, err := svc.CreateAPIKey(ctx, auth.CreateAPIKeyInput{Name: "ci", OwnerID: "alice"})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if !auth.IsAPIKey(created.Key) || created.APIKey.Hash == created.Key {
+		t.Fatalf("created = %+v", created)
+	}
+
+	p, err := svc.AuthenticateAPIKey(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey: %v", err)
+	}
+	if p.Subject != "alice" || p.Admin || p.Method != auth.MethodAPIKey {
+		t.Errorf("principal = %+v", p)
+	}
+	if _, err := svc.AuthenticateAPIKey(ctx, created.Key+"x"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("unknown key error = %v", err)
+	}
+
+	if err := svc.RevokeAPIKey(ctx, created.APIKey.ID.Hex()); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if err := svc.RevokeAPIKey(ctx, created.APIKey.ID.Hex()); !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Errorf("second RevokeAPIKey error = %v", err)
+	}
+	if _, err := svc.AuthenticateAPIKey(ctx, created.Key); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("revoked key error = %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrInvalidCredentials is returned for a token or API key that is malformed,
+// expired, revoked or unknown.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// AuthenticateJWT verifies a bearer token. Its subject owns the media the
+// caller uploads.
+func (i *impl) AuthenticateJWT(ctx context.Context, token string) (*Principal, error) {
+
+	claims, err := i.verifier.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if claims.Subject() == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	roles := claims.Strings(i.rolesClaim)
+	return &Principal{
+		Subject: claims.Subject(),
+		Roles:   roles,
+		Method:  MethodJWT,
+		Admin:   slices.Contains(roles, i.adminRole),
+	}, nil
+}
+
+// AuthenticateAPIKey looks up an API key by its hash. The key acts as its
+// owner with the roles it was created with.
+func (i *impl) AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+
+	if !IsAPIKey(key) {
+		return nil, fmt.Errorf("%w: malformed api key", ErrInvalidCredentials)
+	}
+
+	apiKey, err := i.repo.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+	if apiKey == nil || apiKey.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: unknown or revoked api key", ErrInvalidCredentials)
+	}
+
+	return &Principal{
+		Subject: apiKey.OwnerID,
+		Roles:   apiKey.Roles,
+		Method:  MethodAPIKey,
+		Admin:   slices.Contains(apiKey.Roles, i.adminRole),
+	}, nil
+}
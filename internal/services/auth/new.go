@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"fmt"
+	"media-svc/config"
+	"media-svc/pkgs/jwt"
+)
+
+// Defaults of the auth config.
+const (
+	defaultAdminRole  = "admin"
+	defaultRolesClaim = "roles"
+)
+
+type impl struct {
+	cfg        *config.Config
+	repo       AuthRepository
+	verifier   *jwt.Verifier
+	adminRole  string
+	rolesClaim string
+}
+
+// NewService returns the auth service. It fails if the JWKS file of the
+// config cannot be read.
+func NewService(cfg *config.Config, repo AuthRepository) (AuthService, error) {
+
+	jwtCfg := cfg.Auth.JWT
+	opts := []jwt.Option{
+		jwt.WithIssuer(jwtCfg.Issuer),
+		jwt.WithAudience(jwtCfg.Audience),
+		jwt.WithLeeway(jwtCfg.Leeway),
+	}
+	if jwtCfg.HS256Secret != "" {
+		opts = append(opts, jwt.WithHMACSecret([]byte(jwtCfg.HS256Secret)))
+	}
+	if jwtCfg.JWKSFile != "" {
+		keys, err := jwt.LoadJWKS(jwtCfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		opts = append(opts, jwt.WithRSAKeys(keys))
+	}
+
+	adminRole := cfg.Auth.AdminRole
+	if adminRole == "" {
+		adminRole = defaultAdminRole
+	}
+	rolesClaim := jwtCfg.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = defaultRolesClaim
+	}
+
+	return &impl{
+		cfg:        cfg,
+		repo:       repo,
+		verifier:   jwt.NewVerifier(opts...),
+		adminRole:  adminRole,
+		rolesClaim: rolesClaim,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package auth
+
+import "context"
+
+// How a principal authenticated.
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string // Owner ID of the media the caller uploads
+	Roles   []string
+	Method  string
+	Admin   bool // Whether the caller may access the media of every owner
+}
+
+// CanAccess reports whether the principal may read or change media owned by
+// ownerID. Media without an owner, uploaded before authentication was
+// enabled, are left to admins.
+func (p *Principal) CanAccess(ownerID string) bool {
+	return p.Admin || (ownerID != "" && ownerID == p.Subject)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal of a request, or nil when the
+// request was not authenticated, e.g. because authentication is disabled or
+// the caller is the service itself.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
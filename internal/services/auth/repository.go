@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"context"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuthRepository is the persistence the auth service depends on,
+// implemented by the MongoDB repository and the in-memory fake.
+type AuthRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, ownerID string) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error)
+}
@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"context"
+	"media-svc/internal/models"
+)
+
+type AuthService interface {
+	AuthenticateJWT(ctx context.Context, token string) (*Principal, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error)
+
+	CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*CreateAPIKeyOutput, error)
+	ListAPIKeys(ctx context.Context, ownerID string) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
@@ -0,0 +1,46 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-svc/internal/adapters/mongodb/media"
+	"media-svc/internal/models"
+)
+
+// maxAttemptConflicts bounds how often a job is renumbered when concurrent
+// jobs of the same media take its attempt number first.
+const maxAttemptConflicts = 5
+
+// createAttempt numbers a new job after the latest attempt of its media and
+// stores it with create. The (media_id, attempt) index is unique, so a job
+// started at the same time may take the number first; the latest attempt is
+// then read again and the next number tried. check, when set, may refuse the
+// job based on the latest attempt.
+func (i *impl) createAttempt(ctx context.Context, job *models.TranscodeJob, check func(prev *models.TranscodeJob) error, create func(context.Context, *models.TranscodeJob) error) error {
+	for try := 1; ; try++ {
+		prev, err := i.mediaRepo.GetTranscodeJobByMediaID(ctx, job.MediaID.Hex())
+		if err != nil {
+			return fmt.Errorf("get transcode job: %w", err)
+		}
+		if check != nil {
+			if err := check(prev); err != nil {
+				return err
+			}
+		}
+
+		job.Attempt = 1
+		if prev != nil {
+			job.Attempt = prev.Attempt + 1
+		}
+
+		err = create(ctx, job)
+		if errors.Is(err, media.ErrAttemptTaken) && try < maxAttemptConflicts {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("create transcode job: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+)
+
+var (
+	// ErrNoActiveJob is returned when a media has no job left to cancel.
+	ErrNoActiveJob = errors.New("no pending or processing transcode job")
+	// ErrJobCancelled stops a job whose cancellation was requested.
+	ErrJobCancelled = errors.New("transcode job cancelled")
+)
+
+// CancelTranscode cancels the latest job of a media. A pending job is
+// cancelled at once; a processing job is flagged, and its worker stops
+// ffmpeg and records the cancellation on its next heartbeat.
+func (i *impl) CancelTranscode(ctx context.Context, mediaID string) (*models.TranscodeJob, error) {
+	if _, err := i.ownedMedia(ctx, mediaID); err != nil {
+		return nil, err
+	}
+
+	job, err := i.mediaRepo.GetTranscodeJobByMediaID(ctx, mediaID)
+	if err != nil {
+		return nil, fmt.Errorf("get transcode job: %w", err)
+	}
+	if job == nil {
+		return nil, ErrNoActiveJob
+	}
+
+	var updated *models.TranscodeJob
+	switch job.Status {
+	case types.TranscodeJobStatusPending.String():
+		updated, err = i.mediaRepo.CancelPendingTranscodeJob(ctx, job)
+	case types.TranscodeJobStatusProcessing.String():
+		updated, err = i.mediaRepo.RequestTranscodeJobCancel(ctx, job)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cancel transcode job: %w", err)
+	}
+	if updated == nil {
+		// Finished, or changed state since it was read
+		return nil, ErrNoActiveJob
+	}
+
+	return updated, nil
+}
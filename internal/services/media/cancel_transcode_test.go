@@ -0,0 +1,52 @@
+package media_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+)
+
+func TestCancelTranscode(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        types.TranscodeJobStatus
+		wantStatus    types.TranscodeJobStatus
+		wantRequested bool
+		wantErr       error
+	}{
+		{name: "pending", status: types.TranscodeJobStatusPending, wantStatus: types.TranscodeJobStatusCancelled, wantRequested: true},
+		{name: "processing", status: types.TranscodeJobStatusProcessing, wantStatus: types.TranscodeJobStatusProcessing, wantRequested: true},
+		{name: "done", status: types.TranscodeJobStatusDone, wantStatus: types.TranscodeJobStatusDone, wantErr: media.ErrNoActiveJob},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			source := env.createSource(t, "clip.mp4")
+			env.createJob(t, source, models.TranscodeJob{Attempt: 1, Status: tt.status.String()})
+
+			_, err := env.svc.CancelTranscode(context.Background(), source.ID.Hex())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CancelTranscode error = %v, want %v", err, tt.wantErr)
+			}
+
+			job := env.repo.TranscodeJobs()[0]
+			if job.Status != tt.wantStatus.String() || job.CancelRequested != tt.wantRequested {
+				t.Errorf("job = %+v", job)
+			}
+		})
+	}
+}
+
+func TestCancelTranscodeWithoutJob(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+
+	if _, err := env.svc.CancelTranscode(context.Background(), source.ID.Hex()); !errors.Is(err, media.ErrNoActiveJob) {
+		t.Errorf("CancelTranscode error = %v, want %v", err, media.ErrNoActiveJob)
+	}
+}
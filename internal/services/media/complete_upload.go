@@ -0,0 +1,139 @@
+package media
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"media-svc/internal/adapters/minio"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"strings"
+)
+
+// CompleteUpload checks that the file of a pending upload reached storage
+// with the declared size and checksum, assembling the parts of a multipart
+// upload first, and dispatches its transcode through the outbox.
+func (i *impl) CompleteUpload(ctx context.Context, mediaID string) (*models.Media, error) {
+	media, err := i.ownedMedia(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	upload := media.Upload
+	if upload == nil {
+		return nil, ErrUploadNotPending
+	}
+
+	// A multipart object already exists when an earlier call assembled it
+	// but failed afterwards
+	info, err := i.mediaStorage.StatObject(ctx, media.Path)
+	if err != nil && upload.UploadID != "" {
+		if err := i.completeMultipartUpload(ctx, media); err != nil {
+			return nil, err
+		}
+		info, err = i.mediaStorage.StatObject(ctx, media.Path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUploadIncomplete, err)
+	}
+
+	if info.Size != media.Size {
+		return nil, fmt.Errorf("%w: stored %d bytes, declared %d", ErrUploadMismatch, info.Size, media.Size)
+	}
+	if upload.Checksum != "" {
+		sum, err := i.storedMD5(ctx, media.Path, info)
+		if err != nil {
+			return nil, fmt.Errorf("verify checksum: %w", err)
+		}
+		if !strings.EqualFold(sum, upload.Checksum) {
+			return nil, fmt.Errorf("%w: stored MD5 %s, declared %s", ErrUploadMismatch, sum, upload.Checksum)
+		}
+	}
+	if media.ContentType == "" {
+		media.ContentType = info.ContentType
+	}
+
+	msg, err := i.transcodeMessage(types.TranscodeJob{
+		MediaID: media.ID.Hex(),
+		Ladder:  upload.Ladder,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	completed, err := i.mediaRepo.CompleteMediaUpload(ctx, media, msg)
+	if err != nil {
+		return nil, fmt.Errorf("complete upload: %w", err)
+	}
+	if !completed {
+		return nil, ErrUploadNotPending
+	}
+
+	media.Upload = nil
+	i.probeUploadedMedia(ctx, media)
+	return media, nil
+}
+
+// completeMultipartUpload checks that every part was uploaded with its
+// expected size and assembles them into the object.
+func (i *impl) completeMultipartUpload(ctx context.Context, media *models.Media) error {
+	upload := media.Upload
+
+	parts, err := i.mediaStorage.ListUploadedParts(ctx, media.Path, upload.UploadID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUploadIncomplete, err)
+	}
+
+	var uploaded []minio.UploadedPart
+	for _, p := range parts {
+		if p.PartNumber >= 1 && p.PartNumber <= upload.Parts {
+			uploaded = append(uploaded, p)
+		}
+	}
+	if len(uploaded) != upload.Parts {
+		return fmt.Errorf("%w: %d of %d parts uploaded", ErrUploadIncomplete, len(uploaded), upload.Parts)
+	}
+	for _, p := range uploaded {
+		want := upload.PartSize
+		if p.PartNumber == upload.Parts {
+			want = media.Size - int64(upload.Parts-1)*upload.PartSize
+		}
+		if p.Size != want {
+			return fmt.Errorf("%w: part %d is %d bytes, expected %d", ErrUploadMismatch, p.PartNumber, p.Size, want)
+		}
+	}
+
+	return i.mediaStorage.CompleteMultipartUpload(ctx, media.Path, upload.UploadID, uploaded)
+}
+
+// storedMD5 returns the hex MD5 of a stored object. The ETag is used when it
+// is the MD5 of the content; otherwise, as for multipart objects and the
+// filesystem storage, the object is read and hashed.
+func (i *impl) storedMD5(ctx context.Context, objectName string, info minio.ObjectInfo) (string, error) {
+	if etag := strings.Trim(info.ETag, `"`); isMD5(etag) {
+		return etag, nil
+	}
+
+	reader, _, err := i.mediaStorage.GetObjectReader(ctx, objectName)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isMD5 reports whether an ETag is the MD5 of the content. Multipart ETags
+// and those of the filesystem storage are not.
+func isMD5(etag string) bool {
+	if len(etag) != md5.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(etag)
+	return err == nil
+}
@@ -31,6 +31,7 @@ func (i *impl) CreateMedia(ctx context.Context, input CreateMediaInput) (string,
 		Height:       input.Height,
 		IsStreamable: input.IsStreamable,
 		Tags:         input.Tags,
+		OwnerID:      callerID(ctx),
 		CreatedAt:    time.Now().UTC(),
 	}
 
@@ -0,0 +1,147 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"media-svc/internal/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultUploadURLExpiry    = time.Hour
+	defaultMultipartThreshold = 100 << 20
+	defaultPartSize           = 64 << 20
+	// maxUploadParts is the most parts S3 accepts in a multipart upload.
+	maxUploadParts = 10000
+)
+
+var (
+	// ErrUploadNotPending is returned when completing a media that has no
+	// pending upload, e.g. because it was already completed.
+	ErrUploadNotPending = errors.New("media has no pending upload")
+	// ErrUploadIncomplete is returned when the file or some of its parts
+	// did not reach storage.
+	ErrUploadIncomplete = errors.New("upload is incomplete")
+	// ErrUploadMismatch is returned when the stored file differs from the
+	// declared size or checksum.
+	ErrUploadMismatch = errors.New("uploaded file does not match the declared size or checksum")
+)
+
+type CreateUploadInput struct {
+	Filename    string
+	Size        int64
+	ContentType string
+	Checksum    string // Optional hex MD5 of the file
+	Ladder      string // Optional rendition ladder name, empty for the default
+}
+
+// UploadPartURL is the presigned URL a part of a multipart upload is PUT to.
+type UploadPartURL struct {
+	PartNumber int
+	URL        string
+}
+
+type CreateUploadOutput struct {
+	Media *models.Media
+	URL   string          // Presigned PUT of the whole file, empty for a multipart upload
+	Parts []UploadPartURL // Presigned PUT of every part of a multipart upload
+}
+
+// CreateUpload creates a media waiting for its file and returns the
+// presigned URLs the client uploads it to, directly to storage. Files from
+// the multipart threshold on are split into parts. CompleteUpload starts
+// the transcode once the upload is done.
+func (i *impl) CreateUpload(ctx context.Context, input CreateUploadInput) (*CreateUploadOutput, error) {
+	ladder, _, err := i.resolveLadder(input.Ladder)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry := i.cfg.Upload.URLExpiry
+	if expiry <= 0 {
+		expiry = defaultUploadURLExpiry
+	}
+
+	id := primitive.NewObjectID()
+	media := &models.Media{
+		ID:          id,
+		Name:        input.Filename,
+		Description: input.Filename,
+		Path:        sourceObjectName(id, input.Filename),
+		Size:        input.Size,
+		ContentType: input.ContentType,
+		OwnerID:     callerID(ctx),
+		Upload: &models.MediaUpload{
+			Ladder:    ladder,
+			Checksum:  strings.ToLower(input.Checksum),
+			ExpiresAt: time.Now().Add(expiry).UTC(),
+		},
+	}
+	out := &CreateUploadOutput{Media: media}
+
+	if input.Size < i.multipartThreshold() {
+		out.URL, err = i.mediaStorage.PresignPutObject(ctx, media.Path, expiry)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		upload := media.Upload
+		upload.PartSize = i.partSize(input.Size)
+		upload.Parts = int((input.Size + upload.PartSize - 1) / upload.PartSize)
+		upload.UploadID, err = i.mediaStorage.NewMultipartUpload(ctx, media.Path)
+		if err != nil {
+			return nil, err
+		}
+		for n := 1; n <= upload.Parts; n++ {
+			url, err := i.mediaStorage.PresignUploadPart(ctx, media.Path, upload.UploadID, n, expiry)
+			if err != nil {
+				i.abortUpload(media)
+				return nil, err
+			}
+			out.Parts = append(out.Parts, UploadPartURL{PartNumber: n, URL: url})
+		}
+	}
+
+	if err := i.mediaRepo.CreateMedia(ctx, media); err != nil {
+		i.abortUpload(media)
+		return nil, fmt.Errorf("create media: %w", err)
+	}
+
+	return out, nil
+}
+
+func (i *impl) multipartThreshold() int64 {
+	if i.cfg.Upload.MultipartThresholdMB > 0 {
+		return i.cfg.Upload.MultipartThresholdMB << 20
+	}
+	return defaultMultipartThreshold
+}
+
+// partSize returns the configured part size, grown so that size fits in
+// maxUploadParts parts.
+func (i *impl) partSize(size int64) int64 {
+	partSize := int64(defaultPartSize)
+	if i.cfg.Upload.PartSizeMB > 0 {
+		partSize = i.cfg.Upload.PartSizeMB << 20
+	}
+	if minSize := (size + maxUploadParts - 1) / maxUploadParts; partSize < minSize {
+		partSize = minSize
+	}
+	return partSize
+}
+
+// abortUpload drops the parts of a multipart upload that will not be
+// completed.
+func (i *impl) abortUpload(media *models.Media) {
+	if media.Upload == nil || media.Upload.UploadID == "" {
+		return
+	}
+	if err := i.mediaStorage.AbortMultipartUpload(context.Background(), media.Path, media.Upload.UploadID); err != nil {
+		log.Printf("abort upload of %s: %v", media.Path, err)
+	}
+}
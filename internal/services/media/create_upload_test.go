@@ -0,0 +1,217 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+)
+
+func TestCreateUploadSinglePut(t *testing.T) {
+	content := []byte("direct upload")
+	sum := md5.Sum(content)
+
+	tests := []struct {
+		name     string
+		upload   []byte
+		checksum string
+		wantErr  error
+	}{
+		{name: "complete", upload: content, checksum: hex.EncodeToString(sum[:])},
+		{name: "not uploaded", wantErr: media.ErrUploadIncomplete},
+		{name: "size mismatch", upload: content[:5], wantErr: media.ErrUploadMismatch},
+		{name: "checksum mismatch", upload: []byte("direct UPLOAD"), checksum: hex.EncodeToString(sum[:]), wantErr: media.ErrUploadMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			out, err := env.svc.CreateUpload(context.Background(), media.CreateUploadInput{
+				Filename:    "clip.mp4",
+				Size:        int64(len(content)),
+				ContentType: "video/mp4",
+				Checksum:    tt.checksum,
+			})
+			if err != nil {
+				t.Fatalf("CreateUpload: %v", err)
+			}
+			if out.URL == "" || len(out.Parts) != 0 || out.Media.Upload == nil {
+				t.Fatalf("upload = %+v", out)
+			}
+			if listed, _ := env.svc.ListMedia(context.Background(), media.ListMediaInput{}); len(listed) != 0 {
+				t.Errorf("pending upload listed: %+v", listed)
+			}
+
+			if tt.upload != nil {
+				if _, err := env.storage.PutObject(context.Background(), out.Media.Path, bytes.NewReader(tt.upload), int64(len(tt.upload))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = env.svc.CompleteUpload(context.Background(), out.Media.ID.Hex())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompleteUpload error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if n := len(env.repo.Outbox()); n != 0 {
+					t.Errorf("outbox = %d messages, want none", n)
+				}
+				return
+			}
+
+			stored, _ := env.repo.GetMedia(context.Background(), out.Media.ID.Hex())
+			if stored.Upload != nil {
+				t.Errorf("upload still pending: %+v", stored.Upload)
+			}
+			outbox := env.repo.Outbox()
+			if len(outbox) != 1 {
+				t.Fatalf("outbox = %+v", outbox)
+			}
+			var msg types.TranscodeJob
+			if err := json.Unmarshal(outbox[0].Payload, &msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.MediaID != out.Media.ID.Hex() || msg.Ladder != "default" {
+				t.Errorf("dispatch message = %+v", msg)
+			}
+
+			// Completing twice does not dispatch again
+			if _, err := env.svc.CompleteUpload(context.Background(), out.Media.ID.Hex()); !errors.Is(err, media.ErrUploadNotPending) {
+				t.Errorf("second CompleteUpload error = %v, want %v", err, media.ErrUploadNotPending)
+			}
+		})
+	}
+}
+
+func TestCreateUploadMultipart(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Upload.MultipartThresholdMB = 1
+	env.cfg.Upload.PartSizeMB = 1
+
+	content := bytes.Repeat([]byte("x"), 5<<19) // 2.5 MiB
+	out, err := env.svc.CreateUpload(context.Background(), media.CreateUploadInput{
+		Filename: "large.mp4",
+		Size:     int64(len(content)),
+	})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	upload := out.Media.Upload
+	if out.URL != "" || len(out.Parts) != 3 || upload.Parts != 3 || upload.PartSize != 1<<20 || upload.UploadID == "" {
+		t.Fatalf("upload = %+v, %+v", out, upload)
+	}
+
+	// Only two of three parts are uploaded
+	for n := 1; n <= 2; n++ {
+		part := content[(n-1)<<20 : n<<20]
+		if err := env.storage.UploadPart(upload.UploadID, n, part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.svc.CompleteUpload(context.Background(), out.Media.ID.Hex()); !errors.Is(err, media.ErrUploadIncomplete) {
+		t.Fatalf("CompleteUpload error = %v, want %v", err, media.ErrUploadIncomplete)
+	}
+
+	if err := env.storage.UploadPart(upload.UploadID, 3, content[2<<20:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.svc.CompleteUpload(context.Background(), out.Media.ID.Hex()); err != nil {
+		t.Fatalf("CompleteUpload: %v", err)
+	}
+
+	data, err := env.storage.GetObject(context.Background(), out.Media.Path)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("assembled object = %d bytes, %v", len(data), err)
+	}
+	if n := len(env.repo.Outbox()); n != 1 {
+		t.Errorf("outbox = %d messages, want 1", n)
+	}
+}
+
+func TestCompleteUploadVerifiesMultipartChecksum(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 3<<19) // 1.5 MiB
+	sum := md5.Sum(content)
+
+	tests := []struct {
+		name     string
+		checksum string
+		wantErr  error
+	}{
+		{name: "matching", checksum: hex.EncodeToString(sum[:])},
+		{name: "different", checksum: "00112233445566778899aabbccddeeff", wantErr: media.ErrUploadMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.cfg.Upload.MultipartThresholdMB = 1
+			env.cfg.Upload.PartSizeMB = 1
+
+			out, err := env.svc.CreateUpload(context.Background(), media.CreateUploadInput{
+				Filename: "large.mp4",
+				Size:     int64(len(content)),
+				Checksum: tt.checksum,
+			})
+			if err != nil {
+				t.Fatalf("CreateUpload: %v", err)
+			}
+			upload := out.Media.Upload
+			if err := env.storage.UploadPart(upload.UploadID, 1, content[:1<<20]); err != nil {
+				t.Fatal(err)
+			}
+			if err := env.storage.UploadPart(upload.UploadID, 2, content[1<<20:]); err != nil {
+				t.Fatal(err)
+			}
+
+			// The multipart ETag is not an MD5, so the object is hashed
+			_, err = env.svc.CompleteUpload(context.Background(), out.Media.ID.Hex())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CompleteUpload error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReapExpiredUploads(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Upload.MultipartThresholdMB = 1
+	env.cfg.Upload.PartSizeMB = 1
+
+	abandoned, err := env.svc.CreateUpload(context.Background(), media.CreateUploadInput{Filename: "large.mp4", Size: 3 << 19})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	if err := env.storage.UploadPart(abandoned.Media.Upload.UploadID, 1, bytes.Repeat([]byte("x"), 1<<20)); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := env.repo.GetMedia(context.Background(), abandoned.Media.ID.Hex())
+	stored.Upload.ExpiresAt = time.Now().Add(-2 * time.Hour)
+	if err := env.repo.UpdateMedia(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := env.svc.CreateUpload(context.Background(), media.CreateUploadInput{Filename: "small.mp4", Size: 6})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+
+	if n, err := env.svc.ReapExpiredUploads(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ReapExpiredUploads = %d, %v; want 1, nil", n, err)
+	}
+	if m, _ := env.repo.GetMedia(context.Background(), abandoned.Media.ID.Hex()); m != nil {
+		t.Errorf("abandoned media kept: %+v", m)
+	}
+	if _, err := env.storage.ListUploadedParts(context.Background(), abandoned.Media.Path, abandoned.Media.Upload.UploadID); err == nil {
+		t.Error("multipart upload of the abandoned media was not aborted")
+	}
+	if m, _ := env.repo.GetMedia(context.Background(), active.Media.ID.Hex()); m == nil {
+		t.Error("upload within its expiry was removed")
+	}
+}
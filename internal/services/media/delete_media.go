@@ -0,0 +1,49 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"media-svc/internal/types"
+	"path"
+)
+
+// DeleteMedia removes a media the caller owns with its jobs and content
+// key, its source file and its transcode output. A media with a pending or
+// processing job is refused with ErrJobInProgress; the job has to be
+// cancelled first.
+func (i *impl) DeleteMedia(ctx context.Context, id string) error {
+
+	media, err := i.ownedMedia(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	job, err := i.mediaRepo.GetTranscodeJobByMediaID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get transcode job: %w", err)
+	}
+	if job != nil && (job.Status == types.TranscodeJobStatusPending.String() || job.Status == types.TranscodeJobStatusProcessing.String()) {
+		return ErrJobInProgress
+	}
+
+	if err := i.mediaRepo.DeleteMedia(ctx, media.ID); err != nil {
+		return fmt.Errorf("delete media: %w", err)
+	}
+
+	// The documents are gone, so leftover files are only logged
+	if media.Upload != nil && media.Upload.UploadID != "" {
+		if err := i.mediaStorage.AbortMultipartUpload(ctx, media.Path, media.Upload.UploadID); err != nil {
+			log.Printf("abort upload of media %s: %v", id, err)
+		}
+	}
+	if err := i.mediaStorage.RemoveObject(ctx, media.Path); err != nil {
+		log.Printf("remove source of media %s: %v", id, err)
+	}
+	if media.TranscodeSource != nil && media.TranscodeSource.FilePath != "" {
+		if err := i.streamStorage.RemovePrefix(ctx, path.Dir(media.TranscodeSource.FilePath)); err != nil {
+			log.Printf("remove stream output of media %s: %v", id, err)
+		}
+	}
+	return nil
+}
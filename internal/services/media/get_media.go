@@ -11,6 +11,11 @@ func (i *impl) GetMedia(ctx context.Context, id string) (*models.Media, error) {
 	if err != nil {
 		return nil, err
 	}
+	if media != nil {
+		if err := authorize(ctx, media.OwnerID); err != nil {
+			return nil, err
+		}
+	}
 
 	return media, nil
 }
@@ -8,11 +8,14 @@ import (
 type GetVideoStatusResponse struct {
 	Status          string
 	TranscodeSource string
+	Stage           string
+	Progress        float64
+	ETASeconds      int64
 }
 
 func (i *impl) GetVideoStatus(ctx context.Context, videoId string) (GetVideoStatusResponse, error) {
 
-	media, err := i.mediaRepo.GetMedia(ctx, videoId)
+	media, err := i.ownedMedia(ctx, videoId)
 	if err != nil {
 		return GetVideoStatusResponse{}, err
 	}
@@ -37,5 +40,8 @@ func (i *impl) GetVideoStatus(ctx context.Context, videoId string) (GetVideoStat
 	return GetVideoStatusResponse{
 		Status:          transcodeJob.Status,
 		TranscodeSource: source,
+		Stage:           transcodeJob.Stage,
+		Progress:        transcodeJob.Progress,
+		ETASeconds:      transcodeJob.ETASeconds,
 	}, nil
 }
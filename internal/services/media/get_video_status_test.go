@@ -0,0 +1,78 @@
+package media_test
+
+import (
+	"context"
+	"testing"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+)
+
+func TestGetVideoStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		job    *models.TranscodeJob
+		source *models.TranscodeSource
+		want   media.GetVideoStatusResponse
+	}{
+		{
+			name: "no job yet",
+			want: media.GetVideoStatusResponse{Status: types.TranscodeJobStatusPending.String()},
+		},
+		{
+			name: "encoding",
+			job: &models.TranscodeJob{
+				Status:     types.TranscodeJobStatusProcessing.String(),
+				Stage:      types.TranscodeStageEncoding.String(),
+				Progress:   42.5,
+				ETASeconds: 30,
+			},
+			want: media.GetVideoStatusResponse{
+				Status:     types.TranscodeJobStatusProcessing.String(),
+				Stage:      types.TranscodeStageEncoding.String(),
+				Progress:   42.5,
+				ETASeconds: 30,
+			},
+		},
+		{
+			name: "done",
+			job: &models.TranscodeJob{
+				Status:   types.TranscodeJobStatusDone.String(),
+				Stage:    types.TranscodeStageCompleted.String(),
+				Progress: 100,
+			},
+			source: &models.TranscodeSource{FilePath: "clip.mp4/master.m3u8"},
+			want: media.GetVideoStatusResponse{
+				Status:          types.TranscodeJobStatusDone.String(),
+				TranscodeSource: "clip.mp4/master.m3u8",
+				Stage:           types.TranscodeStageCompleted.String(),
+				Progress:        100,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			source := env.createSource(t, "clip.mp4")
+			if tt.job != nil {
+				env.createJob(t, source, *tt.job)
+			}
+			if tt.source != nil {
+				source.TranscodeSource = tt.source
+				if err := env.repo.UpdateMedia(context.Background(), source); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := env.svc.GetVideoStatus(context.Background(), source.ID.Hex())
+			if err != nil {
+				t.Fatalf("GetVideoStatus: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("status = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"media-svc/pkgs/transcoder"
+	"strings"
+)
+
+// DefaultLadderName is the ladder used when neither the request nor the
+// configuration names one.
+const DefaultLadderName = "default"
+
+// ErrLadderNotFound is returned when a requested ladder is not configured.
+var ErrLadderNotFound = errors.New("transcode ladder not found")
+
+// ErrInvalidOptions is returned for transcode options the transcoder does
+// not support.
+var ErrInvalidOptions = errors.New("invalid transcode options")
+
+// resolveLadder returns the normalized ladder name and its renditions.
+// An empty name selects the configured default ladder. When no ladders are
+// configured at all, the built-in transcoder.DefaultRenditions are used.
+func (i *impl) resolveLadder(name string) (string, []transcoder.Rendition, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = strings.ToLower(i.cfg.Transcode.DefaultLadder)
+	}
+	if name == "" {
+		name = DefaultLadderName
+	}
+
+	ladders := i.cfg.Transcode.Ladders
+	if len(ladders) == 0 {
+		if name != DefaultLadderName {
+			return "", nil, fmt.Errorf("%w: %s", ErrLadderNotFound, name)
+		}
+		return name, transcoder.DefaultRenditions, nil
+	}
+
+	// Viper lower-cases map keys, so ladder names are matched case-insensitively.
+	profiles, ok := ladders[name]
+	if !ok || len(profiles) == 0 {
+		return "", nil, fmt.Errorf("%w: %s", ErrLadderNotFound, name)
+	}
+
+	renditions := make([]transcoder.Rendition, 0, len(profiles))
+	for _, p := range profiles {
+		renditions = append(renditions, transcoder.Rendition{
+			Name:            p.Name,
+			Width:           p.Width,
+			Height:          p.Height,
+			VideoCodec:      p.VideoCodec,
+			Encoder:         p.Encoder,
+			Preset:          p.Preset,
+			VideoBitrate:    p.VideoBitrate,
+			MaxRate:         p.MaxRate,
+			BufSize:         p.BufSize,
+			Profile:         p.Profile,
+			Level:           p.Level,
+			AudioBitrate:    p.AudioBitrate,
+			AudioChannels:   p.AudioChannels,
+			AudioSampleRate: p.AudioSampleRate,
+		})
+	}
+
+	return name, renditions, nil
+}
+
+// normalizeOptions validates opts and returns them with the codec in its
+// canonical form, or nil when they override nothing.
+func normalizeOptions(opts *types.TranscodeOptions) (*types.TranscodeOptions, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	out := types.TranscodeOptions{
+		Preset:  strings.TrimSpace(opts.Preset),
+		Profile: strings.TrimSpace(opts.Profile),
+	}
+	if codec := strings.TrimSpace(opts.VideoCodec); codec != "" {
+		normalized, err := transcoder.NormalizeCodec(codec)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+		}
+		out.VideoCodec = normalized
+	}
+	if out == (types.TranscodeOptions{}) {
+		return nil, nil
+	}
+	return &out, nil
+}
+
+// applyOptions returns the renditions with the codec settings of opts. A
+// rendition moved to another codec drops the encoder, preset, profile and
+// level of the ladder, which only fit its original codec.
+func applyOptions(renditions []transcoder.Rendition, opts *types.TranscodeOptions) []transcoder.Rendition {
+	if opts == nil {
+		return renditions
+	}
+
+	out := make([]transcoder.Rendition, 0, len(renditions))
+	for _, r := range renditions {
+		if opts.VideoCodec != "" {
+			if codec, err := transcoder.NormalizeCodec(r.VideoCodec); err != nil || codec != opts.VideoCodec {
+				r.Encoder, r.Preset, r.Profile, r.Level = "", "", "", ""
+			}
+			r.VideoCodec = opts.VideoCodec
+		}
+		if opts.Preset != "" {
+			r.Preset = opts.Preset
+		}
+		if opts.Profile != "" {
+			r.Profile = opts.Profile
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// jobOptions converts the options of a job message to their stored form.
+func jobOptions(opts *types.TranscodeOptions) *models.TranscodeOptions {
+	if opts == nil {
+		return nil
+	}
+	return &models.TranscodeOptions{
+		VideoCodec: opts.VideoCodec,
+		Preset:     opts.Preset,
+		Profile:    opts.Profile,
+	}
+}
+
+// messageOptions converts the stored options of a job back to their message
+// form.
+func messageOptions(opts *models.TranscodeOptions) *types.TranscodeOptions {
+	if opts == nil {
+		return nil
+	}
+	return &types.TranscodeOptions{
+		VideoCodec: opts.VideoCodec,
+		Preset:     opts.Preset,
+		Profile:    opts.Profile,
+	}
+}
@@ -0,0 +1,180 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"time"
+)
+
+const (
+	defaultLeaseDuration  = time.Minute
+	defaultMaxLostLeases  = 3
+	defaultPendingTimeout = 30 * time.Minute
+	reapBatchSize         = 100
+)
+
+// ErrLeaseLost is returned when a job was taken away from its worker
+// because its lease expired, e.g. during a long stall. Another worker runs
+// it, so the outcome must not be recorded.
+var ErrLeaseLost = errors.New("transcode job lease lost")
+
+func (i *impl) leaseDuration() time.Duration {
+	if d := i.cfg.Worker.LeaseDuration; d > 0 {
+		return d
+	}
+	return defaultLeaseDuration
+}
+
+// heartbeatInterval returns how often a running job renews its lease. A
+// configured interval that would let the lease expire between two
+// heartbeats is replaced by a third of the lease.
+func (i *impl) heartbeatInterval() time.Duration {
+	lease := i.leaseDuration()
+	if d := i.cfg.Worker.HeartbeatInterval; d > 0 && d < lease {
+		return d
+	}
+	return lease / 3
+}
+
+// keepLease renews the lease of a job until ctx is done, cancelling ctx with
+// ErrLeaseLost when the job no longer belongs to the worker, or with
+// ErrJobCancelled when a cancel was requested. Call the returned function to
+// stop renewing.
+func (i *impl) keepLease(ctx context.Context, cancel context.CancelCauseFunc, job *models.TranscodeJob) (stop func()) {
+	lease := i.leaseDuration()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(i.heartbeatInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := i.mediaRepo.RenewTranscodeJobLease(ctx, job.ID.Hex(), job.WorkerID, time.Now().Add(lease))
+			if err != nil {
+				// Keep working; the lease only expires if renewals keep failing
+				log.Printf("renew lease of job %s: %v", job.ID.Hex(), err)
+				continue
+			}
+			if current == nil {
+				log.Printf("lease of job %s lost, stopping", job.ID.Hex())
+				cancel(ErrLeaseLost)
+				return
+			}
+			if current.CancelRequested {
+				log.Printf("job %s cancelled, stopping", job.ID.Hex())
+				cancel(ErrJobCancelled)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// ReapExpiredJobs recovers jobs whose worker stopped renewing the lease,
+// e.g. because it crashed, and processing jobs that have no lease. Each job goes back to pending and is dispatched
+// again through the outbox, or is marked failed once it has lost its lease
+// Worker.MaxLostLeases times. It returns how many jobs were recovered.
+func (i *impl) ReapExpiredJobs(ctx context.Context) (int, error) {
+	maxLost := i.cfg.Worker.MaxLostLeases
+	if maxLost <= 0 {
+		maxLost = defaultMaxLostLeases
+	}
+
+	now := time.Now().UTC()
+	jobs, err := i.mediaRepo.ListExpiredTranscodeJobs(ctx, now, reapBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list expired jobs: %w", err)
+	}
+
+	reaped := 0
+	for _, job := range jobs {
+		if job.LostLeases+1 >= maxLost {
+			ok, err := i.mediaRepo.FailExpiredTranscodeJob(ctx, job.ID, now,
+				fmt.Sprintf("worker lost %d times, last %s", job.LostLeases+1, job.WorkerID))
+			if err != nil {
+				return reaped, fmt.Errorf("fail expired job: %w", err)
+			}
+			if ok {
+				log.Printf("job %s of media %s failed after %d lost leases", job.ID.Hex(), job.MediaID.Hex(), job.LostLeases+1)
+				reaped++
+			}
+			continue
+		}
+
+		msg, err := i.transcodeMessage(types.TranscodeJob{
+			MediaID: job.MediaID.Hex(),
+			Ladder:  job.Ladder,
+			JobID:   job.ID.Hex(),
+			Options: messageOptions(job.Options),
+		})
+		if err != nil {
+			return reaped, err
+		}
+		ok, err := i.mediaRepo.RequeueExpiredTranscodeJob(ctx, job.ID, now, msg)
+		if err != nil {
+			return reaped, fmt.Errorf("requeue expired job: %w", err)
+		}
+		if ok {
+			log.Printf("job %s of media %s requeued, lease of %s expired", job.ID.Hex(), job.MediaID.Hex(), job.WorkerID)
+			reaped++
+		}
+	}
+	return reaped, nil
+}
+
+// ReapStalePendingJobs dispatches pending jobs again through the outbox
+// when they were not updated within Worker.PendingTimeout, e.g. because
+// their requeued message was lost. Without it such a job would keep its
+// media blocked for retranscodes and deletes. It returns how many jobs were
+// dispatched again.
+func (i *impl) ReapStalePendingJobs(ctx context.Context) (int, error) {
+	timeout := i.cfg.Worker.PendingTimeout
+	if timeout <= 0 {
+		timeout = defaultPendingTimeout
+	}
+
+	before := time.Now().UTC().Add(-timeout)
+	jobs, err := i.mediaRepo.ListStalePendingTranscodeJobs(ctx, before, reapBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list stale pending jobs: %w", err)
+	}
+
+	reaped := 0
+	for _, job := range jobs {
+		msg, err := i.transcodeMessage(types.TranscodeJob{
+			MediaID: job.MediaID.Hex(),
+			Ladder:  job.Ladder,
+			JobID:   job.ID.Hex(),
+			Options: messageOptions(job.Options),
+		})
+		if err != nil {
+			return reaped, err
+		}
+		ok, err := i.mediaRepo.RequeueStalePendingTranscodeJob(ctx, job.ID, before, msg)
+		if err != nil {
+			return reaped, fmt.Errorf("requeue stale pending job: %w", err)
+		}
+		if ok {
+			log.Printf("job %s of media %s pending since %s, dispatched again", job.ID.Hex(), job.MediaID.Hex(), job.UpdatedAt.Format(time.RFC3339))
+			reaped++
+		}
+	}
+	return reaped, nil
+}
@@ -0,0 +1,150 @@
+package media_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+)
+
+func TestReapExpiredJobs(t *testing.T) {
+	useFakeFFmpeg(t)
+	env := newTestEnv(t)
+	env.cfg.Worker.MaxLostLeases = 2
+	source := env.createSource(t, "clip.mp4")
+
+	expired := time.Now().Add(-time.Minute)
+	live := time.Now().Add(time.Minute)
+	crashed := env.createJob(t, source, models.TranscodeJob{
+		Attempt:    1,
+		Ladder:     "default",
+		WorkerID:   "host-1/worker-0",
+		Status:     types.TranscodeJobStatusProcessing.String(),
+		LeaseUntil: &expired,
+	})
+	other := env.createSource(t, "other.mp4")
+	env.createJob(t, other, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusProcessing.String(), LeaseUntil: &live})
+
+	// The crashed job goes back to pending and is dispatched again
+	if n, err := env.svc.ReapExpiredJobs(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ReapExpiredJobs = %d, %v; want 1, nil", n, err)
+	}
+	jobs := env.repo.TranscodeJobs()
+	if jobs[0].Status != types.TranscodeJobStatusPending.String() || jobs[0].LostLeases != 1 || jobs[0].LeaseUntil != nil {
+		t.Errorf("reaped job = %+v", jobs[0])
+	}
+	if jobs[1].Status != types.TranscodeJobStatusProcessing.String() {
+		t.Errorf("live job reaped: %+v", jobs[1])
+	}
+	outbox := env.repo.Outbox()
+	if len(outbox) != 1 {
+		t.Fatalf("outbox = %+v", outbox)
+	}
+	var msg types.TranscodeJob
+	if err := json.Unmarshal(outbox[0].Payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.JobID != crashed.ID.Hex() || msg.MediaID != source.ID.Hex() || msg.Ladder != "default" {
+		t.Errorf("requeue message = %+v", msg)
+	}
+
+	// The next worker takes over the same job record
+	if _, err := env.svc.TranscodeVideo(context.Background(), media.TranscodeVideoInput{
+		MediaID:   msg.MediaID,
+		Ladder:    msg.Ladder,
+		MessageID: msg.MessageID,
+		JobID:     msg.JobID,
+		WorkerID:  "host-2/worker-0",
+	}); err != nil {
+		t.Fatalf("TranscodeVideo: %v", err)
+	}
+	job, _ := env.repo.GetTranscodeJob(context.Background(), crashed.ID.Hex())
+	if job.WorkerID != "host-2/worker-0" || job.Attempt != 1 || job.LeaseUntil == nil {
+		t.Errorf("claimed job = %+v", job)
+	}
+	if n := len(env.repo.TranscodeJobs()); n != 2 {
+		t.Errorf("jobs = %d, want the claimed job reused", n)
+	}
+
+	// A second claim of the same job is a duplicate
+	_, err := env.svc.TranscodeVideo(context.Background(), media.TranscodeVideoInput{MediaID: msg.MediaID, JobID: msg.JobID})
+	if err != media.ErrDuplicateJob {
+		t.Errorf("second claim error = %v, want %v", err, media.ErrDuplicateJob)
+	}
+
+	// Losing the worker again reaches MaxLostLeases and fails the job
+	job.Status = types.TranscodeJobStatusProcessing.String()
+	job.LeaseUntil = &expired
+	env.repo.UpdateTranscodeJob(context.Background(), job)
+	if n, err := env.svc.ReapExpiredJobs(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ReapExpiredJobs = %d, %v; want 1, nil", n, err)
+	}
+	job, _ = env.repo.GetTranscodeJob(context.Background(), crashed.ID.Hex())
+	if job.Status != types.TranscodeJobStatusError.String() || job.LostLeases != 2 || job.FinishedAt == nil {
+		t.Errorf("failed job = %+v", job)
+	}
+	if n := len(env.repo.Outbox()); n != 1 {
+		t.Errorf("outbox = %d messages, want no new dispatch", n)
+	}
+}
+
+func TestReapExpiredJobsWithoutLease(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+	env.createJob(t, source, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusProcessing.String()})
+
+	// Nobody renews a processing job without a lease, so it is requeued
+	if n, err := env.svc.ReapExpiredJobs(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ReapExpiredJobs = %d, %v; want 1, nil", n, err)
+	}
+	jobs := env.repo.TranscodeJobs()
+	if jobs[0].Status != types.TranscodeJobStatusPending.String() || jobs[0].LostLeases != 1 {
+		t.Errorf("reaped job = %+v", jobs[0])
+	}
+	if n := len(env.repo.Outbox()); n != 1 {
+		t.Errorf("outbox = %d messages, want 1", n)
+	}
+}
+
+func TestReapStalePendingJobs(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Worker.PendingTimeout = 50 * time.Millisecond
+	source := env.createSource(t, "clip.mp4")
+	live := time.Now().Add(time.Minute)
+
+	// Put back to pending, but the requeued message never arrived
+	stale := env.createJob(t, source, models.TranscodeJob{Attempt: 1, Ladder: "default", Status: types.TranscodeJobStatusPending.String()})
+	other := env.createSource(t, "other.mp4")
+	env.createJob(t, other, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusProcessing.String(), LeaseUntil: &live})
+	time.Sleep(100 * time.Millisecond)
+	fresh := env.createSource(t, "fresh.mp4")
+	env.createJob(t, fresh, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusPending.String()})
+
+	if n, err := env.svc.ReapStalePendingJobs(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ReapStalePendingJobs = %d, %v; want 1, nil", n, err)
+	}
+	outbox := env.repo.Outbox()
+	if len(outbox) != 1 {
+		t.Fatalf("outbox = %+v", outbox)
+	}
+	var msg types.TranscodeJob
+	if err := json.Unmarshal(outbox[0].Payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.JobID != stale.ID.Hex() || msg.MediaID != source.ID.Hex() || msg.Ladder != "default" {
+		t.Errorf("dispatch message = %+v", msg)
+	}
+	job, _ := env.repo.GetTranscodeJob(context.Background(), stale.ID.Hex())
+	if job.Status != types.TranscodeJobStatusPending.String() || !job.UpdatedAt.After(stale.UpdatedAt) {
+		t.Errorf("reaped job = %+v", job)
+	}
+
+	// The dispatched job waits another timeout before the next dispatch
+	if n, err := env.svc.ReapStalePendingJobs(context.Background()); err != nil || n != 0 {
+		t.Errorf("second ReapStalePendingJobs = %d, %v; want 0, nil", n, err)
+	}
+}
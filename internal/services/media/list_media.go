@@ -4,16 +4,34 @@ import (
 	"context"
 	"media-svc/internal/adapters/mongodb/media"
 	"media-svc/internal/models"
+	"media-svc/internal/services/auth"
 )
 
 type ListMediaInput struct {
-	Keyword string
+	Keyword     string
+	VideoCodec  string
+	HDR         *bool
+	MinHeight   int
+	MinDuration float64
+	MaxDuration float64
 }
 
 func (i *impl) ListMedia(ctx context.Context, input ListMediaInput) ([]*models.Media, error) {
 
+	// Callers other than admins only see their own media
+	var ownerID string
+	if p := auth.PrincipalFromContext(ctx); p != nil && !p.Admin {
+		ownerID = p.Subject
+	}
+
 	medias, err := i.mediaRepo.ListMedia(ctx, media.ListMediaInput{
-		Keyword: input.Keyword,
+		Keyword:     input.Keyword,
+		VideoCodec:  input.VideoCodec,
+		HDR:         input.HDR,
+		MinHeight:   input.MinHeight,
+		MinDuration: input.MinDuration,
+		MaxDuration: input.MaxDuration,
+		OwnerID:     ownerID,
 	})
 	if err != nil {
 		return nil, err
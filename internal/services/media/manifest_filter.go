@@ -0,0 +1,117 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"media-svc/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrNoMatchingRenditions is returned when a filter leaves no rendition to
+// play.
+var ErrNoMatchingRenditions = errors.New("no rendition matches the filter")
+
+// videoCodecPrefixes maps the video codec names of a ladder to the RFC 6381
+// prefixes of their CODECS values.
+var videoCodecPrefixes = map[string]string{
+	"h264": "avc1",
+	"hevc": "hvc1",
+	"vp9":  "vp09",
+	"av1":  "av01",
+}
+
+// ManifestFilter selects the renditions a master playlist or DASH manifest
+// offers, e.g. to cap the quality for a device. Zero fields do not filter.
+type ManifestFilter struct {
+	MaxHeight  int
+	MaxBitrate int64 // Bits per second of video and audio together
+	// Codecs are video codecs by name (h264, hevc, vp9, av1) or by RFC 6381
+	// prefix (avc1, hvc1, vp09, av01).
+	Codecs     []string
+	Renditions []string // Rendition names, e.g. "720p"
+}
+
+func (f ManifestFilter) IsZero() bool {
+	return f.MaxHeight == 0 && f.MaxBitrate == 0 && len(f.Codecs) == 0 && len(f.Renditions) == 0
+}
+
+// Matches reports whether the filter keeps rendition r.
+func (f ManifestFilter) Matches(r models.Rendition) bool {
+	if f.MaxHeight > 0 && r.Height > f.MaxHeight {
+		return false
+	}
+	if f.MaxBitrate > 0 && renditionBitrate(r) > f.MaxBitrate {
+		return false
+	}
+	if len(f.Codecs) > 0 && !slices.ContainsFunc(f.Codecs, func(codec string) bool { return matchesCodec(r, codec) }) {
+		return false
+	}
+	if len(f.Renditions) > 0 && !slices.Contains(f.Renditions, r.Name) {
+		return false
+	}
+	return true
+}
+
+// denied returns the indexes of the renditions the filter drops. It fails
+// with ErrNoMatchingRenditions when it would drop all of them.
+func (f ManifestFilter) denied(renditions []models.Rendition) ([]int, error) {
+	var denied []int
+	for idx, r := range renditions {
+		if !f.Matches(r) {
+			denied = append(denied, idx)
+		}
+	}
+	if len(renditions) > 0 && len(denied) == len(renditions) {
+		return nil, ErrNoMatchingRenditions
+	}
+	return denied, nil
+}
+
+// matchesCodec reports whether the video codec of r is codec, given by name
+// or RFC 6381 prefix.
+func matchesCodec(r models.Rendition, codec string) bool {
+	codec = strings.ToLower(codec)
+	videoCodec := r.VideoCodec
+	if videoCodec == "" {
+		videoCodec = "h264"
+	}
+	if codec == videoCodec {
+		return true
+	}
+	prefix, ok := videoCodecPrefixes[codec]
+	if !ok {
+		prefix = codec
+	}
+	// The first CODECS entry is the video codec; HEVC may be tagged hev1
+	return strings.HasPrefix(r.Codecs, prefix) || (prefix == "hvc1" && strings.HasPrefix(r.Codecs, "hev1"))
+}
+
+// renditionBitrate returns the nominal video plus audio bitrate of r.
+func renditionBitrate(r models.Rendition) int64 {
+	video, _ := ParseBitrate(r.VideoBitrate)
+	audio, _ := ParseBitrate(r.AudioBitrate)
+	return video + audio
+}
+
+// ParseBitrate parses a bitrate in bits per second with an optional k or M
+// suffix, as used by ffmpeg, e.g. "3000k" or "2.5M".
+func ParseBitrate(s string) (int64, error) {
+	number := strings.TrimSpace(s)
+	if number == "" {
+		return 0, nil
+	}
+	multiplier := 1.0
+	switch number[len(number)-1] {
+	case 'k', 'K':
+		multiplier, number = 1e3, number[:len(number)-1]
+	case 'm', 'M':
+		multiplier, number = 1e6, number[:len(number)-1]
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", s)
+	}
+	return int64(value * multiplier), nil
+}
@@ -0,0 +1,161 @@
+package media_test
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+)
+
+const filterMaster = "#EXTM3U\n" +
+	"#EXT-X-STREAM-INF:BANDWIDTH=2700000,RESOLUTION=1920x1080,CODECS=\"av01.0.08M.08,mp4a.40.2\"\n0/stream.m3u8\n" +
+	"#EXT-X-STREAM-INF:BANDWIDTH=5200000,RESOLUTION=1920x1080,CODECS=\"avc1.640028,mp4a.40.2\"\n1/stream.m3u8\n" +
+	"#EXT-X-STREAM-INF:BANDWIDTH=3100000,RESOLUTION=1280x720,CODECS=\"avc1.4d401f,mp4a.40.2\"\n2/stream.m3u8\n"
+
+// createLadder stores a transcoded media with an AV1 and an H.264 1080p
+// and an H.264 720p rendition.
+func (e *testEnv) createLadder(t *testing.T) *models.Media {
+	t.Helper()
+
+	m := e.createSource(t, "ladder.mp4")
+	m.TranscodeSource = &models.TranscodeSource{
+		FilePath: path.Join(m.ID.Hex(), "job", "master.m3u8"),
+		Renditions: []models.Rendition{
+			{Name: "1080p-av1", Height: 1080, VideoCodec: "av1", Codecs: "av01.0.08M.08,mp4a.40.2", VideoBitrate: "2500k", AudioBitrate: "128k"},
+			{Name: "1080p", Height: 1080, VideoCodec: "h264", Codecs: "avc1.640028,mp4a.40.2", VideoBitrate: "5000k", AudioBitrate: "128k"},
+			{Name: "720p", Height: 720, VideoCodec: "h264", Codecs: "avc1.4d401f,mp4a.40.2", VideoBitrate: "3000k", AudioBitrate: "96k"},
+		},
+	}
+	if err := e.repo.UpdateMedia(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRewritePlaylistFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter media.ManifestFilter
+		want   []string
+	}{
+		{name: "max height", filter: media.ManifestFilter{MaxHeight: 720}, want: []string{"2/"}},
+		{name: "max bitrate", filter: media.ManifestFilter{MaxBitrate: 3_200_000}, want: []string{"0/", "2/"}},
+		{name: "codec name", filter: media.ManifestFilter{Codecs: []string{"h264"}}, want: []string{"1/", "2/"}},
+		{name: "codec prefix", filter: media.ManifestFilter{Codecs: []string{"av01"}}, want: []string{"0/"}},
+		{name: "renditions", filter: media.ManifestFilter{Renditions: []string{"1080p", "720p"}}, want: []string{"1/", "2/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			m := env.createLadder(t)
+
+			got := rewrite(t, env, media.RewritePlaylistInput{
+				FilePath: m.TranscodeSource.FilePath,
+				Data:     []byte(filterMaster),
+				Filter:   tt.filter,
+			})
+			for _, dir := range []string{"0/", "1/", "2/"} {
+				kept := strings.Contains(got, "\n"+dir+"stream.m3u8")
+				if want := strings.Contains(strings.Join(tt.want, " "), dir); kept != want {
+					t.Errorf("variant %s kept = %v, want %v:\n%s", dir, kept, want, got)
+				}
+			}
+			if strings.Contains(got, "token=") {
+				t.Errorf("token added without one:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestRewritePlaylistFilterErrors(t *testing.T) {
+	env := newTestEnv(t)
+	m := env.createLadder(t)
+
+	_, err := env.svc.RewritePlaylist(context.Background(), media.RewritePlaylistInput{
+		FilePath: m.TranscodeSource.FilePath,
+		Data:     []byte(filterMaster),
+		Filter:   media.ManifestFilter{MaxHeight: 360},
+	})
+	if !errors.Is(err, media.ErrNoMatchingRenditions) {
+		t.Errorf("no match: error = %v", err)
+	}
+
+	_, err = env.svc.RewritePlaylist(context.Background(), media.RewritePlaylistInput{
+		FilePath: "other.mp4/master.m3u8",
+		Data:     []byte(filterMaster),
+		Filter:   media.ManifestFilter{MaxHeight: 720},
+	})
+	if !errors.Is(err, media.ErrMediaNotFound) {
+		t.Errorf("unknown media: error = %v", err)
+	}
+
+	// Child playlists are not filtered, so no media is needed
+	variant := "#EXTM3U\n#EXTINF:4.0,\nseg_001.m4s\n"
+	if got := rewrite(t, env, media.RewritePlaylistInput{
+		FilePath: "other.mp4/0/stream.m3u8",
+		Data:     []byte(variant),
+		Filter:   media.ManifestFilter{MaxHeight: 720},
+	}); got != variant {
+		t.Errorf("child playlist = %q", got)
+	}
+}
+
+func TestPlaybackTokenFilter(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Playback.Secret = "secret"
+	m := env.createLadder(t)
+
+	res, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{
+		MediaID: m.ID.Hex(),
+		Filter:  media.ManifestFilter{Codecs: []string{"h264"}},
+	})
+	if err != nil {
+		t.Fatalf("IssuePlaybackToken: %v", err)
+	}
+	claims, err := env.svc.VerifyPlaybackToken(context.Background(), media.VerifyPlaybackTokenInput{Token: res.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The request filter narrows the token's further
+	got := rewrite(t, env, media.RewritePlaylistInput{
+		FilePath: m.TranscodeSource.FilePath,
+		Data:     []byte(filterMaster),
+		Token:    res.Token,
+		Claims:   claims,
+		Filter:   media.ManifestFilter{MaxHeight: 720},
+	})
+	if strings.Contains(got, "\n0/") || strings.Contains(got, "\n1/") || !strings.Contains(got, "\n2/stream.m3u8?token=") {
+		t.Errorf("playlist = \n%s", got)
+	}
+
+	// Segments of renditions outside the token's filter are refused
+	_, err = env.svc.VerifyPlaybackToken(context.Background(), media.VerifyPlaybackTokenInput{Token: res.Token, FilePath: path.Join(path.Dir(m.TranscodeSource.FilePath), "0", "seg_001.m4s")})
+	if !errors.Is(err, media.ErrPlaybackForbidden) {
+		t.Errorf("denied segment: error = %v", err)
+	}
+
+	_, err = env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{
+		MediaID: m.ID.Hex(),
+		Filter:  media.ManifestFilter{Codecs: []string{"vp9"}},
+	})
+	if !errors.Is(err, media.ErrNoMatchingRenditions) {
+		t.Errorf("no match: error = %v", err)
+	}
+}
+
+func TestParseBitrate(t *testing.T) {
+	for in, want := range map[string]int64{"": 0, "3000000": 3_000_000, "3000k": 3_000_000, "2.5M": 2_500_000} {
+		if got, err := media.ParseBitrate(in); err != nil || got != want {
+			t.Errorf("ParseBitrate(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	if _, err := media.ParseBitrate("fast"); err == nil {
+		t.Error("ParseBitrate(fast) succeeded")
+	}
+}
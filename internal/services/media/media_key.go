@@ -0,0 +1,128 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-svc/internal/models"
+	"media-svc/internal/utils"
+	"media-svc/pkgs/transcoder"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrMediaKeyNotFound is returned when a media has no encryption key.
+var ErrMediaKeyNotFound = errors.New("media key not found")
+
+// encryptionMethod returns the configured HLS encryption method.
+func (i *impl) encryptionMethod() string {
+	if strings.EqualFold(i.cfg.Transcode.Encryption.Method, transcoder.EncryptionSampleAES) {
+		return transcoder.EncryptionSampleAES
+	}
+	return transcoder.EncryptionAES128
+}
+
+// keyURI returns the URI players fetch the content key of a media from.
+func (i *impl) keyURI(mediaID string) string {
+	base := strings.TrimSuffix(i.cfg.Transcode.Encryption.KeyURL, "/")
+	return fmt.Sprintf("%s/v1/videos/%s/key", base, mediaID)
+}
+
+// encryptionForMedia returns the HLS encryption settings of a media, creating
+// and storing its content key on first use so re-transcodes keep the same
+// key. It returns nil when encryption is disabled.
+func (i *impl) encryptionForMedia(ctx context.Context, mediaID string) (*transcoder.Encryption, error) {
+	if !i.cfg.Transcode.Encryption.Enabled {
+		return nil, nil
+	}
+
+	masterKey, err := utils.DecodeMasterKey(i.cfg.Transcode.Encryption.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := i.mediaRepo.GetMediaKeyByMediaID(ctx, mediaID)
+	if err != nil {
+		return nil, fmt.Errorf("get media key: %w", err)
+	}
+	if stored == nil {
+		if stored, err = i.createMediaKey(ctx, masterKey, mediaID); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := utils.Open(masterKey, stored.EncryptedKey, stored.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("open media key: %w", err)
+	}
+
+	return &transcoder.Encryption{
+		Method: stored.Method,
+		Key:    key,
+		KeyID:  stored.KeyID,
+		KeyURI: i.keyURI(mediaID),
+	}, nil
+}
+
+// createMediaKey generates a random content key for a media and stores it
+// sealed with the master key.
+func (i *impl) createMediaKey(ctx context.Context, masterKey []byte, mediaID string) (*models.MediaKey, error) {
+	mediaObjectId, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := utils.RandomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	sealed, nonce, err := utils.Seal(masterKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("seal media key: %w", err)
+	}
+
+	stored := &models.MediaKey{
+		MediaID:      mediaObjectId,
+		Method:       i.encryptionMethod(),
+		EncryptedKey: sealed,
+		Nonce:        nonce,
+	}
+	// AES-128 segments use their sequence number as IV, so only SAMPLE-AES
+	// needs more than the key.
+	if stored.Method == transcoder.EncryptionSampleAES {
+		if stored.KeyID, err = utils.RandomBytes(16); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.mediaRepo.CreateMediaKey(ctx, stored); err != nil {
+		// Another worker may have created the key concurrently.
+		existing, getErr := i.mediaRepo.GetMediaKeyByMediaID(ctx, mediaID)
+		if getErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("create media key: %w", err)
+	}
+
+	return stored, nil
+}
+
+// GetMediaKey returns the plaintext content key of an encrypted media.
+func (i *impl) GetMediaKey(ctx context.Context, mediaID string) ([]byte, error) {
+
+	stored, err := i.mediaRepo.GetMediaKeyByMediaID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, ErrMediaKeyNotFound
+	}
+
+	masterKey, err := utils.DecodeMasterKey(i.cfg.Transcode.Encryption.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.Open(masterKey, stored.EncryptedKey, stored.Nonce)
+}
@@ -3,24 +3,25 @@ package media
 import (
 	"media-svc/config"
 	"media-svc/internal/adapters/minio"
-	"media-svc/internal/adapters/mongodb/media"
-	"media-svc/pkgs/rabbitmq"
+	"media-svc/pkgs/scratch"
 )
 
 type impl struct {
 	cfg           *config.Config
-	mediaRepo     *media.MediaRepository
+	mediaRepo     MediaRepository
 	mediaStorage  minio.StorageAdapter
 	streamStorage minio.StorageAdapter
-	rabbitClient  *rabbitmq.Publisher
+	rabbitClient  Publisher
+	scratch       *scratch.Manager
 }
 
 func NewService(
 	cfg *config.Config,
-	mediaRepo *media.MediaRepository,
+	mediaRepo MediaRepository,
 	mediaStorage minio.StorageAdapter,
 	streamStorage minio.StorageAdapter,
-	rabbitClient *rabbitmq.Publisher,
+	rabbitClient Publisher,
+	scratch *scratch.Manager,
 ) MediaService {
 	return &impl{
 		cfg:           cfg,
@@ -28,5 +29,6 @@ func NewService(
 		mediaStorage:  mediaStorage,
 		streamStorage: streamStorage,
 		rabbitClient:  rabbitClient,
+		scratch:       scratch,
 	}
 }
@@ -0,0 +1,48 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-svc/internal/models"
+	"media-svc/internal/services/auth"
+)
+
+// ErrForbidden is returned when the caller is neither the owner of a media
+// or upload nor an admin.
+var ErrForbidden = errors.New("forbidden")
+
+// authorize checks that the caller may access what ownerID owns. Calls
+// without a principal, from the worker or with authentication disabled,
+// are not restricted.
+func authorize(ctx context.Context, ownerID string) error {
+	p := auth.PrincipalFromContext(ctx)
+	if p == nil || p.CanAccess(ownerID) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// callerID returns the owner of what the caller creates, empty without a
+// principal.
+func callerID(ctx context.Context) string {
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		return p.Subject
+	}
+	return ""
+}
+
+// ownedMedia returns a media the caller may access.
+func (i *impl) ownedMedia(ctx context.Context, id string) (*models.Media, error) {
+	media, err := i.mediaRepo.GetMedia(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+	if media == nil {
+		return nil, ErrMediaNotFound
+	}
+	if err := authorize(ctx, media.OwnerID); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
@@ -0,0 +1,118 @@
+package media_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/auth"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+)
+
+func asCaller(subject string, admin bool) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject, Admin: admin})
+}
+
+func TestMediaOwnership(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := asCaller("alice", false)
+
+	uploaded, err := env.svc.UploadVideo(ctx, media.UploadVideoInput{File: fileHeader(t, "clip.mp4", []byte("source"))})
+	if err != nil {
+		t.Fatalf("UploadVideo: %v", err)
+	}
+	if uploaded.OwnerID != "alice" {
+		t.Fatalf("OwnerID = %q, want alice", uploaded.OwnerID)
+	}
+	id := uploaded.ID.Hex()
+	other := env.createSource(t, "other.mp4")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "owner", ctx: ctx},
+		{name: "admin", ctx: asCaller("root", true)},
+		{name: "other user", ctx: asCaller("bob", false), wantErr: media.ErrForbidden},
+		{name: "no principal", ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.svc.GetMedia(tt.ctx, id); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetMedia error = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := env.svc.GetVideoStatus(tt.ctx, id); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetVideoStatus error = %v, want %v", err, tt.wantErr)
+			}
+			name := "renamed by " + tt.name
+			if _, err := env.svc.UpdateMedia(tt.ctx, media.UpdateMediaInput{ID: id, Name: &name}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateMedia error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Media without an owner are left to admins
+	if _, err := env.svc.GetMedia(ctx, other.ID.Hex()); !errors.Is(err, media.ErrForbidden) {
+		t.Errorf("GetMedia of unowned media error = %v, want %v", err, media.ErrForbidden)
+	}
+
+	medias, err := env.svc.ListMedia(ctx, media.ListMediaInput{})
+	if err != nil || len(medias) != 1 || medias[0].ID != uploaded.ID {
+		t.Errorf("ListMedia as owner = %v, %v", medias, err)
+	}
+	medias, err = env.svc.ListMedia(asCaller("root", true), media.ListMediaInput{})
+	if err != nil || len(medias) != 2 {
+		t.Errorf("ListMedia as admin = %d media, %v", len(medias), err)
+	}
+}
+
+func TestDeleteMedia(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	source := env.createSource(t, "clip.mp4")
+	source.OwnerID = "alice"
+	source.TranscodeSource = &models.TranscodeSource{FilePath: "clip.mp4/master.m3u8"}
+	if err := env.repo.UpdateMedia(ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"clip.mp4/master.m3u8", "clip.mp4/0/index.m3u8", "clip.mp4.other/master.m3u8"} {
+		if _, err := env.stream.PutObject(ctx, name, strings.NewReader("#EXTM3U"), 7); err != nil {
+			t.Fatal(err)
+		}
+	}
+	job := env.createJob(t, source, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusProcessing.String()})
+	id := source.ID.Hex()
+
+	if err := env.svc.DeleteMedia(asCaller("bob", false), id); !errors.Is(err, media.ErrForbidden) {
+		t.Fatalf("DeleteMedia by other user error = %v, want %v", err, media.ErrForbidden)
+	}
+	if err := env.svc.DeleteMedia(asCaller("alice", false), id); !errors.Is(err, media.ErrJobInProgress) {
+		t.Fatalf("DeleteMedia while transcoding error = %v, want %v", err, media.ErrJobInProgress)
+	}
+
+	job.Status = types.TranscodeJobStatusDone.String()
+	if err := env.repo.UpdateTranscodeJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.svc.DeleteMedia(asCaller("alice", false), id); err != nil {
+		t.Fatalf("DeleteMedia: %v", err)
+	}
+
+	if m, _ := env.repo.GetMedia(ctx, id); m != nil {
+		t.Error("media still stored")
+	}
+	if jobs := env.repo.TranscodeJobs(); len(jobs) != 0 {
+		t.Errorf("jobs left = %v", jobs)
+	}
+	if keys := env.storage.Keys(); len(keys) != 0 {
+		t.Errorf("source objects left = %v", keys)
+	}
+	if keys := env.stream.Keys(); !slices.Equal(keys, []string{"clip.mp4.other/master.m3u8"}) {
+		t.Errorf("stream objects left = %v", keys)
+	}
+}
@@ -0,0 +1,261 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"media-svc/internal/models"
+	"media-svc/internal/utils"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPlaybackTokenTTL = time.Hour
+
+var (
+	// ErrPlaybackDisabled is returned when no playback token secret is
+	// configured.
+	ErrPlaybackDisabled = errors.New("playback tokens are not configured")
+	// ErrMediaNotReady is returned for a media without transcoded output,
+	// or whose output predates the per-media output prefixes and must be
+	// transcoded again.
+	ErrMediaNotReady = errors.New("media has not been transcoded")
+	// ErrRenditionNotFound is returned for a rendition the media lacks.
+	ErrRenditionNotFound = errors.New("rendition not found")
+	// ErrPlaybackTokenMissing is returned for a stream request without a
+	// token.
+	ErrPlaybackTokenMissing = errors.New("playback token required")
+	// ErrPlaybackTokenInvalid is returned for a token that is malformed or
+	// not signed with the configured secret.
+	ErrPlaybackTokenInvalid = errors.New("invalid playback token")
+	// ErrPlaybackTokenExpired is returned for a token past its expiry.
+	ErrPlaybackTokenExpired = errors.New("playback token expired")
+	// ErrPlaybackForbidden is returned when a valid token does not cover
+	// the request, e.g. another media or client.
+	ErrPlaybackForbidden = errors.New("playback token does not allow this request")
+)
+
+// PlaybackClaims are the signed contents of a playback token. They hold all
+// that is needed to check a stream request without a database lookup.
+type PlaybackClaims struct {
+	MediaID      string `json:"mid"`
+	Prefix       string `json:"pfx"`           // Directory of the stream files of the media
+	ExpiresAt    int64  `json:"exp"`           // Unix seconds
+	IP           string `json:"ip,omitempty"`  // Client IP the token is bound to
+	Referrer     string `json:"ref,omitempty"` // Host the Referer or Origin header must name
+	MaxRendition string `json:"max,omitempty"` // Highest rendition the token plays
+	// Denied are the indexes of the renditions above MaxRendition.
+	Denied []int `json:"deny,omitempty"`
+	// Audio is set when renditions carry audio, so DASH stream ids
+	// alternate between video and audio.
+	Audio bool `json:"aud,omitempty"`
+}
+
+type IssuePlaybackTokenInput struct {
+	MediaID      string
+	TTL          time.Duration // Lifetime of the token, 0 for the configured default
+	IP           string        // Optional client IP to bind the token to
+	Referrer     string        // Optional host or URL the requests must come from
+	MaxRendition string        // Optional name of the highest rendition to play
+	// Filter restricts the renditions the token plays, e.g. for a tier.
+	Filter ManifestFilter
+}
+
+type IssuePlaybackTokenOutput struct {
+	Token     string
+	ExpiresAt time.Time
+	// HLSPath and DASHPath are the stream file paths of the master playlist
+	// and the DASH manifest, which encrypted media do not have.
+	HLSPath  string
+	DASHPath string
+}
+
+// IssuePlaybackToken signs a token that authorizes stream requests for the
+// files of one media until it expires.
+func (i *impl) IssuePlaybackToken(ctx context.Context, input IssuePlaybackTokenInput) (*IssuePlaybackTokenOutput, error) {
+	if i.cfg.Playback.Secret == "" {
+		return nil, ErrPlaybackDisabled
+	}
+
+	media, err := i.ownedMedia(ctx, input.MediaID)
+	if err != nil {
+		return nil, err
+	}
+	source := media.TranscodeSource
+	if source == nil || source.FilePath == "" {
+		return nil, ErrMediaNotReady
+	}
+	// The token covers the output prefix, which must belong to the media
+	// alone
+	prefix := path.Dir(source.FilePath)
+	if !inMediaPrefix(prefix, media.ID.Hex()) {
+		return nil, ErrMediaNotReady
+	}
+
+	ttl := input.TTL
+	if ttl <= 0 {
+		ttl = i.cfg.Playback.TokenTTL
+	}
+	if ttl <= 0 {
+		ttl = defaultPlaybackTokenTTL
+	}
+	if maxTTL := i.cfg.Playback.MaxTokenTTL; maxTTL > 0 && ttl > maxTTL {
+		ttl = maxTTL
+	}
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	claims := PlaybackClaims{
+		MediaID:   media.ID.Hex(),
+		Prefix:    prefix,
+		ExpiresAt: expiresAt.Unix(),
+		IP:        input.IP,
+		Referrer:  referrerHost(input.Referrer),
+	}
+	claims.Audio = renditionsHaveAudio(source.Renditions)
+	if input.MaxRendition != "" {
+		idx := slices.IndexFunc(source.Renditions, func(r models.Rendition) bool { return r.Name == input.MaxRendition })
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrRenditionNotFound, input.MaxRendition)
+		}
+		claims.MaxRendition = input.MaxRendition
+		input.Filter.MaxHeight = minPositive(input.Filter.MaxHeight, source.Renditions[idx].Height)
+	}
+	if claims.Denied, err = input.Filter.denied(source.Renditions); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	out := &IssuePlaybackTokenOutput{
+		Token:     utils.SignToken([]byte(i.cfg.Playback.Secret), payload),
+		ExpiresAt: expiresAt,
+		HLSPath:   source.FilePath,
+	}
+	if source.EncryptionMethod == "" {
+		out.DASHPath = path.Join(claims.Prefix, dashManifestName)
+	}
+	return out, nil
+}
+
+type VerifyPlaybackTokenInput struct {
+	Token    string
+	MediaID  string // Media the request is for, empty to skip the check
+	FilePath string // Stream file the request is for, empty to skip the check
+	IP       string // Client IP of the request
+	Referrer string // Referer or Origin header of the request
+}
+
+// VerifyPlaybackToken checks the signature and expiry of a playback token
+// and that it covers the request.
+func (i *impl) VerifyPlaybackToken(ctx context.Context, input VerifyPlaybackTokenInput) (*PlaybackClaims, error) {
+	if i.cfg.Playback.Secret == "" {
+		return nil, ErrPlaybackDisabled
+	}
+	if input.Token == "" {
+		return nil, ErrPlaybackTokenMissing
+	}
+
+	payload, err := utils.VerifyToken([]byte(i.cfg.Playback.Secret), input.Token)
+	if err != nil {
+		return nil, ErrPlaybackTokenInvalid
+	}
+	var claims PlaybackClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrPlaybackTokenInvalid
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrPlaybackTokenExpired
+	}
+	if claims.IP != "" && claims.IP != input.IP {
+		return nil, fmt.Errorf("%w: client IP", ErrPlaybackForbidden)
+	}
+	if claims.Referrer != "" && !strings.EqualFold(claims.Referrer, referrerHost(input.Referrer)) {
+		return nil, fmt.Errorf("%w: referrer", ErrPlaybackForbidden)
+	}
+	if input.MediaID != "" && input.MediaID != claims.MediaID {
+		return nil, fmt.Errorf("%w: media", ErrPlaybackForbidden)
+	}
+	if input.FilePath != "" {
+		rel, ok := strings.CutPrefix(path.Clean("/" + input.FilePath)[1:], claims.Prefix+"/")
+		if !ok || !inMediaPrefix(claims.Prefix, claims.MediaID) {
+			return nil, fmt.Errorf("%w: media", ErrPlaybackForbidden)
+		}
+		if claims.denies(rel) {
+			return nil, fmt.Errorf("%w: rendition", ErrPlaybackForbidden)
+		}
+	}
+	return &claims, nil
+}
+
+// inMediaPrefix reports whether an output prefix lies under the directory
+// of the media, as the prefixes of every transcode attempt do.
+func inMediaPrefix(prefix, mediaID string) bool {
+	return strings.HasPrefix(prefix, mediaID+"/")
+}
+
+// dashStreamFile matches the DASH init and media segments ffmpeg names
+// after their stream id.
+var dashStreamFile = regexp.MustCompile(`^(?:init|chunk)-stream(\d+)[-.]`)
+
+// denies reports whether the stream file at rel, relative to the prefix,
+// belongs to a denied rendition. HLS files are in a directory named after
+// the rendition index; DASH files are named after the output stream id.
+func (c *PlaybackClaims) denies(rel string) bool {
+	if len(c.Denied) == 0 {
+		return false
+	}
+	if dir, _, ok := strings.Cut(rel, "/"); ok {
+		idx, err := strconv.Atoi(dir)
+		return err == nil && slices.Contains(c.Denied, idx)
+	}
+	if m := dashStreamFile.FindStringSubmatch(rel); m != nil {
+		id, _ := strconv.Atoi(m[1])
+		return c.deniesDASHStream(id)
+	}
+	return false
+}
+
+// deniesDASHStream reports whether a DASH stream id belongs to a denied
+// rendition.
+func (c *PlaybackClaims) deniesDASHStream(id int) bool {
+	if c.Audio {
+		id /= 2
+	}
+	return slices.Contains(c.Denied, id)
+}
+
+// renditionsHaveAudio reports whether the variants carry audio, which their
+// CODECS value then lists after the video codec.
+func renditionsHaveAudio(renditions []models.Rendition) bool {
+	return slices.ContainsFunc(renditions, func(r models.Rendition) bool { return strings.Contains(r.Codecs, ",") })
+}
+
+// minPositive returns the smaller of a and b, ignoring zero.
+func minPositive(a, b int) int {
+	if a <= 0 {
+		return b
+	}
+	return min(a, b)
+}
+
+// referrerHost returns the host of a Referer or Origin value, or the value
+// itself when it is a bare host.
+func referrerHost(referrer string) string {
+	if !strings.Contains(referrer, "://") {
+		return strings.ToLower(referrer)
+	}
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
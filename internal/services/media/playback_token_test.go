@@ -0,0 +1,206 @@
+package media_test
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+)
+
+// createTranscoded stores a media with a 1080p and a 720p rendition under
+// an attempt prefix of the media.
+func (e *testEnv) createTranscoded(t *testing.T) *models.Media {
+	t.Helper()
+
+	m := e.createSource(t, "clip.mp4")
+	m.TranscodeSource = &models.TranscodeSource{
+		FilePath: path.Join(m.ID.Hex(), "job", "master.m3u8"),
+		Renditions: []models.Rendition{
+			{Name: "1080p", Width: 1920, Height: 1080, Codecs: "avc1.640028,mp4a.40.2"},
+			{Name: "720p", Width: 1280, Height: 720, Codecs: "avc1.4d401f,mp4a.40.2"},
+		},
+	}
+	if err := e.repo.UpdateMedia(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestPlaybackToken(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Playback.Secret = "secret"
+	m := env.createTranscoded(t)
+	prefix := path.Dir(m.TranscodeSource.FilePath)
+
+	res, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{
+		MediaID:      m.ID.Hex(),
+		IP:           "203.0.113.7",
+		Referrer:     "https://player.example.com/watch",
+		MaxRendition: "720p",
+	})
+	if err != nil {
+		t.Fatalf("IssuePlaybackToken: %v", err)
+	}
+	if res.HLSPath != prefix+"/master.m3u8" || res.DASHPath != prefix+"/manifest.mpd" || time.Until(res.ExpiresAt) <= 0 {
+		t.Errorf("output = %+v", res)
+	}
+
+	valid := media.VerifyPlaybackTokenInput{
+		Token:    res.Token,
+		FilePath: prefix + "/1/seg_001.m4s",
+		IP:       "203.0.113.7",
+		Referrer: "https://player.example.com/other",
+	}
+	if _, err := env.svc.VerifyPlaybackToken(context.Background(), valid); err != nil {
+		t.Errorf("VerifyPlaybackToken: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(in *media.VerifyPlaybackTokenInput)
+		want   error
+	}{
+		{name: "missing", modify: func(in *media.VerifyPlaybackTokenInput) { in.Token = "" }, want: media.ErrPlaybackTokenMissing},
+		{name: "tampered", modify: func(in *media.VerifyPlaybackTokenInput) { in.Token = "x" + in.Token }, want: media.ErrPlaybackTokenInvalid},
+		{name: "other IP", modify: func(in *media.VerifyPlaybackTokenInput) { in.IP = "198.51.100.1" }, want: media.ErrPlaybackForbidden},
+		{name: "other referrer", modify: func(in *media.VerifyPlaybackTokenInput) { in.Referrer = "https://evil.example.org/" }, want: media.ErrPlaybackForbidden},
+		{name: "other media", modify: func(in *media.VerifyPlaybackTokenInput) { in.FilePath = "other.mp4/master.m3u8" }, want: media.ErrPlaybackForbidden},
+		{name: "path escape", modify: func(in *media.VerifyPlaybackTokenInput) { in.FilePath = prefix + "/../other.mp4/master.m3u8" }, want: media.ErrPlaybackForbidden},
+		{name: "denied rendition", modify: func(in *media.VerifyPlaybackTokenInput) { in.FilePath = prefix + "/0/seg_001.m4s" }, want: media.ErrPlaybackForbidden},
+		{name: "denied dash stream", modify: func(in *media.VerifyPlaybackTokenInput) { in.FilePath = prefix + "/chunk-stream1-00001.m4s" }, want: media.ErrPlaybackForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid
+			tt.modify(&in)
+			if _, err := env.svc.VerifyPlaybackToken(context.Background(), in); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaybackTokenExpired(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Playback.Secret = "secret"
+	m := env.createTranscoded(t)
+
+	res, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{MediaID: m.ID.Hex(), TTL: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("IssuePlaybackToken: %v", err)
+	}
+	time.Sleep(time.Second)
+
+	_, err = env.svc.VerifyPlaybackToken(context.Background(), media.VerifyPlaybackTokenInput{Token: res.Token})
+	if !errors.Is(err, media.ErrPlaybackTokenExpired) {
+		t.Errorf("error = %v, want ErrPlaybackTokenExpired", err)
+	}
+}
+
+func TestIssuePlaybackTokenErrors(t *testing.T) {
+	env := newTestEnv(t)
+	m := env.createTranscoded(t)
+	source := env.createSource(t, "raw.mp4")
+
+	if _, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{MediaID: m.ID.Hex()}); !errors.Is(err, media.ErrPlaybackDisabled) {
+		t.Errorf("without secret: error = %v", err)
+	}
+
+	env.cfg.Playback.Secret = "secret"
+	if _, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{MediaID: source.ID.Hex()}); !errors.Is(err, media.ErrMediaNotReady) {
+		t.Errorf("untranscoded: error = %v", err)
+	}
+	if _, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{MediaID: m.ID.Hex(), MaxRendition: "4k"}); !errors.Is(err, media.ErrRenditionNotFound) {
+		t.Errorf("unknown rendition: error = %v", err)
+	}
+
+	// Output written outside the media's directory could be shared with
+	// another upload of the same name
+	m.TranscodeSource.FilePath = "clip.mp4/master.m3u8"
+	if err := env.repo.UpdateMedia(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{MediaID: m.ID.Hex()}); !errors.Is(err, media.ErrMediaNotReady) {
+		t.Errorf("legacy prefix: error = %v", err)
+	}
+}
+
+func TestRewritePlaylist(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Playback.Secret = "secret"
+	m := env.createTranscoded(t)
+	prefix := path.Dir(m.TranscodeSource.FilePath)
+
+	res, err := env.svc.IssuePlaybackToken(context.Background(), media.IssuePlaybackTokenInput{MediaID: m.ID.Hex(), MaxRendition: "720p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := env.svc.VerifyPlaybackToken(context.Background(), media.VerifyPlaybackTokenInput{Token: res.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	master := "#EXTM3U\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080\n0/stream.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1280x720\n1/stream.m3u8\n" +
+		"#EXT-X-IMAGE-STREAM-INF:BANDWIDTH=1000,URI=\"images.m3u8\"\n"
+	got := rewrite(t, env, media.RewritePlaylistInput{FilePath: prefix + "/master.m3u8", Data: []byte(master), Token: res.Token, Claims: claims})
+	if strings.Contains(got, "0/stream.m3u8") || strings.Contains(got, "RESOLUTION=1920x1080") {
+		t.Errorf("denied variant kept:\n%s", got)
+	}
+	if !strings.Contains(got, "1/stream.m3u8?token=") || !strings.Contains(got, `URI="images.m3u8?token=`) {
+		t.Errorf("URIs without token:\n%s", got)
+	}
+
+	variant := "#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"http://localhost/v1/videos/x/key\"\n#EXT-X-MAP:URI=\"init_1.mp4\"\n#EXTINF:4.0,\nseg_001.m4s\n"
+	got = rewrite(t, env, media.RewritePlaylistInput{FilePath: prefix + "/1/stream.m3u8", Data: []byte(variant), Token: res.Token, Claims: claims})
+	for _, want := range []string{`/key?token=`, `URI="init_1.mp4?token=`, "\nseg_001.m4s?token="} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	mpd := `<MPD>
+	<Period>
+		<AdaptationSet id="0" contentType="video">
+			<Representation id="0" bandwidth="5000000" width="1920" height="1080">
+				<SegmentTemplate initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s"/>
+			</Representation>
+			<Representation id="2" bandwidth="3000000" width="1280" height="720">
+				<SegmentTemplate initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s"/>
+			</Representation>
+		</AdaptationSet>
+		<AdaptationSet id="1" contentType="audio">
+			<Representation id="1" bandwidth="128000">
+				<SegmentTemplate initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s"/>
+			</Representation>
+			<Representation id="3" bandwidth="96000">
+				<SegmentTemplate initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s"/>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+</MPD>`
+	got = rewrite(t, env, media.RewritePlaylistInput{FilePath: prefix + "/manifest.mpd", Data: []byte(mpd), Token: res.Token, Claims: claims})
+	if strings.Contains(got, `Representation id="0"`) || strings.Contains(got, `Representation id="1"`) {
+		t.Errorf("denied representations kept:\n%s", got)
+	}
+	if !strings.Contains(got, `Representation id="2"`) || !strings.Contains(got, `Representation id="3"`) ||
+		!strings.Contains(got, `media="chunk-stream$RepresentationID$-$Number%05d$.m4s?token=`) {
+		t.Errorf("manifest = \n%s", got)
+	}
+}
+
+func rewrite(t *testing.T, env *testEnv, input media.RewritePlaylistInput) string {
+	t.Helper()
+
+	data, err := env.svc.RewritePlaylist(context.Background(), input)
+	if err != nil {
+		t.Fatalf("RewritePlaylist: %v", err)
+	}
+	return string(data)
+}
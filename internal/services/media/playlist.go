@@ -0,0 +1,208 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-svc/internal/models"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// File names of the master playlist and DASH manifest of a transcode.
+const (
+	masterPlaylistName = "master.m3u8"
+	dashManifestName   = "manifest.mpd"
+)
+
+// IsPlaylist reports whether a stream file is an HLS playlist or a DASH
+// manifest, which reference other stream files.
+func IsPlaylist(filePath string) bool {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".m3u8", ".mpd":
+		return true
+	default:
+		return false
+	}
+}
+
+type RewritePlaylistInput struct {
+	FilePath string
+	Data     []byte
+	// Token is added to every URI so the player's requests for child
+	// playlists, segments and keys are authorized too, and Claims are its
+	// contents. Both are empty when streams are served without tokens.
+	Token  string
+	Claims *PlaybackClaims
+	// Filter selects the variants of a master playlist or DASH manifest.
+	Filter ManifestFilter
+}
+
+// RewritePlaylist rewrites a playlist or manifest for one request: variants
+// of renditions the token or the filter exclude are dropped, and the token
+// is passed on to every URI.
+func (i *impl) RewritePlaylist(ctx context.Context, input RewritePlaylistInput) ([]byte, error) {
+	filePath := path.Clean("/" + input.FilePath)[1:]
+
+	scope := PlaybackClaims{Prefix: path.Dir(filePath)}
+	if input.Claims != nil {
+		scope = *input.Claims
+	}
+	if !input.Filter.IsZero() && isMasterPlaylist(filePath) {
+		media, err := i.streamMedia(ctx, filePath, input.Claims)
+		if err != nil {
+			return nil, err
+		}
+		renditions := media.TranscodeSource.Renditions
+		denied, err := input.Filter.denied(renditions)
+		if err != nil {
+			return nil, err
+		}
+
+		// The filter only narrows what the token allows
+		scope.Denied = slices.Clone(scope.Denied)
+		for _, idx := range denied {
+			if !slices.Contains(scope.Denied, idx) {
+				scope.Denied = append(scope.Denied, idx)
+			}
+		}
+		if len(scope.Denied) == len(renditions) {
+			return nil, ErrNoMatchingRenditions
+		}
+		scope.Audio = renditionsHaveAudio(renditions)
+	}
+
+	if strings.EqualFold(path.Ext(filePath), ".mpd") {
+		return rewriteDASHManifest(input.Data, input.Token, &scope), nil
+	}
+	dir := path.Dir(filePath)
+	return rewriteHLSPlaylist(input.Data, input.Token, func(uri string) bool {
+		rel, ok := strings.CutPrefix(path.Join(dir, uri), scope.Prefix+"/")
+		return ok && scope.denies(rel)
+	}), nil
+}
+
+// isMasterPlaylist reports whether a stream file lists the renditions of a
+// media, so that a filter applies to it.
+func isMasterPlaylist(filePath string) bool {
+	base := path.Base(filePath)
+	return base == masterPlaylistName || base == dashManifestName
+}
+
+// streamMedia returns the media a master playlist belongs to, by the media
+// of the token or else by the path of its output.
+func (i *impl) streamMedia(ctx context.Context, filePath string, claims *PlaybackClaims) (*models.Media, error) {
+	var (
+		media *models.Media
+		err   error
+	)
+	if claims != nil {
+		media, err = i.mediaRepo.GetMedia(ctx, claims.MediaID)
+	} else {
+		media, err = i.mediaRepo.GetMediaByStreamPath(ctx, path.Join(path.Dir(filePath), masterPlaylistName))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+	if media == nil || media.TranscodeSource == nil {
+		return nil, ErrMediaNotFound
+	}
+	return media, nil
+}
+
+// m3u8URIAttribute matches the URI attribute of tags such as EXT-X-MEDIA,
+// EXT-X-MAP and EXT-X-KEY.
+var m3u8URIAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+// rewriteHLSPlaylist adds token, if any, to the URI lines and URI
+// attributes of an HLS playlist. Variants and renditions whose URI is denied are dropped.
+func rewriteHLSPlaylist(data []byte, token string, denied func(uri string) bool) []byte {
+	lines := strings.Split(string(data), "\n")
+	out := make([]string, 0, len(lines))
+	for n := 0; n < len(lines); n++ {
+		line := strings.TrimRight(lines[n], "\r")
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			// The variant URI is on the next line
+			if n+1 < len(lines) && denied(strings.TrimSpace(lines[n+1])) {
+				n++
+				continue
+			}
+		case strings.HasPrefix(line, "#"):
+			m := m3u8URIAttribute.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			if !strings.HasPrefix(line, "#EXT-X-KEY:") && !strings.HasPrefix(line, "#EXT-X-MAP:") && denied(m[1]) {
+				continue
+			}
+			line = strings.Replace(line, m[0], `URI="`+withToken(m[1], token)+`"`, 1)
+		case strings.TrimSpace(line) != "":
+			line = withToken(strings.TrimSpace(line), token)
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+var (
+	dashAdaptationSet       = regexp.MustCompile(`(?s)[ \t]*<AdaptationSet\b.*?</AdaptationSet>\n?`)
+	dashRepresentation      = regexp.MustCompile(`(?s)[ \t]*<Representation\b[^>]*?(?:/>|>.*?</Representation>)\n?`)
+	dashRepresentationID    = regexp.MustCompile(`^\s*<Representation\b[^>]*\bid="(\d+)"`)
+	dashSegmentURLAttribute = regexp.MustCompile(`\b(media|initialization)="([^"]*)"`)
+)
+
+// rewriteDASHManifest adds token, if any, to the segment templates of a
+// DASH manifest. Representations of denied renditions are dropped, and so are
+// adaptation sets left without any.
+func rewriteDASHManifest(data []byte, token string, claims *PlaybackClaims) []byte {
+	manifest := string(data)
+	if len(claims.Denied) > 0 {
+		manifest = dashAdaptationSet.ReplaceAllStringFunc(manifest, func(set string) string {
+			set = dashRepresentation.ReplaceAllStringFunc(set, func(rep string) string {
+				m := dashRepresentationID.FindStringSubmatch(rep)
+				if m == nil {
+					return rep
+				}
+				id, _ := strconv.Atoi(m[1])
+				if claims.deniesDASHStream(id) {
+					return ""
+				}
+				return rep
+			})
+			if !strings.Contains(set, "<Representation") {
+				return ""
+			}
+			return set
+		})
+	}
+
+	if token == "" {
+		return []byte(manifest)
+	}
+	// The URIs are XML attribute values, so the separator is escaped
+	manifest = dashSegmentURLAttribute.ReplaceAllStringFunc(manifest, func(attr string) string {
+		m := dashSegmentURLAttribute.FindStringSubmatch(attr)
+		sep := "?"
+		if strings.Contains(m[2], "?") {
+			sep = "&amp;"
+		}
+		return m[1] + `="` + m[2] + sep + "token=" + url.QueryEscape(token) + `"`
+	})
+	return []byte(manifest)
+}
+
+// withToken adds the token query parameter to uri, if there is a token.
+func withToken(uri, token string) string {
+	if token == "" {
+		return uri
+	}
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
+	}
+	return uri + sep + "token=" + url.QueryEscape(token)
+}
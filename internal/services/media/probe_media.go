@@ -0,0 +1,109 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"media-svc/internal/models"
+	"media-svc/pkgs/transcoder"
+	"time"
+)
+
+// uploadProbeTimeout bounds the probe of a new upload, which runs within
+// the upload request.
+const uploadProbeTimeout = 30 * time.Second
+
+// probeMedia extracts the source metadata of a video, given by local path or
+// URL, and stores it on the media document, filling in its duration and
+// display dimensions.
+func (i *impl) probeMedia(ctx context.Context, media *models.Media, source string) (*transcoder.ProbeResult, error) {
+
+	probe, err := transcoder.Probe(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	media.Probe = newMediaProbe(probe)
+	media.Duration = probe.Duration.Seconds()
+	if video := probe.PrimaryVideo(); video != nil {
+		media.Width, media.Height = video.DisplaySize()
+	}
+
+	if err := i.mediaRepo.SetMediaProbe(ctx, media); err != nil {
+		return nil, fmt.Errorf("update media probe: %w", err)
+	}
+
+	return probe, nil
+}
+
+// probeUploadedMedia probes a new source in storage through a presigned URL,
+// so that its metadata is known before a worker picks up the transcode. A
+// failure is only logged, as the transcode probes the downloaded file again.
+func (i *impl) probeUploadedMedia(ctx context.Context, media *models.Media) {
+	ctx, cancel := context.WithTimeout(ctx, uploadProbeTimeout)
+	defer cancel()
+
+	url, err := i.mediaStorage.PresignGetObject(ctx, media.Path, uploadProbeTimeout)
+	if err == nil {
+		_, err = i.probeMedia(ctx, media, url)
+	}
+	if err != nil {
+		log.Printf("probe upload of media %s: %v", media.ID.Hex(), err)
+	}
+}
+
+func newMediaProbe(probe *transcoder.ProbeResult) *models.MediaProbe {
+	out := &models.MediaProbe{
+		FormatName:     probe.FormatName,
+		FormatLongName: probe.FormatLongName,
+		Duration:       probe.Duration.Seconds(),
+		BitRate:        probe.BitRate,
+		ProbedAt:       time.Now().UTC(),
+	}
+
+	for _, v := range probe.VideoStreams {
+		out.VideoStreams = append(out.VideoStreams, models.VideoStreamInfo{
+			Index:          v.Index,
+			Codec:          v.Codec,
+			Profile:        v.Profile,
+			Level:          v.Level,
+			Width:          v.Width,
+			Height:         v.Height,
+			PixelFormat:    v.PixelFormat,
+			FrameRate:      v.AvgFrameRate,
+			BitRate:        v.BitRate,
+			Rotation:       v.Rotation,
+			Language:       v.Language,
+			ColorRange:     v.ColorRange,
+			ColorSpace:     v.ColorSpace,
+			ColorTransfer:  v.ColorTransfer,
+			ColorPrimaries: v.ColorPrimaries,
+			HDRFormat:      v.HDRFormat,
+		})
+	}
+
+	for _, a := range probe.AudioStreams {
+		out.AudioStreams = append(out.AudioStreams, models.AudioStreamInfo{
+			Index:         a.Index,
+			Codec:         a.Codec,
+			Profile:       a.Profile,
+			SampleRate:    a.SampleRate,
+			Channels:      a.Channels,
+			ChannelLayout: a.ChannelLayout,
+			BitRate:       a.BitRate,
+			Language:      a.Language,
+		})
+	}
+
+	for _, s := range probe.SubtitleStreams {
+		out.SubtitleStreams = append(out.SubtitleStreams, models.SubtitleStreamInfo{
+			Index:    s.Index,
+			Codec:    s.Codec,
+			Language: s.Language,
+			Title:    s.Title,
+			Forced:   s.Forced,
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,71 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// outboxMaxBackoff caps the wait between publish attempts of a message.
+const outboxMaxBackoff = 5 * time.Minute
+
+// PublishOutbox publishes up to limit due outbox messages and marks each one
+// sent once the broker confirms it. Failed messages are retried later with
+// exponential backoff. It returns how many messages were published.
+//
+// A message confirmed by the broker but not marked sent, e.g. on a crash in
+// between, is published again; consumers skip it by its message ID.
+func (i *impl) PublishOutbox(ctx context.Context, limit int) (int, error) {
+	msgs, err := i.mediaRepo.ListPendingOutbox(ctx, int64(limit))
+	if err != nil {
+		return 0, fmt.Errorf("list outbox: %w", err)
+	}
+
+	sent := 0
+	for _, msg := range msgs {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		if err := i.rabbitClient.Publish(msg.Queue, msg.Payload); err != nil {
+			backoff := outboxMaxBackoff
+			if msg.Attempts < 8 {
+				backoff = min(time.Second<<msg.Attempts, outboxMaxBackoff)
+			}
+			log.Printf("publish outbox message %s failed (attempt %d): %v", msg.ID.Hex(), msg.Attempts+1, err)
+			if err := i.mediaRepo.MarkOutboxFailed(ctx, msg.ID, err.Error(), time.Now().Add(backoff)); err != nil {
+				return sent, fmt.Errorf("mark outbox failed: %w", err)
+			}
+			continue
+		}
+
+		if err := i.mediaRepo.MarkOutboxSent(ctx, msg.ID); err != nil {
+			return sent, fmt.Errorf("mark outbox sent: %w", err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// transcodeMessage returns the outbox message dispatching job, with a new
+// message ID consumers dedupe redeliveries by.
+func (i *impl) transcodeMessage(job types.TranscodeJob) (*models.OutboxMessage, error) {
+	id := primitive.NewObjectID()
+	job.MessageID = id.Hex()
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OutboxMessage{
+		ID:      id,
+		Queue:   i.cfg.RabbitMQ.Queue,
+		Payload: data,
+	}, nil
+}
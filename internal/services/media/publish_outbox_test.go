@@ -0,0 +1,57 @@
+package media_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+)
+
+func TestPublishOutbox(t *testing.T) {
+	env := newTestEnv(t)
+	res, err := env.svc.UploadVideo(context.Background(), media.UploadVideoInput{
+		File: fileHeader(t, "clip.mp4", []byte("video bytes")),
+	})
+	if err != nil {
+		t.Fatalf("UploadVideo: %v", err)
+	}
+
+	// A failed publish stays pending and is retried after a backoff
+	env.publisher.FailWith(errors.New("broker down"))
+	sent, err := env.svc.PublishOutbox(context.Background(), 10)
+	if err != nil || sent != 0 {
+		t.Fatalf("PublishOutbox = %d, %v; want 0, nil", sent, err)
+	}
+	msg := env.repo.Outbox()[0]
+	if msg.Status != string(models.OutboxStatusPending) || msg.Attempts != 1 ||
+		msg.LastError != "broker down" || !msg.NextAttemptAt.After(time.Now()) {
+		t.Errorf("outbox after failure = %+v", msg)
+	}
+
+	// Not due yet
+	env.publisher.FailWith(nil)
+	if sent, _ := env.svc.PublishOutbox(context.Background(), 10); sent != 0 {
+		t.Errorf("published %d messages before the backoff elapsed", sent)
+	}
+
+	env.repo.MarkOutboxFailed(context.Background(), msg.ID, msg.LastError, time.Now().Add(-time.Second))
+	sent, err = env.svc.PublishOutbox(context.Background(), 10)
+	if err != nil || sent != 1 {
+		t.Fatalf("PublishOutbox = %d, %v; want 1, nil", sent, err)
+	}
+	if msg := env.repo.Outbox()[0]; msg.Status != string(models.OutboxStatusSent) || msg.SentAt == nil {
+		t.Errorf("outbox after publish = %+v", msg)
+	}
+	messages := env.publisher.Messages(testQueue)
+	if len(messages) != 1 || string(messages[0]) != string(msg.Payload) {
+		t.Errorf("published %q for media %s", messages, res.ID.Hex())
+	}
+
+	// Sent messages are not published again
+	if sent, _ := env.svc.PublishOutbox(context.Background(), 10); sent != 0 {
+		t.Errorf("republished %d sent messages", sent)
+	}
+}
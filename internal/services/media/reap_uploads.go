@@ -0,0 +1,44 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// abandonedUploadGrace is how long after its URLs expired a direct upload
+// may still be completed before it is removed.
+const abandonedUploadGrace = time.Hour
+
+// ReapExpiredUploads removes media whose direct upload was never completed,
+// together with the parts or file uploaded so far. It returns how many
+// media were removed.
+func (i *impl) ReapExpiredUploads(ctx context.Context) (int, error) {
+	before := time.Now().UTC().Add(-abandonedUploadGrace)
+	medias, err := i.mediaRepo.ListExpiredMediaUploads(ctx, before, reapBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list expired uploads: %w", err)
+	}
+
+	reaped := 0
+	for _, media := range medias {
+		ok, err := i.mediaRepo.DeleteExpiredMediaUpload(ctx, media.ID, before)
+		if err != nil {
+			return reaped, fmt.Errorf("delete expired upload: %w", err)
+		}
+		if !ok {
+			// Completed in the meantime
+			continue
+		}
+
+		// The document is gone, so leftover files are only logged
+		i.abortUpload(media)
+		if err := i.mediaStorage.RemoveObject(ctx, media.Path); err != nil {
+			log.Printf("remove source of abandoned upload %s: %v", media.ID.Hex(), err)
+		}
+		log.Printf("upload of media %s abandoned, removed", media.ID.Hex())
+		reaped++
+	}
+	return reaped, nil
+}
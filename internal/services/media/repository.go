@@ -0,0 +1,68 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/adapters/mongodb/media"
+	"media-svc/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MediaRepository is the persistence the media service depends on,
+// implemented by the MongoDB repository and the in-memory fake. Lookups by
+// a malformed id find nothing rather than fail.
+type MediaRepository interface {
+	CreateMedia(ctx context.Context, media *models.Media) error
+	CreateMediaWithOutbox(ctx context.Context, media *models.Media, msg *models.OutboxMessage) error
+	GetMedia(ctx context.Context, id string) (*models.Media, error)
+	GetMediaByStreamPath(ctx context.Context, filePath string) (*models.Media, error)
+	UpdateMedia(ctx context.Context, media *models.Media) error
+	UpdateMediaDetails(ctx context.Context, input media.UpdateMediaDetailsInput) (*models.Media, error)
+	DeleteMedia(ctx context.Context, id primitive.ObjectID) error
+	SetMediaProbe(ctx context.Context, media *models.Media) error
+	ListMedia(ctx context.Context, input media.ListMediaInput) ([]*models.Media, error)
+	CompleteMediaUpload(ctx context.Context, media *models.Media, msg *models.OutboxMessage) (bool, error)
+	ListExpiredMediaUploads(ctx context.Context, before time.Time, limit int64) ([]*models.Media, error)
+	DeleteExpiredMediaUpload(ctx context.Context, id primitive.ObjectID, before time.Time) (bool, error)
+
+	CreateTranscodeJob(ctx context.Context, transcode *models.TranscodeJob) error
+	GetTranscodeJob(ctx context.Context, id string) (*models.TranscodeJob, error)
+	ListTranscodeJobsByMediaID(ctx context.Context, mediaId string) ([]*models.TranscodeJob, error)
+	GetTranscodeJobByMediaID(ctx context.Context, mediaId string) (*models.TranscodeJob, error)
+	GetTranscodeJobByMessageID(ctx context.Context, mediaId, messageId string) (*models.TranscodeJob, error)
+	UpdateTranscodeJob(ctx context.Context, transcode *models.TranscodeJob) error
+	UpdateTranscodeJobProgress(ctx context.Context, input media.UpdateTranscodeJobProgressInput) error
+
+	ClaimTranscodeJob(ctx context.Context, jobID, workerID string, leaseUntil time.Time) (*models.TranscodeJob, error)
+	RenewTranscodeJobLease(ctx context.Context, jobID, workerID string, leaseUntil time.Time) (*models.TranscodeJob, error)
+	ListExpiredTranscodeJobs(ctx context.Context, now time.Time, limit int64) ([]*models.TranscodeJob, error)
+	RequeueExpiredTranscodeJob(ctx context.Context, jobID primitive.ObjectID, now time.Time, msg *models.OutboxMessage) (bool, error)
+	FailExpiredTranscodeJob(ctx context.Context, jobID primitive.ObjectID, now time.Time, errMsg string) (bool, error)
+	ListStalePendingTranscodeJobs(ctx context.Context, before time.Time, limit int64) ([]*models.TranscodeJob, error)
+	RequeueStalePendingTranscodeJob(ctx context.Context, jobID primitive.ObjectID, before time.Time, msg *models.OutboxMessage) (bool, error)
+
+	CreateTranscodeJobWithOutbox(ctx context.Context, job *models.TranscodeJob, msg *models.OutboxMessage) error
+	RequestTranscodeJobCancel(ctx context.Context, job *models.TranscodeJob) (*models.TranscodeJob, error)
+	CancelPendingTranscodeJob(ctx context.Context, job *models.TranscodeJob) (*models.TranscodeJob, error)
+
+	CreateTusUpload(ctx context.Context, upload *models.TusUpload) error
+	GetTusUpload(ctx context.Context, id string) (*models.TusUpload, error)
+	LockTusUpload(ctx context.Context, id primitive.ObjectID, offset int64, lockID string, until time.Time) (bool, error)
+	UnlockTusUpload(ctx context.Context, id primitive.ObjectID, lockID string) error
+	UpdateTusUploadOffset(ctx context.Context, upload *models.TusUpload, prevOffset int64) (bool, error)
+	SetTusUploadMedia(ctx context.Context, id primitive.ObjectID, mediaID string) error
+	DeleteTusUpload(ctx context.Context, id primitive.ObjectID) error
+
+	CreateMediaKey(ctx context.Context, key *models.MediaKey) error
+	GetMediaKeyByMediaID(ctx context.Context, mediaId string) (*models.MediaKey, error)
+
+	ListPendingOutbox(ctx context.Context, limit int64) ([]*models.OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, id primitive.ObjectID) error
+	MarkOutboxFailed(ctx context.Context, id primitive.ObjectID, errMsg string, nextAttemptAt time.Time) error
+}
+
+// Publisher publishes messages to a broker queue.
+type Publisher interface {
+	Publish(queue string, data []byte) error
+}
@@ -0,0 +1,80 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrJobInProgress is returned when a media already has a pending or
+	// processing transcode job.
+	ErrJobInProgress = errors.New("transcode job already in progress")
+	// ErrUploadPending is returned when the source of a media has not been
+	// uploaded yet.
+	ErrUploadPending = errors.New("media upload is still pending")
+)
+
+type RetranscodeVideoInput struct {
+	MediaID string
+	Ladder  string                  // Optional rendition ladder name, empty for the default
+	Options *types.TranscodeOptions // Optional codec settings overriding the ladder
+}
+
+// RetranscodeVideo starts a new transcode attempt of an existing media, e.g.
+// with another ladder or codec. The job is recorded as pending and
+// dispatched through the outbox.
+func (i *impl) RetranscodeVideo(ctx context.Context, input RetranscodeVideoInput) (*models.TranscodeJob, error) {
+	ladder, _, err := i.resolveLadder(input.Ladder)
+	if err != nil {
+		return nil, err
+	}
+	options, err := normalizeOptions(input.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := i.ownedMedia(ctx, input.MediaID)
+	if err != nil {
+		return nil, err
+	}
+	if media.Upload != nil {
+		return nil, ErrUploadPending
+	}
+
+	job := &models.TranscodeJob{
+		ID:      primitive.NewObjectID(),
+		MediaID: media.ID,
+		Ladder:  ladder,
+		Options: jobOptions(options),
+		Status:  types.TranscodeJobStatusPending.String(),
+	}
+	msg, err := i.transcodeMessage(types.TranscodeJob{
+		MediaID: input.MediaID,
+		Ladder:  ladder,
+		JobID:   job.ID.Hex(),
+		Options: options,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only one attempt of a media runs at a time
+	inProgress := func(prev *models.TranscodeJob) error {
+		if prev != nil && (prev.Status == types.TranscodeJobStatusPending.String() || prev.Status == types.TranscodeJobStatusProcessing.String()) {
+			return ErrJobInProgress
+		}
+		return nil
+	}
+	create := func(ctx context.Context, job *models.TranscodeJob) error {
+		return i.mediaRepo.CreateTranscodeJobWithOutbox(ctx, job, msg)
+	}
+	if err := i.createAttempt(ctx, job, inProgress, create); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
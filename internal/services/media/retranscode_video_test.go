@@ -0,0 +1,139 @@
+package media_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"media-svc/internal/adapters/inmemory"
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+	"media-svc/pkgs/scratch"
+)
+
+func TestRetranscodeVideo(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+	env.createJob(t, source, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusDone.String()})
+
+	job, err := env.svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{MediaID: source.ID.Hex()})
+	if err != nil {
+		t.Fatalf("RetranscodeVideo: %v", err)
+	}
+	if job.Attempt != 2 || job.Status != types.TranscodeJobStatusPending.String() || job.Ladder != "default" {
+		t.Errorf("job = %+v", job)
+	}
+
+	outbox := env.repo.Outbox()
+	if len(outbox) != 1 {
+		t.Fatalf("outbox = %+v", outbox)
+	}
+	var msg types.TranscodeJob
+	if err := json.Unmarshal(outbox[0].Payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.JobID != job.ID.Hex() || msg.MediaID != source.ID.Hex() || msg.MessageID != outbox[0].ID.Hex() {
+		t.Errorf("dispatch message = %+v", msg)
+	}
+
+	// The new attempt is still pending
+	_, err = env.svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{MediaID: source.ID.Hex()})
+	if !errors.Is(err, media.ErrJobInProgress) {
+		t.Errorf("second RetranscodeVideo error = %v, want %v", err, media.ErrJobInProgress)
+	}
+}
+
+func TestRetranscodeVideoErrors(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+
+	_, err := env.svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{MediaID: "0123456789abcdef01234567"})
+	if !errors.Is(err, media.ErrMediaNotFound) {
+		t.Errorf("missing media error = %v, want %v", err, media.ErrMediaNotFound)
+	}
+	_, err = env.svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{MediaID: source.ID.Hex(), Ladder: "nope"})
+	if !errors.Is(err, media.ErrLadderNotFound) {
+		t.Errorf("unknown ladder error = %v, want %v", err, media.ErrLadderNotFound)
+	}
+	_, err = env.svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{MediaID: source.ID.Hex(), Options: &types.TranscodeOptions{VideoCodec: "mpeg2"}})
+	if !errors.Is(err, media.ErrInvalidOptions) {
+		t.Errorf("unsupported codec error = %v, want %v", err, media.ErrInvalidOptions)
+	}
+
+	// The source of a pending upload is not in storage yet
+	source.Upload = &models.MediaUpload{}
+	if err := env.repo.UpdateMedia(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{MediaID: source.ID.Hex()})
+	if !errors.Is(err, media.ErrUploadPending) {
+		t.Errorf("pending upload error = %v, want %v", err, media.ErrUploadPending)
+	}
+	if n := len(env.repo.Outbox()); n != 0 {
+		t.Errorf("outbox = %d messages, want none", n)
+	}
+}
+
+func TestRetranscodeVideoWithOptions(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+
+	job, err := env.svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{
+		MediaID: source.ID.Hex(),
+		Options: &types.TranscodeOptions{VideoCodec: "AV1", Preset: "6"},
+	})
+	if err != nil {
+		t.Fatalf("RetranscodeVideo: %v", err)
+	}
+	if job.Options == nil || *job.Options != (models.TranscodeOptions{VideoCodec: "av1", Preset: "6"}) {
+		t.Errorf("job options = %+v", job.Options)
+	}
+
+	var msg types.TranscodeJob
+	if err := json.Unmarshal(env.repo.Outbox()[0].Payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Options == nil || *msg.Options != (types.TranscodeOptions{VideoCodec: "av1", Preset: "6"}) {
+		t.Errorf("dispatch options = %+v", msg.Options)
+	}
+}
+
+// staleRepository misses the latest attempt on its first read, as when
+// another job of the media is created concurrently.
+type staleRepository struct {
+	*inmemory.MediaRepository
+	reads int
+}
+
+func (r *staleRepository) GetTranscodeJobByMediaID(ctx context.Context, mediaId string) (*models.TranscodeJob, error) {
+	r.reads++
+	if r.reads == 1 {
+		return nil, nil
+	}
+	return r.MediaRepository.GetTranscodeJobByMediaID(ctx, mediaId)
+}
+
+func TestRetranscodeVideoRenumbersTakenAttempt(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+	env.createJob(t, source, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusDone.String()})
+
+	repo := &staleRepository{MediaRepository: env.repo}
+	svc := media.NewService(env.cfg, repo, env.storage, env.stream, env.publisher, scratch.New(env.scratchDir))
+
+	job, err := svc.RetranscodeVideo(context.Background(), media.RetranscodeVideoInput{MediaID: source.ID.Hex()})
+	if err != nil {
+		t.Fatalf("RetranscodeVideo: %v", err)
+	}
+	if job.Attempt != 2 || repo.reads != 2 {
+		t.Errorf("attempt = %d after %d reads, want 2 after 2", job.Attempt, repo.reads)
+	}
+	if jobs := env.repo.TranscodeJobs(); len(jobs) != 2 {
+		t.Errorf("jobs = %+v", jobs)
+	}
+	if n := len(env.repo.Outbox()); n != 1 {
+		t.Errorf("outbox = %d messages, want 1", n)
+	}
+}
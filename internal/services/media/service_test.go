@@ -0,0 +1,125 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"media-svc/config"
+	"media-svc/internal/adapters/inmemory"
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/pkgs/scratch"
+)
+
+const testQueue = "transcode"
+
+// testEnv is a media service wired to in-memory adapters.
+type testEnv struct {
+	svc        media.MediaService
+	cfg        *config.Config
+	repo       *inmemory.MediaRepository
+	publisher  *inmemory.Publisher
+	storage    *inmemory.Storage
+	stream     *inmemory.Storage
+	scratchDir string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.RabbitMQ.Queue = testQueue
+
+	env := &testEnv{
+		cfg:        cfg,
+		repo:       inmemory.NewMediaRepository(),
+		publisher:  inmemory.NewPublisher(),
+		storage:    inmemory.NewStorage("media"),
+		stream:     inmemory.NewStorage("stream"),
+		scratchDir: t.TempDir(),
+	}
+	env.svc = media.NewService(cfg, env.repo, env.storage, env.stream, env.publisher, scratch.New(env.scratchDir))
+	return env
+}
+
+// useFakeFFmpeg puts the fake ffmpeg and ffprobe of testdata/bin first on
+// PATH for the duration of the test.
+func useFakeFFmpeg(t *testing.T) {
+	t.Helper()
+
+	bin, err := filepath.Abs(filepath.Join("testdata", "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// createSource stores a source object and its media document.
+func (e *testEnv) createSource(t *testing.T, name string) *models.Media {
+	t.Helper()
+
+	path := "videos/" + name
+	if _, err := e.storage.PutObject(context.Background(), path, bytes.NewReader([]byte("source")), 6); err != nil {
+		t.Fatal(err)
+	}
+	m := &models.Media{Name: name, Path: path, Size: 6, ContentType: "video/mp4"}
+	if err := e.repo.CreateMedia(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// createJob stores a transcode job for a media.
+func (e *testEnv) createJob(t *testing.T, m *models.Media, job models.TranscodeJob) *models.TranscodeJob {
+	t.Helper()
+
+	job.MediaID = m.ID
+	if err := e.repo.CreateTranscodeJob(context.Background(), &job); err != nil {
+		t.Fatal(err)
+	}
+	return &job
+}
+
+// scratchEntries lists what is left in the scratch root.
+func (e *testEnv) scratchEntries(t *testing.T) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(e.scratchDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// fileHeader builds the multipart file header of an upload request.
+func fileHeader(t *testing.T, name string, content []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	w.Close()
+
+	req, err := http.NewRequest(http.MethodPost, "/", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return req.MultipartForm.File["file"][0]
+}
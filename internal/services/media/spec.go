@@ -2,19 +2,39 @@ package media
 
 import (
 	"context"
+	"io"
+	"media-svc/internal/adapters/minio"
 	"media-svc/internal/models"
 )
 
 type MediaService interface {
 	CreateMedia(ctx context.Context, input CreateMediaInput) (string, error)
-	UpdateMedia(ctx context.Context, input UpdateMediaInput) error
+	UpdateMedia(ctx context.Context, input UpdateMediaInput) (*models.Media, error)
+	DeleteMedia(ctx context.Context, id string) error
 	GetMedia(ctx context.Context, id string) (*models.Media, error)
 	ListMedia(ctx context.Context, input ListMediaInput) ([]*models.Media, error)
 
-	PresignGetStreamObject(ctx context.Context, input PresignGetObjectInput) (string, error)
+	OpenStreamObject(ctx context.Context, filePath string) (io.ReadSeekCloser, minio.ObjectInfo, error)
 	UploadVideo(ctx context.Context, input UploadVideoInput) (*models.Media, error)
+	PublishOutbox(ctx context.Context, limit int) (int, error)
 	TranscodeVideo(ctx context.Context, input TranscodeVideoInput) (TranscodeVideoOutput, error)
 	UpdateTranscodeJobError(ctx context.Context, input UpdateTranscodeJobErrorInput) error
 	UpdateTranscodeJobSuccess(ctx context.Context, input UpdateTranscodeJobSuccessInput) error
 	GetVideoStatus(ctx context.Context, videoId string) (GetVideoStatusResponse, error)
+	ListTranscodeJobs(ctx context.Context, mediaID string) ([]*models.TranscodeJob, error)
+	ReapExpiredJobs(ctx context.Context) (int, error)
+	ReapStalePendingJobs(ctx context.Context) (int, error)
+	RetranscodeVideo(ctx context.Context, input RetranscodeVideoInput) (*models.TranscodeJob, error)
+	CancelTranscode(ctx context.Context, mediaID string) (*models.TranscodeJob, error)
+	CreateUpload(ctx context.Context, input CreateUploadInput) (*CreateUploadOutput, error)
+	CompleteUpload(ctx context.Context, mediaID string) (*models.Media, error)
+	ReapExpiredUploads(ctx context.Context) (int, error)
+	CreateTusUpload(ctx context.Context, input CreateTusUploadInput) (*models.TusUpload, error)
+	GetTusUpload(ctx context.Context, id string) (*models.TusUpload, error)
+	WriteTusUpload(ctx context.Context, input WriteTusUploadInput) (*models.TusUpload, error)
+	DeleteTusUpload(ctx context.Context, id string) error
+	GetMediaKey(ctx context.Context, mediaID string) ([]byte, error)
+	IssuePlaybackToken(ctx context.Context, input IssuePlaybackTokenInput) (*IssuePlaybackTokenOutput, error)
+	VerifyPlaybackToken(ctx context.Context, input VerifyPlaybackTokenInput) (*PlaybackClaims, error)
+	RewritePlaylist(ctx context.Context, input RewritePlaylistInput) ([]byte, error)
 }
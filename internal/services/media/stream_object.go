@@ -0,0 +1,34 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"media-svc/internal/adapters/minio"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrStreamObjectNotFound is returned for a file that is not in the stream
+// bucket.
+var ErrStreamObjectNotFound = errors.New("stream object not found")
+
+// OpenStreamObject opens a transcoded file, such as a playlist or segment,
+// for serving. The caller must close the returned reader.
+func (i *impl) OpenStreamObject(ctx context.Context, filePath string) (io.ReadSeekCloser, minio.ObjectInfo, error) {
+	filePath = path.Clean("/" + filePath)[1:]
+	if filePath == "" || strings.HasSuffix(filePath, "/") {
+		return nil, minio.ObjectInfo{}, ErrStreamObjectNotFound
+	}
+
+	reader, info, err := i.streamStorage.GetObjectReader(ctx, filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, minio.ObjectInfo{}, ErrStreamObjectNotFound
+	}
+	if err != nil {
+		return nil, minio.ObjectInfo{}, fmt.Errorf("open stream object: %w", err)
+	}
+	return reader, info, nil
+}
@@ -0,0 +1,47 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"media-svc/internal/services/media"
+)
+
+func TestOpenStreamObject(t *testing.T) {
+	env := newTestEnv(t)
+	data := []byte("#EXTM3U\n")
+	if _, err := env.stream.PutObject(context.Background(), "clip.mp4/master.m3u8", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, info, err := env.svc.OpenStreamObject(context.Background(), "clip.mp4/../clip.mp4/master.m3u8")
+	if err != nil {
+		t.Fatalf("OpenStreamObject: %v", err)
+	}
+	defer reader.Close()
+	if info.Size != int64(len(data)) || info.ETag == "" {
+		t.Errorf("info = %+v", info)
+	}
+
+	// Ranges are served by seeking
+	if _, err := reader.Seek(1, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "EXTM3U\n" {
+		t.Errorf("read after seek = %q", rest)
+	}
+}
+
+func TestOpenStreamObjectNotFound(t *testing.T) {
+	env := newTestEnv(t)
+
+	for _, filePath := range []string{"clip.mp4/missing.ts", "", "clip.mp4/"} {
+		if _, _, err := env.svc.OpenStreamObject(context.Background(), filePath); !errors.Is(err, media.ErrStreamObjectNotFound) {
+			t.Errorf("OpenStreamObject(%q) error = %v, want ErrStreamObjectNotFound", filePath, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/types"
+	"media-svc/pkgs/transcoder"
+	"path"
+)
+
+// thumbnailDir is the subdirectory of the transcode output the images are
+// written to, so they are uploaded next to the HLS output.
+const thumbnailDir = "thumbnails"
+
+// generateThumbnails renders the configured poster and thumbnails of a
+// source into outputDir. The returned images carry their object keys under
+// targetDir. It returns nothing when thumbnails are disabled.
+func (i *impl) generateThumbnails(
+	ctx context.Context,
+	t *transcoder.Transcoder,
+	inputPath, outputDir, targetDir string,
+) (poster []types.Image, thumbnails []types.Image, err error) {
+	cfg := i.cfg.Transcode.Thumbnails
+	if !cfg.Enabled {
+		return nil, nil, nil
+	}
+
+	sizes := make([]transcoder.ImageSize, 0, len(cfg.Sizes))
+	for _, s := range cfg.Sizes {
+		sizes = append(sizes, transcoder.ImageSize{Width: s.Width, Height: s.Height})
+	}
+
+	images, err := t.GenerateThumbnails(ctx, inputPath, outputDir, transcoder.ThumbnailOptions{
+		PosterAt:   cfg.PosterAt,
+		PosterAuto: cfg.PosterAuto,
+		Count:      cfg.Count,
+		Sizes:      sizes,
+		Formats:    cfg.Formats,
+		Quality:    cfg.Quality,
+		Dir:        thumbnailDir,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, img := range images {
+		image := types.Image{
+			Key:    path.Join(targetDir, img.Path),
+			Width:  img.Width,
+			Height: img.Height,
+			Format: img.Format,
+			Time:   img.Time.Seconds(),
+		}
+		if img.Kind == transcoder.ImageKindPoster {
+			poster = append(poster, image)
+		} else {
+			thumbnails = append(thumbnails, image)
+		}
+	}
+
+	return poster, thumbnails, nil
+}
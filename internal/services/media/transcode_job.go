@@ -0,0 +1,23 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/models"
+)
+
+// ListTranscodeJobs returns every transcode attempt of a media, oldest first.
+func (i *impl) ListTranscodeJobs(ctx context.Context, mediaID string) ([]*models.TranscodeJob, error) {
+	if _, err := i.ownedMedia(ctx, mediaID); err != nil {
+		return nil, err
+	}
+	return i.mediaRepo.ListTranscodeJobsByMediaID(ctx, mediaID)
+}
+
+// transcodeJob returns the job with jobID, or the latest job of the media
+// when jobID is empty.
+func (i *impl) transcodeJob(ctx context.Context, jobID, mediaID string) (*models.TranscodeJob, error) {
+	if jobID != "" {
+		return i.mediaRepo.GetTranscodeJob(ctx, jobID)
+	}
+	return i.mediaRepo.GetTranscodeJobByMediaID(ctx, mediaID)
+}
@@ -0,0 +1,143 @@
+package media
+
+import (
+	"context"
+	"log"
+	"media-svc/internal/adapters/mongodb/media"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"media-svc/pkgs/transcoder"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultProgressInterval = 2 * time.Second
+
+// progressTracker persists the stage and progress of a transcode job. Encode
+// progress is written at most once per interval so a fast ffmpeg does not
+// flood MongoDB; stage changes are written immediately, together with the
+// timing of each stage.
+type progressTracker struct {
+	repo     MediaRepository
+	jobID    primitive.ObjectID
+	interval time.Duration
+
+	mu      sync.Mutex
+	stage   types.TranscodeStage
+	latest  transcoder.Progress
+	dirty   bool
+	timings []models.StageTiming
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (i *impl) newProgressTracker(job *models.TranscodeJob) *progressTracker {
+	interval := i.cfg.Transcode.ProgressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	return &progressTracker{
+		repo:     i.mediaRepo,
+		jobID:    job.ID,
+		interval: interval,
+		stage:    types.TranscodeStage(job.Stage),
+		timings:  append([]models.StageTiming(nil), job.StageTimings...),
+	}
+}
+
+// SetStage records that the job moved to a new stage and resets its progress.
+func (t *progressTracker) SetStage(ctx context.Context, stage types.TranscodeStage) {
+	now := time.Now().UTC()
+
+	t.mu.Lock()
+	t.stage = stage
+	t.latest = transcoder.Progress{}
+	t.dirty = false
+	if n := len(t.timings); n > 0 && t.timings[n-1].FinishedAt == nil {
+		t.timings[n-1].FinishedAt = &now
+	}
+	t.timings = append(t.timings, models.StageTiming{Stage: stage.String(), StartedAt: now})
+	timings := append([]models.StageTiming(nil), t.timings...)
+	t.mu.Unlock()
+
+	t.writeStage(ctx, stage, timings)
+}
+
+// Report receives encode progress from the transcoder.
+func (t *progressTracker) Report(p transcoder.Progress) {
+	t.mu.Lock()
+	t.latest = p
+	t.dirty = true
+	t.mu.Unlock()
+}
+
+// Start begins flushing reported progress in the background until Stop.
+func (t *progressTracker) Start(ctx context.Context) {
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.flush(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends the background flushing started by Start.
+func (t *progressTracker) Stop() {
+	if t.stop == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+	t.stop = nil
+}
+
+func (t *progressTracker) flush(ctx context.Context) {
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return
+	}
+	stage, latest := t.stage, t.latest
+	t.dirty = false
+	t.mu.Unlock()
+
+	t.write(ctx, stage, latest)
+}
+
+func (t *progressTracker) write(ctx context.Context, stage types.TranscodeStage, p transcoder.Progress) {
+	t.update(ctx, media.UpdateTranscodeJobProgressInput{
+		JobID:      t.jobID,
+		Stage:      stage.String(),
+		Progress:   p.Percent,
+		ETASeconds: int64(p.ETA.Seconds()),
+	})
+}
+
+func (t *progressTracker) writeStage(ctx context.Context, stage types.TranscodeStage, timings []models.StageTiming) {
+	t.update(ctx, media.UpdateTranscodeJobProgressInput{
+		JobID:        t.jobID,
+		Stage:        stage.String(),
+		StageTimings: timings,
+	})
+}
+
+func (t *progressTracker) update(ctx context.Context, input media.UpdateTranscodeJobProgressInput) {
+	if err := t.repo.UpdateTranscodeJobProgress(ctx, input); err != nil {
+		log.Printf("update transcode progress for job %s: %v", t.jobID.Hex(), err)
+	}
+}
@@ -2,29 +2,72 @@ package media
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"media-svc/internal/models"
 	"media-svc/internal/types"
-	"media-svc/internal/utils"
 	"media-svc/pkgs/transcoder"
+	"path"
 	"path/filepath"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// scratchReserveFactor is the scratch space reserved per job as a multiple
+// of the source size.
+const scratchReserveFactor = 3
+
+// ErrDuplicateJob is returned for a redelivered job message whose earlier
+// delivery is still running or has finished.
+var ErrDuplicateJob = errors.New("transcode job already dispatched")
+
+// ErrMediaNotFound is returned when the media of a job does not exist.
+var ErrMediaNotFound = errors.New("media not found")
+
 type TranscodeVideoInput struct {
-	MediaID string
+	MediaID   string
+	Ladder    string
+	Options   *types.TranscodeOptions // Codec settings overriding the ladder
+	MessageID string                  // Broker message ID, used to skip redelivered jobs
+	JobID     string                  // ID for the job record of this attempt, generated when empty
+	WorkerID  string                  // Worker running the attempt, recorded on the job
 }
 
 type TranscodeVideoOutput struct {
-	Path       string
-	Renditions []types.Rendition
+	JobID            string // Job record of this attempt, also set on failure
+	Path             string
+	Ladder           string
+	EncryptionMethod string // HLS encryption method, empty when unencrypted
+	Renditions       []types.Rendition
+	Poster           []types.Image
+	Thumbnails       []types.Image
+	Trickplay        *types.Trickplay
 }
 
 // TranscodeVideo downloads a video file, transcodes it into adaptive streams,
 // uploads the transcoded files back to storage, and returns the master playlist path.
-func (i *impl) TranscodeVideo(ctx context.Context, input TranscodeVideoInput) (TranscodeVideoOutput, error) {
+//
+// The worker holds a lease on the job while it runs, renewed by a heartbeat,
+// so the job can be recovered by ReapExpiredJobs if the worker dies.
+func (i *impl) TranscodeVideo(ctx context.Context, input TranscodeVideoInput) (out TranscodeVideoOutput, err error) {
+	// A redelivery may take up an earlier job instead of input.JobID
+	defer func() {
+		if out.JobID == "" {
+			out.JobID = input.JobID
+		}
+	}()
+
+	ladder, ladderRenditions, err := i.resolveLadder(input.Ladder)
+	if err != nil {
+		return TranscodeVideoOutput{}, err
+	}
+	options, err := normalizeOptions(input.Options)
+	if err != nil {
+		return TranscodeVideoOutput{}, err
+	}
+	ladderRenditions = applyOptions(ladderRenditions, options)
 
 	media, err := i.mediaRepo.GetMedia(ctx, input.MediaID)
 	if err != nil {
@@ -32,50 +75,177 @@ func (i *impl) TranscodeVideo(ctx context.Context, input TranscodeVideoInput) (T
 	}
 
 	if media == nil {
-		return TranscodeVideoOutput{}, fmt.Errorf("media not found")
+		return TranscodeVideoOutput{}, ErrMediaNotFound
 	}
 
 	filePath := media.Path
 
-	// Download video file from storage
-	src, err := i.mediaStorage.GetObject(ctx, filePath)
+	// A message can be delivered more than once; only a failed earlier
+	// attempt is run again, and a job interrupted by a worker shutdown is
+	// taken up again
+	if input.MessageID != "" {
+		prev, err := i.mediaRepo.GetTranscodeJobByMessageID(ctx, input.MediaID, input.MessageID)
+		if err != nil {
+			return TranscodeVideoOutput{}, fmt.Errorf("get transcode job: %w", err)
+		}
+		if prev != nil {
+			switch prev.Status {
+			case types.TranscodeJobStatusProcessing.String(), types.TranscodeJobStatusDone.String():
+				return TranscodeVideoOutput{}, ErrDuplicateJob
+			case types.TranscodeJobStatusPending.String():
+				input.JobID = prev.ID.Hex()
+			}
+		}
+	}
+
+	// Take over a job requeued after its worker was lost
+	now := time.Now().UTC()
+	leaseUntil := now.Add(i.leaseDuration())
+	var job *models.TranscodeJob
+	if input.JobID != "" {
+		existing, err := i.mediaRepo.GetTranscodeJob(ctx, input.JobID)
+		if err != nil {
+			return TranscodeVideoOutput{}, fmt.Errorf("get transcode job: %w", err)
+		}
+		if existing != nil {
+			job, err = i.mediaRepo.ClaimTranscodeJob(ctx, input.JobID, input.WorkerID, leaseUntil)
+			if err != nil {
+				return TranscodeVideoOutput{}, fmt.Errorf("claim transcode job: %w", err)
+			}
+			if job == nil {
+				return TranscodeVideoOutput{}, ErrDuplicateJob
+			}
+		}
+	}
+
+	// Record this attempt as a new job after the previous ones
+	claimed := job != nil
+	if !claimed {
+		mediaObjectId, _ := primitive.ObjectIDFromHex(input.MediaID)
+		jobObjectId, _ := primitive.ObjectIDFromHex(input.JobID)
+		job = &models.TranscodeJob{
+			ID:           jobObjectId,
+			MediaID:      mediaObjectId,
+			MessageID:    input.MessageID,
+			WorkerID:     input.WorkerID,
+			Ladder:       ladder,
+			Options:      jobOptions(options),
+			Status:       types.TranscodeJobStatusProcessing.String(),
+			Stage:        types.TranscodeStageDownloading.String(),
+			StageTimings: []models.StageTiming{{Stage: types.TranscodeStageDownloading.String(), StartedAt: now}},
+			LeaseUntil:   &leaseUntil,
+			StartedAt:    &now,
+		}
+		if err := i.createAttempt(ctx, job, nil, i.mediaRepo.CreateTranscodeJob); err != nil {
+			return TranscodeVideoOutput{}, err
+		}
+	}
+	input.JobID = job.ID.Hex()
+
+	// Renew the lease while working; losing it stops the job, whose outcome
+	// then belongs to the worker that took it over. A cancel request seen
+	// by the heartbeat stops the job too.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stopLease := i.keepLease(ctx, cancel, job)
+	defer stopLease()
+	defer func() {
+		cause := context.Cause(ctx)
+		if err != nil && (errors.Is(cause, ErrLeaseLost) || errors.Is(cause, ErrJobCancelled)) {
+			err = fmt.Errorf("%w: %v", cause, err)
+		}
+	}()
+
+	progress := i.newProgressTracker(job)
+	if claimed {
+		progress.SetStage(ctx, types.TranscodeStageDownloading)
+	}
+
+	// Work in a directory of this attempt only, removed on every exit path.
+	// The reservation covers the source and an output of similar size.
+	scratchDir, err := i.scratch.Create("job-"+job.ID.Hex(), media.Size*scratchReserveFactor)
 	if err != nil {
-		return TranscodeVideoOutput{}, fmt.Errorf("get object from storage: %w", err)
+		return TranscodeVideoOutput{}, fmt.Errorf("create scratch dir: %w", err)
 	}
+	defer scratchDir.Close()
 
 	filename := filepath.Base(filePath)
+	localFilePath := scratchDir.Join(filename)
 
-	// Save downloaded file locally
-	if err := utils.WriteFile("assets", filename, src); err != nil {
-		return TranscodeVideoOutput{}, fmt.Errorf("write file local: %w", err)
+	// Stream the source to disk, resuming and verifying the transfer
+	if _, err := i.mediaStorage.DownloadToFile(ctx, filePath, localFilePath); err != nil {
+		return TranscodeVideoOutput{}, fmt.Errorf("download source: %w", err)
 	}
-
-	// create transcode job db
-	mediaObjectId, _ := primitive.ObjectIDFromHex(input.MediaID)
-	now := time.Now().UTC()
-	job := &models.TranscodeJob{
-		MediaID:   mediaObjectId,
-		Status:    types.TranscodeJobStatusProcessing.String(),
-		StartedAt: &now,
+	if err := scratchDir.CheckQuota(); err != nil {
+		return TranscodeVideoOutput{}, err
 	}
-	err = i.mediaRepo.CreateTranscodeJob(ctx, job)
+
+	outputDir := scratchDir.Join("output")
+
+	// Extract the source metadata and store it on the media
+	progress.SetStage(ctx, types.TranscodeStageProbing)
+	probe, err := i.probeMedia(ctx, media, localFilePath)
 	if err != nil {
-		return TranscodeVideoOutput{}, fmt.Errorf("create transcode job: %w", err)
+		return TranscodeVideoOutput{}, fmt.Errorf("probe media: %w", err)
 	}
 
-	localFilePath := filepath.Join("assets", filename)
-	outputDir := filepath.Join("assets", "transcode", filename)
+	// Load or create the content key when encryption is enabled
+	encryption, err := i.encryptionForMedia(ctx, input.MediaID)
+	if err != nil {
+		return TranscodeVideoOutput{}, fmt.Errorf("prepare encryption: %w", err)
+	}
 
-	// Transcode the video into adaptive bitrate streams using ffmpeg
-	transcoder := transcoder.New()
-	renditions, err := transcoder.TranscodeAdaptiveCMAF(localFilePath, outputDir)
+	// Transcode the video into adaptive bitrate streams using ffmpeg,
+	// persisting throttled progress while it runs
+	progress.SetStage(ctx, types.TranscodeStageEncoding)
+	progress.Start(ctx)
+	opts := []transcoder.Option{
+		transcoder.WithTimeout(i.cfg.Transcode.JobTimeout),
+		transcoder.WithKillGracePeriod(i.cfg.Transcode.KillGracePeriod),
+		transcoder.WithProgress(progress.Report),
+		transcoder.WithProbeResult(probe),
+	}
+	if encryption != nil {
+		opts = append(opts, transcoder.WithEncryption(encryption))
+	}
+	transcoder := transcoder.New(opts...)
+	renditions, err := transcoder.TranscodeAdaptiveCMAFContext(ctx, localFilePath, outputDir, ladderRenditions)
+	progress.Stop()
 	if err != nil {
 		return TranscodeVideoOutput{}, fmt.Errorf("transcode adaptive: %w", err)
 	}
 
+	// Render the poster and thumbnails next to the stream output. They are
+	// not essential to playback, so a failure is logged and skipped unless
+	// the job itself was cancelled. Every attempt writes under its own
+	// prefix, so it never overwrites the output players are streaming.
+	targetDir := path.Join(input.MediaID, job.ID.Hex())
+	progress.SetStage(ctx, types.TranscodeStageThumbnails)
+	poster, thumbnails, err := i.generateThumbnails(ctx, transcoder, localFilePath, outputDir, targetDir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return TranscodeVideoOutput{}, fmt.Errorf("generate thumbnails: %w", err)
+		}
+		log.Printf("generate thumbnails for %s: %v", input.MediaID, err)
+	}
+
+	// Render the seek-bar preview sprite sheets, also optional for playback
+	progress.SetStage(ctx, types.TranscodeStageTrickplay)
+	trickplay, err := i.generateTrickplay(ctx, transcoder, localFilePath, outputDir, targetDir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return TranscodeVideoOutput{}, fmt.Errorf("generate trickplay: %w", err)
+		}
+		log.Printf("generate trickplay for %s: %v", input.MediaID, err)
+	}
+
+	if err := scratchDir.CheckQuota(); err != nil {
+		return TranscodeVideoOutput{}, err
+	}
+
 	// Upload the transcoded directory back to storage
-	targetDir := filepath.Join(filename)
-	dirPath, err := i.streamStorage.UploadDir(context.Background(), outputDir, targetDir)
+	progress.SetStage(ctx, types.TranscodeStageUploading)
+	dirPath, err := i.streamStorage.UploadDir(ctx, outputDir, targetDir)
 	if err != nil {
 		return TranscodeVideoOutput{}, fmt.Errorf("upload transcode dir: %w", err)
 	}
@@ -83,27 +253,31 @@ func (i *impl) TranscodeVideo(ctx context.Context, input TranscodeVideoInput) (T
 	// Construct and return the path to the master playlist file
 	filePath = filepath.Join(dirPath, "master.m3u8")
 
-	// remove local files
-	if err := utils.RemoveFile(localFilePath); err != nil {
-		return TranscodeVideoOutput{}, fmt.Errorf("remove local file: %w", err)
-	}
-	if err := utils.RemoveDir(outputDir); err != nil {
-		return TranscodeVideoOutput{}, fmt.Errorf("remove output dir: %w", err)
-	}
-
 	var outRenditions []types.Rendition
 	for _, r := range renditions {
 		outRenditions = append(outRenditions, types.Rendition{
 			Width:        r.Width,
 			Height:       r.Height,
 			Name:         r.Name,
+			VideoCodec:   r.VideoCodec,
+			Codecs:       r.CodecsAttribute(probe.HasAudio()),
 			VideoBitrate: r.VideoBitrate,
 			AudioBitrate: r.AudioBitrate,
 		})
 	}
 
-	return TranscodeVideoOutput{
+	output := TranscodeVideoOutput{
+		JobID:      job.ID.Hex(),
 		Path:       filePath,
+		Ladder:     ladder,
 		Renditions: outRenditions,
-	}, nil
+		Poster:     poster,
+		Thumbnails: thumbnails,
+		Trickplay:  trickplay,
+	}
+	if encryption != nil {
+		output.EncryptionMethod = encryption.Method
+	}
+
+	return output, nil
 }
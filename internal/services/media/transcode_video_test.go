@@ -0,0 +1,208 @@
+package media_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+	"media-svc/pkgs/transcoder"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTranscodeVideo(t *testing.T) {
+	tests := []struct {
+		name           string
+		ladder         string
+		options        *types.TranscodeOptions
+		ffmpegFail     string
+		missingMedia   bool
+		wantErr        string
+		wantCodec      string
+		wantRenditions []string
+	}{
+		{
+			name:           "default ladder above source is skipped",
+			wantCodec:      "h264",
+			wantRenditions: []string{"720p", "360p"},
+		},
+		{
+			name:           "codec option overrides the ladder",
+			options:        &types.TranscodeOptions{VideoCodec: "h265"},
+			wantCodec:      "hevc",
+			wantRenditions: []string{"720p", "360p"},
+		},
+		{
+			name:    "unsupported codec option",
+			options: &types.TranscodeOptions{VideoCodec: "mpeg2"},
+			wantErr: media.ErrInvalidOptions.Error(),
+		},
+		{
+			name:       "ffmpeg failure",
+			ffmpegFail: "encoder exploded",
+			wantErr:    "transcode adaptive",
+		},
+		{
+			name:         "missing media",
+			missingMedia: true,
+			wantErr:      "media not found",
+		},
+		{
+			name:    "unknown ladder",
+			ladder:  "cinema",
+			wantErr: media.ErrLadderNotFound.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeFFmpeg(t)
+			t.Setenv("FAKE_FFMPEG_FAIL", tt.ffmpegFail)
+
+			env := newTestEnv(t)
+			source := env.createSource(t, "clip.mp4")
+			mediaID := source.ID.Hex()
+			if tt.missingMedia {
+				mediaID = "0123456789abcdef01234567"
+			}
+
+			out, err := env.svc.TranscodeVideo(context.Background(), media.TranscodeVideoInput{
+				MediaID: mediaID,
+				Ladder:  tt.ladder,
+				Options: tt.options,
+			})
+
+			if left := env.scratchEntries(t); len(left) != 0 {
+				t.Errorf("scratch dirs left behind: %v", left)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TranscodeVideo: %v", err)
+			}
+
+			// Every attempt has its own output prefix
+			if out.Path != mediaID+"/"+out.JobID+"/master.m3u8" || out.Ladder != media.DefaultLadderName {
+				t.Errorf("output = %+v", out)
+			}
+			var names []string
+			for _, r := range out.Renditions {
+				names = append(names, r.Name)
+				if r.VideoCodec != tt.wantCodec || !strings.HasSuffix(r.Codecs, ",mp4a.40.2") {
+					t.Errorf("rendition %s codecs = %s %q", r.Name, r.VideoCodec, r.Codecs)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantRenditions, ",") {
+				t.Errorf("renditions = %v, want %v", names, tt.wantRenditions)
+			}
+
+			master, err := env.stream.GetObject(context.Background(), out.Path)
+			if err != nil {
+				t.Fatalf("master playlist not uploaded: %v", err)
+			}
+			if !strings.Contains(string(master), `CODECS="`) {
+				t.Errorf("master playlist has no CODECS:\n%s", master)
+			}
+
+			stored, _ := env.repo.GetMedia(context.Background(), mediaID)
+			if stored.Probe == nil || stored.Width != 1280 || stored.Height != 720 || stored.Duration != 10 {
+				t.Errorf("probe not stored on media: %+v", stored)
+			}
+
+			jobs := env.repo.TranscodeJobs()
+			if len(jobs) != 1 || jobs[0].Status != types.TranscodeJobStatusProcessing.String() {
+				t.Errorf("jobs = %+v", jobs)
+			}
+			if tt.options != nil && (jobs[0].Options == nil || jobs[0].Options.VideoCodec != tt.wantCodec) {
+				t.Errorf("job options = %+v", jobs[0].Options)
+			}
+		})
+	}
+}
+
+func TestTranscodeVideoRedelivery(t *testing.T) {
+	tests := []struct {
+		name      string
+		prevState types.TranscodeJobStatus
+		wantErr   error
+		wantJobs  int
+	}{
+		{name: "finished earlier", prevState: types.TranscodeJobStatusDone, wantErr: media.ErrDuplicateJob, wantJobs: 1},
+		{name: "still running", prevState: types.TranscodeJobStatusProcessing, wantErr: media.ErrDuplicateJob, wantJobs: 1},
+		{name: "failed earlier", prevState: types.TranscodeJobStatusError, wantJobs: 2},
+		// A worker shutdown put the job back to pending and requeued it
+		{name: "interrupted earlier", prevState: types.TranscodeJobStatusPending, wantJobs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeFFmpeg(t)
+			env := newTestEnv(t)
+			source := env.createSource(t, "clip.mp4")
+			prev := env.createJob(t, source, models.TranscodeJob{ID: primitive.NewObjectID(), MessageID: "msg-1", Status: tt.prevState.String()})
+
+			out, err := env.svc.TranscodeVideo(context.Background(), media.TranscodeVideoInput{
+				MediaID:   source.ID.Hex(),
+				MessageID: "msg-1",
+				JobID:     primitive.NewObjectID().Hex(),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			jobs := env.repo.TranscodeJobs()
+			if len(jobs) != tt.wantJobs {
+				t.Errorf("jobs = %d, want %d", len(jobs), tt.wantJobs)
+			}
+			if tt.prevState == types.TranscodeJobStatusPending {
+				if out.JobID != prev.ID.Hex() || jobs[0].Status != types.TranscodeJobStatusProcessing.String() {
+					t.Errorf("job %s = %+v, want the interrupted job taken up", out.JobID, jobs[0])
+				}
+			}
+		})
+	}
+}
+
+func TestTranscodeVideoAttempts(t *testing.T) {
+	useFakeFFmpeg(t)
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+
+	t.Setenv("FAKE_FFMPEG_FAIL", "encoder exploded")
+	_, err := env.svc.TranscodeVideo(context.Background(), media.TranscodeVideoInput{MediaID: source.ID.Hex(), WorkerID: "host-1/worker-0"})
+	var exitErr *transcoder.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 1 || !strings.Contains(exitErr.StderrTail, "encoder exploded") {
+		t.Fatalf("error = %v, want ffmpeg exit error with stderr", err)
+	}
+
+	t.Setenv("FAKE_FFMPEG_FAIL", "")
+	out, err := env.svc.TranscodeVideo(context.Background(), media.TranscodeVideoInput{MediaID: source.ID.Hex()})
+	if err != nil {
+		t.Fatalf("TranscodeVideo: %v", err)
+	}
+
+	jobs, _ := env.svc.ListTranscodeJobs(context.Background(), source.ID.Hex())
+	if len(jobs) != 2 || jobs[0].Attempt != 1 || jobs[1].Attempt != 2 || jobs[1].ID.Hex() != out.JobID {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if jobs[0].WorkerID != "host-1/worker-0" {
+		t.Errorf("worker = %q", jobs[0].WorkerID)
+	}
+
+	var stages []string
+	for _, timing := range jobs[1].StageTimings {
+		stages = append(stages, timing.Stage)
+	}
+	want := "downloading,probing,encoding,thumbnails,trickplay,uploading"
+	if strings.Join(stages, ",") != want {
+		t.Errorf("stages = %v, want %s", stages, want)
+	}
+}
@@ -0,0 +1,56 @@
+package media
+
+import (
+	"context"
+	"media-svc/internal/types"
+	"media-svc/pkgs/transcoder"
+	"path"
+)
+
+// trickplayDir is the subdirectory of the transcode output the sprite
+// sheets are written to.
+const trickplayDir = "trickplay"
+
+// generateTrickplay renders the seek-bar preview sprite sheets of a source
+// into outputDir. The returned paths are object keys under targetDir. It
+// returns nil when trickplay is disabled.
+func (i *impl) generateTrickplay(
+	ctx context.Context,
+	t *transcoder.Transcoder,
+	inputPath, outputDir, targetDir string,
+) (*types.Trickplay, error) {
+	cfg := i.cfg.Transcode.Trickplay
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tp, err := t.GenerateTrickplay(ctx, inputPath, outputDir, transcoder.TrickplayOptions{
+		Interval:      cfg.Interval,
+		Width:         cfg.Width,
+		Columns:       cfg.Columns,
+		Rows:          cfg.Rows,
+		Quality:       cfg.Quality,
+		Dir:           trickplayDir,
+		ImagePlaylist: cfg.ImagePlaylist,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &types.Trickplay{
+		VTTPath:    path.Join(targetDir, tp.VTTPath),
+		Interval:   tp.Interval.Seconds(),
+		TileWidth:  tp.TileWidth,
+		TileHeight: tp.TileHeight,
+		Columns:    tp.Columns,
+		Rows:       tp.Rows,
+	}
+	if tp.PlaylistPath != "" {
+		out.PlaylistPath = path.Join(targetDir, tp.PlaylistPath)
+	}
+	for _, sprite := range tp.Sprites {
+		out.Sprites = append(out.Sprites, path.Join(targetDir, sprite))
+	}
+
+	return out, nil
+}
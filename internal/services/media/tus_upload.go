@@ -0,0 +1,380 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"media-svc/internal/adapters/minio"
+	"media-svc/internal/models"
+	"media-svc/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultTusPartSize = 8 << 20
+	// minTusPartSize is the smallest part S3 accepts, except for the last.
+	minTusPartSize = 5 << 20
+	// tusLockDuration is how long a request owns the offset of an upload
+	// without storing a part. The lock of a request that died lapses after
+	// it.
+	tusLockDuration = 5 * time.Minute
+)
+
+var (
+	// ErrUploadNotFound is returned for an unknown tus upload.
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrOffsetMismatch is returned when a request does not continue the
+	// upload at its current offset.
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	// ErrUploadLocked is returned while another request writes to the
+	// upload.
+	ErrUploadLocked = errors.New("upload is locked by another request")
+	// ErrUploadTooLarge is returned for uploads above the maximum size.
+	ErrUploadTooLarge = errors.New("upload exceeds the maximum size")
+	// ErrChecksumMismatch is returned when a request body does not match
+	// its Upload-Checksum. Nothing of the request is kept.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrUnsupportedChecksum is returned for an unknown checksum algorithm.
+	ErrUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+)
+
+// TusChecksumAlgorithms lists the Upload-Checksum algorithms WriteTusUpload
+// verifies.
+var TusChecksumAlgorithms = []string{"md5", "sha1", "sha256"}
+
+type CreateTusUploadInput struct {
+	Length int64
+	// Metadata is the decoded Upload-Metadata. The filename, filetype and
+	// ladder keys are used.
+	Metadata map[string]string
+}
+
+// CreateTusUpload starts a resumable upload of Length bytes.
+func (i *impl) CreateTusUpload(ctx context.Context, input CreateTusUploadInput) (*models.TusUpload, error) {
+	if maxSize := i.cfg.Upload.Tus.MaxSizeMB << 20; maxSize > 0 && input.Length > maxSize {
+		return nil, ErrUploadTooLarge
+	}
+	ladder, _, err := i.resolveLadder(input.Metadata["ladder"])
+	if err != nil {
+		return nil, err
+	}
+
+	filename := input.Metadata["filename"]
+	if filename == "" {
+		filename = "upload"
+	}
+	id := primitive.NewObjectID()
+	upload := &models.TusUpload{
+		ID:          id,
+		Path:        sourceObjectName(id, filename),
+		Length:      input.Length,
+		Metadata:    input.Metadata,
+		Filename:    filename,
+		ContentType: input.Metadata["filetype"],
+		Ladder:      ladder,
+		PartSize:    i.tusPartSize(input.Length),
+		OwnerID:     callerID(ctx),
+	}
+
+	upload.MultipartID, err = i.mediaStorage.NewMultipartUpload(ctx, upload.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.mediaRepo.CreateTusUpload(ctx, upload); err != nil {
+		if err := i.mediaStorage.AbortMultipartUpload(context.Background(), upload.Path, upload.MultipartID); err != nil {
+			log.Printf("abort tus upload %s: %v", upload.Path, err)
+		}
+		return nil, fmt.Errorf("create tus upload: %w", err)
+	}
+	return upload, nil
+}
+
+// tusPartSize returns the configured part size, at least the S3 minimum and
+// grown so that length fits in maxUploadParts parts.
+func (i *impl) tusPartSize(length int64) int64 {
+	partSize := int64(defaultTusPartSize)
+	if i.cfg.Upload.Tus.PartSizeMB > 0 {
+		partSize = max(i.cfg.Upload.Tus.PartSizeMB<<20, minTusPartSize)
+	}
+	return max(partSize, (length+maxUploadParts-1)/maxUploadParts)
+}
+
+func (i *impl) GetTusUpload(ctx context.Context, id string) (*models.TusUpload, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, ErrUploadNotFound
+	}
+	upload, err := i.mediaRepo.GetTusUpload(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get tus upload: %w", err)
+	}
+	if upload == nil {
+		return nil, ErrUploadNotFound
+	}
+	if err := authorize(ctx, upload.OwnerID); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+type WriteTusUploadInput struct {
+	ID     string
+	Offset int64 // Upload-Offset of the request
+	Body   io.Reader
+	// ChecksumAlgorithm and Checksum are the optional Upload-Checksum of
+	// the body.
+	ChecksumAlgorithm string
+	Checksum          []byte
+}
+
+// WriteTusUpload appends a request body to an upload. Full parts go to
+// storage as they are read; the rest is kept as the tail of the upload. An
+// interrupted body is kept up to where it stopped, unless it had a checksum.
+// The media is created and its transcode dispatched once the last byte
+// arrives, or by an empty request at the end if that failed.
+func (i *impl) WriteTusUpload(ctx context.Context, input WriteTusUploadInput) (*models.TusUpload, error) {
+	upload, err := i.GetTusUpload(ctx, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Offset != upload.Offset {
+		return nil, ErrOffsetMismatch
+	}
+
+	// Claim the offset before writing, so concurrent requests at the same
+	// offset cannot store the same parts. The claim is renewed with every
+	// part and released with the new offset, or here on failure.
+	lockID := primitive.NewObjectID().Hex()
+	lock := func() error {
+		locked, err := i.mediaRepo.LockTusUpload(ctx, upload.ID, upload.Offset, lockID, time.Now().Add(tusLockDuration))
+		if err != nil {
+			return fmt.Errorf("lock tus upload: %w", err)
+		}
+		if !locked {
+			return ErrUploadLocked
+		}
+		return nil
+	}
+	if err := lock(); err != nil {
+		return nil, err
+	}
+	upload.LockID = lockID
+	released := false
+	defer func() {
+		if released {
+			return
+		}
+		if err := i.mediaRepo.UnlockTusUpload(context.WithoutCancel(ctx), upload.ID, lockID); err != nil {
+			log.Printf("unlock tus upload %s: %v", upload.ID.Hex(), err)
+		}
+	}()
+
+	var sum hash.Hash
+	body := io.LimitReader(input.Body, upload.Length-upload.Offset)
+	if input.ChecksumAlgorithm != "" {
+		if sum, err = newChecksumHash(input.ChecksumAlgorithm); err != nil {
+			return nil, err
+		}
+		body = io.TeeReader(body, sum)
+	}
+
+	// Bytes after the last full part are read again from the tail object
+	prevTailPath := upload.TailPath
+	src := body
+	if upload.TailSize() > 0 {
+		tail, _, err := i.mediaStorage.GetObjectReader(ctx, prevTailPath)
+		if err != nil {
+			return nil, fmt.Errorf("read tail: %w", err)
+		}
+		defer tail.Close()
+		src = io.MultiReader(tail, body)
+	}
+
+	next := *upload
+	buf := make([]byte, upload.PartSize)
+	var n int
+	var readErr error
+	for {
+		n, readErr = io.ReadFull(src, buf)
+		if n < len(buf) {
+			break
+		}
+		if _, err := i.mediaStorage.PutObjectPart(ctx, upload.Path, upload.MultipartID, next.Parts+1, bytes.NewReader(buf), int64(n)); err != nil {
+			return nil, err
+		}
+		next.Parts++
+		if err := lock(); err != nil {
+			return nil, err
+		}
+	}
+	if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+		readErr = nil
+	}
+	// What was read is stored even when the client disconnected and
+	// cancelled the request
+	ctx = context.WithoutCancel(ctx)
+
+	if sum != nil {
+		if readErr != nil {
+			return nil, fmt.Errorf("read body: %w", readErr)
+		}
+		if !bytes.Equal(sum.Sum(nil), input.Checksum) {
+			return nil, ErrChecksumMismatch
+		}
+	}
+
+	next.Offset = int64(next.Parts)*next.PartSize + int64(n)
+	if next.Offset == upload.Offset {
+		// An empty request at the end retries creating the media
+		if upload.Offset == upload.Length && upload.MediaID == "" {
+			return i.finishTusUpload(ctx, upload)
+		}
+		return upload, readErr
+	}
+
+	// The last part may be short; other leftovers wait for more bytes
+	next.TailPath = ""
+	if n > 0 {
+		if next.Offset == next.Length {
+			if _, err := i.mediaStorage.PutObjectPart(ctx, upload.Path, upload.MultipartID, next.Parts+1, bytes.NewReader(buf[:n]), int64(n)); err != nil {
+				return nil, err
+			}
+			next.Parts++
+		} else {
+			next.TailPath = fmt.Sprintf("tus/%s/tail-%d", upload.ID.Hex(), next.Offset)
+			if _, err := i.mediaStorage.PutObject(ctx, next.TailPath, bytes.NewReader(buf[:n]), int64(n)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ok, err := i.mediaRepo.UpdateTusUploadOffset(ctx, &next, upload.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("update tus upload: %w", err)
+	}
+	if !ok {
+		return nil, ErrUploadLocked
+	}
+	released = true
+	next.LockID = ""
+	if prevTailPath != "" && prevTailPath != next.TailPath {
+		if err := i.mediaStorage.RemoveObject(ctx, prevTailPath); err != nil {
+			log.Printf("remove tail of tus upload %s: %v", upload.ID.Hex(), err)
+		}
+	}
+
+	if readErr != nil {
+		// The client is gone; it resumes from the stored offset
+		log.Printf("tus upload %s interrupted at %d: %v", upload.ID.Hex(), next.Offset, readErr)
+		return &next, nil
+	}
+	if next.Offset == next.Length {
+		return i.finishTusUpload(ctx, &next)
+	}
+	return &next, nil
+}
+
+// finishTusUpload assembles the parts of a complete upload and creates its
+// media together with the transcode message. The media takes the ID of the
+// upload, so a retry after a partial failure finds it instead of creating a
+// second one.
+func (i *impl) finishTusUpload(ctx context.Context, upload *models.TusUpload) (*models.TusUpload, error) {
+	media, err := i.mediaRepo.GetMedia(ctx, upload.ID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+
+	if media == nil {
+		if _, err := i.mediaStorage.StatObject(ctx, upload.Path); err != nil {
+			parts, err := i.mediaStorage.ListUploadedParts(ctx, upload.Path, upload.MultipartID)
+			if err != nil {
+				return nil, err
+			}
+			var uploaded []minio.UploadedPart
+			for _, p := range parts {
+				if p.PartNumber <= upload.Parts {
+					uploaded = append(uploaded, p)
+				}
+			}
+			if len(uploaded) != upload.Parts {
+				return nil, fmt.Errorf("%w: %d of %d parts stored", ErrUploadIncomplete, len(uploaded), upload.Parts)
+			}
+			if err := i.mediaStorage.CompleteMultipartUpload(ctx, upload.Path, upload.MultipartID, uploaded); err != nil {
+				return nil, err
+			}
+		}
+
+		media = &models.Media{
+			ID:          upload.ID,
+			Name:        upload.Filename,
+			Description: upload.Filename,
+			Path:        upload.Path,
+			Size:        upload.Length,
+			ContentType: upload.ContentType,
+			OwnerID:     upload.OwnerID,
+		}
+		msg, err := i.transcodeMessage(types.TranscodeJob{
+			MediaID: media.ID.Hex(),
+			Ladder:  upload.Ladder,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := i.mediaRepo.CreateMediaWithOutbox(ctx, media, msg); err != nil {
+			return nil, fmt.Errorf("create media: %w", err)
+		}
+		i.probeUploadedMedia(ctx, media)
+	}
+
+	if err := i.mediaRepo.SetTusUploadMedia(ctx, upload.ID, media.ID.Hex()); err != nil {
+		return nil, fmt.Errorf("update tus upload: %w", err)
+	}
+	upload.MediaID = media.ID.Hex()
+	return upload, nil
+}
+
+// DeleteTusUpload terminates an upload and drops its stored bytes. The
+// media of a finished upload is kept.
+func (i *impl) DeleteTusUpload(ctx context.Context, id string) error {
+	upload, err := i.GetTusUpload(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if upload.MediaID == "" {
+		if err := i.mediaStorage.AbortMultipartUpload(ctx, upload.Path, upload.MultipartID); err != nil {
+			log.Printf("abort tus upload %s: %v", upload.ID.Hex(), err)
+		}
+		if upload.TailPath != "" {
+			if err := i.mediaStorage.RemoveObject(ctx, upload.TailPath); err != nil {
+				log.Printf("remove tail of tus upload %s: %v", upload.ID.Hex(), err)
+			}
+		}
+	}
+
+	if err := i.mediaRepo.DeleteTusUpload(ctx, upload.ID); err != nil {
+		return fmt.Errorf("delete tus upload: %w", err)
+	}
+	return nil
+}
+
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedChecksum, algorithm)
+	}
+}
@@ -0,0 +1,181 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+)
+
+// failingReader returns data and then err, like a body whose connection
+// dropped.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestTusUpload(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Upload.Tus.PartSizeMB = 5
+
+	content := make([]byte, 12<<20)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	ctx := context.Background()
+
+	upload, err := env.svc.CreateTusUpload(ctx, media.CreateTusUploadInput{
+		Length:   int64(len(content)),
+		Metadata: map[string]string{"filename": "clip.mp4", "filetype": "video/mp4"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTusUpload: %v", err)
+	}
+	id := upload.ID.Hex()
+
+	write := func(offset int64, body io.Reader) (int64, error) {
+		t.Helper()
+		got, err := env.svc.WriteTusUpload(ctx, media.WriteTusUploadInput{ID: id, Offset: offset, Body: body})
+		if err != nil {
+			return 0, err
+		}
+		return got.Offset, nil
+	}
+
+	// A small chunk stays in the tail
+	if offset, err := write(0, bytes.NewReader(content[:1<<20])); err != nil || offset != 1<<20 {
+		t.Fatalf("first write = %d, %v", offset, err)
+	}
+	// The connection drops after 5 MiB more: one full part and a tail
+	if offset, err := write(1<<20, &failingReader{data: content[1<<20 : 6<<20], err: io.ErrClosedPipe}); err != nil || offset != 6<<20 {
+		t.Fatalf("interrupted write = %d, %v", offset, err)
+	}
+	stored, _ := env.svc.GetTusUpload(ctx, id)
+	if stored.Parts != 1 || stored.TailSize() != 1<<20 {
+		t.Errorf("after interruption: parts = %d, tail = %d", stored.Parts, stored.TailSize())
+	}
+
+	// A stale offset is rejected
+	if _, err := write(1<<20, bytes.NewReader(content[1<<20:])); !errors.Is(err, media.ErrOffsetMismatch) {
+		t.Errorf("stale offset error = %v, want %v", err, media.ErrOffsetMismatch)
+	}
+
+	// A body that does not match its checksum is dropped
+	sum := sha1.Sum(content[6<<20:])
+	_, err = env.svc.WriteTusUpload(ctx, media.WriteTusUploadInput{
+		ID: id, Offset: 6 << 20, Body: bytes.NewReader(bytes.Repeat([]byte{0}, 6<<20)),
+		ChecksumAlgorithm: "sha1", Checksum: sum[:],
+	})
+	if !errors.Is(err, media.ErrChecksumMismatch) {
+		t.Fatalf("checksum mismatch error = %v, want %v", err, media.ErrChecksumMismatch)
+	}
+	if stored, _ := env.svc.GetTusUpload(ctx, id); stored.Offset != 6<<20 {
+		t.Errorf("offset after checksum mismatch = %d, want %d", stored.Offset, 6<<20)
+	}
+
+	// The rest finishes the upload and creates the media
+	got, err := env.svc.WriteTusUpload(ctx, media.WriteTusUploadInput{
+		ID: id, Offset: 6 << 20, Body: bytes.NewReader(content[6<<20:]),
+		ChecksumAlgorithm: "sha1", Checksum: sum[:],
+	})
+	if err != nil {
+		t.Fatalf("final write: %v", err)
+	}
+	if got.Offset != int64(len(content)) || got.MediaID != id {
+		t.Errorf("finished upload = %+v", got)
+	}
+
+	data, err := env.storage.GetObject(ctx, upload.Path)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("assembled object = %d bytes, %v", len(data), err)
+	}
+	m, _ := env.repo.GetMedia(ctx, id)
+	if m == nil || m.Path != upload.Path || m.Size != int64(len(content)) || m.ContentType != "video/mp4" {
+		t.Errorf("media = %+v", m)
+	}
+	if n := len(env.repo.Outbox()); n != 1 {
+		t.Errorf("outbox = %d messages, want 1", n)
+	}
+	for _, key := range env.storage.Keys() {
+		if key != upload.Path {
+			t.Errorf("leftover object %s", key)
+		}
+	}
+
+	// Termination keeps the media
+	if err := env.svc.DeleteTusUpload(ctx, id); err != nil {
+		t.Fatalf("DeleteTusUpload: %v", err)
+	}
+	if _, err := env.svc.GetTusUpload(ctx, id); !errors.Is(err, media.ErrUploadNotFound) {
+		t.Errorf("deleted upload error = %v, want %v", err, media.ErrUploadNotFound)
+	}
+	if m, _ := env.repo.GetMedia(ctx, id); m == nil {
+		t.Error("media deleted with the upload")
+	}
+}
+
+func TestCreateTusUploadTooLarge(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Upload.Tus.MaxSizeMB = 1
+
+	_, err := env.svc.CreateTusUpload(context.Background(), media.CreateTusUploadInput{Length: 2 << 20})
+	if !errors.Is(err, media.ErrUploadTooLarge) {
+		t.Errorf("CreateTusUpload error = %v, want %v", err, media.ErrUploadTooLarge)
+	}
+}
+
+func TestWriteTusUploadLocked(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.Upload.Tus.PartSizeMB = 5
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("x"), 6<<20)
+	upload, err := env.svc.CreateTusUpload(ctx, media.CreateTusUploadInput{
+		Length:   int64(len(content)),
+		Metadata: map[string]string{"filename": "clip.mp4"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTusUpload: %v", err)
+	}
+	write := func() (*models.TusUpload, error) {
+		return env.svc.WriteTusUpload(ctx, media.WriteTusUploadInput{ID: upload.ID.Hex(), Offset: 0, Body: bytes.NewReader(content)})
+	}
+
+	// Another request is writing at the same offset: nothing is stored
+	if ok, err := env.repo.LockTusUpload(ctx, upload.ID, 0, "other", time.Now().Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("LockTusUpload = %v, %v", ok, err)
+	}
+	if _, err := write(); !errors.Is(err, media.ErrUploadLocked) {
+		t.Fatalf("locked write error = %v, want %v", err, media.ErrUploadLocked)
+	}
+	if parts, _ := env.storage.ListUploadedParts(ctx, upload.Path, upload.MultipartID); len(parts) != 0 {
+		t.Errorf("parts stored while locked: %+v", parts)
+	}
+
+	// The lock of a request that died lapses
+	if ok, err := env.repo.LockTusUpload(ctx, upload.ID, 0, "other", time.Now().Add(-time.Second)); err != nil || !ok {
+		t.Fatalf("LockTusUpload = %v, %v", ok, err)
+	}
+	got, err := write()
+	if err != nil || got.Offset != int64(len(content)) {
+		t.Fatalf("write after the lock lapsed = %+v, %v", got, err)
+	}
+	if stored, _ := env.repo.GetTusUpload(ctx, upload.ID.Hex()); stored.LockID != "" || stored.LockedUntil != nil {
+		t.Errorf("lock kept after the write: %+v", stored)
+	}
+}
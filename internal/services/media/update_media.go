@@ -2,49 +2,42 @@ package media
 
 import (
 	"context"
+	"fmt"
+	"media-svc/internal/adapters/mongodb/media"
 	"media-svc/internal/models"
-	"time"
-
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// UpdateMediaInput changes the descriptive fields of a media. Nil fields
+// are left as they are; an empty description or tag list clears the field.
 type UpdateMediaInput struct {
-	ID           string
-	Name         string
-	Description  string
-	Path         string
-	ContentType  string
-	Size         int64
-	Duration     float64
-	Width        int
-	Height       int
-	IsStreamable bool
-	Tags         []string
+	ID          string
+	Name        *string
+	Description *string
+	Tags        *[]string
 }
 
-func (i *impl) UpdateMedia(ctx context.Context, input UpdateMediaInput) error {
-
-	objectID, _ := primitive.ObjectIDFromHex(input.ID)
+// UpdateMedia changes the name, description or tags of a media the caller
+// owns. The file and the transcode output are not affected.
+func (i *impl) UpdateMedia(ctx context.Context, input UpdateMediaInput) (*models.Media, error) {
 
-	media := &models.Media{
-		ID:           objectID,
-		Name:         input.Name,
-		Description:  input.Description,
-		Path:         input.Path,
-		ContentType:  input.ContentType,
-		Size:         input.Size,
-		Duration:     input.Duration,
-		Width:        input.Width,
-		Height:       input.Height,
-		IsStreamable: input.IsStreamable,
-		Tags:         input.Tags,
-		UpdatedAt:    time.Now().UTC(),
+	stored, err := i.ownedMedia(ctx, input.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	err := i.mediaRepo.UpdateMedia(ctx, media)
+	// Only the given fields are written, so the transcode output the worker
+	// stores meanwhile is kept
+	updated, err := i.mediaRepo.UpdateMediaDetails(ctx, media.UpdateMediaDetailsInput{
+		ID:          stored.ID,
+		Name:        input.Name,
+		Description: input.Description,
+		Tags:        input.Tags,
+	})
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("update media: %w", err)
 	}
-
-	return nil
+	if updated == nil {
+		return nil, ErrMediaNotFound
+	}
+	return updated, nil
 }
@@ -0,0 +1,66 @@
+package media_test
+
+import (
+	"context"
+	"testing"
+
+	"media-svc/internal/adapters/inmemory"
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/pkgs/scratch"
+)
+
+// transcodingRepository stores a transcode output right after the media is
+// read, as a worker finishing meanwhile does.
+type transcodingRepository struct {
+	*inmemory.MediaRepository
+}
+
+func (r *transcodingRepository) GetMedia(ctx context.Context, id string) (*models.Media, error) {
+	m, err := r.MediaRepository.GetMedia(ctx, id)
+	if err != nil || m == nil {
+		return m, err
+	}
+	done := *m
+	done.TranscodeSource = &models.TranscodeSource{FilePath: m.ID.Hex() + "/job/master.m3u8"}
+	if err := r.MediaRepository.UpdateMedia(ctx, &done); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func TestUpdateMedia(t *testing.T) {
+	env := newTestEnv(t)
+	m := env.createSource(t, "clip.mp4")
+	m.Description = "a clip"
+	m.Tags = []string{"demo"}
+	if err := env.repo.UpdateMedia(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &transcodingRepository{MediaRepository: env.repo}
+	svc := media.NewService(env.cfg, repo, env.storage, env.stream, env.publisher, scratch.New(env.scratchDir))
+
+	name, description, tags := "renamed", "", []string{}
+	res, err := svc.UpdateMedia(context.Background(), media.UpdateMediaInput{
+		ID:          m.ID.Hex(),
+		Name:        &name,
+		Description: &description,
+		Tags:        &tags,
+	})
+	if err != nil {
+		t.Fatalf("UpdateMedia: %v", err)
+	}
+	if res.Name != "renamed" || res.Description != "" || len(res.Tags) != 0 {
+		t.Errorf("media = %+v", res)
+	}
+
+	// The output the worker stored while the update ran is kept
+	stored, err := env.repo.GetMedia(context.Background(), m.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.TranscodeSource == nil || stored.Name != "renamed" || stored.Description != "" || stored.Tags != nil {
+		t.Errorf("stored = %+v", stored)
+	}
+}
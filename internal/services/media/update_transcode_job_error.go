@@ -3,16 +3,21 @@ package media
 import (
 	"context"
 	"media-svc/internal/types"
+	"time"
 )
 
 type UpdateTranscodeJobErrorInput struct {
-	MediaID string
-	Err     string
+	MediaID    string
+	JobID      string // Job of the failed attempt, the latest job of the media when empty
+	Err        string
+	Status     types.TranscodeJobStatus // Status to record, defaults to error; pending returns the job to the queue
+	ExitCode   *int                     // ffmpeg exit code, if ffmpeg failed
+	StderrTail string                   // End of the ffmpeg stderr, if ffmpeg failed
 }
 
 func (i *impl) UpdateTranscodeJobError(ctx context.Context, input UpdateTranscodeJobErrorInput) error {
 
-	job, err := i.mediaRepo.GetTranscodeJobByMediaID(ctx, input.MediaID)
+	job, err := i.transcodeJob(ctx, input.JobID, input.MediaID)
 	if err != nil {
 		return err
 	}
@@ -21,8 +26,24 @@ func (i *impl) UpdateTranscodeJobError(ctx context.Context, input UpdateTranscod
 		return nil
 	}
 
-	job.Status = types.TranscodeJobStatusError.String()
+	status := input.Status
+	if status == "" {
+		status = types.TranscodeJobStatusError
+	}
+
+	now := time.Now().UTC()
+	job.Status = status.String()
 	job.Error = input.Err
+	job.ExitCode = input.ExitCode
+	job.StderrTail = input.StderrTail
+	job.FinishStage(now)
+	job.LeaseUntil = nil
+	if status == types.TranscodeJobStatusPending {
+		// Interrupted, not finished: the next delivery claims it again
+		job.WorkerID = ""
+	} else {
+		job.FinishedAt = &now
+	}
 
 	err = i.mediaRepo.UpdateTranscodeJob(ctx, job)
 	if err != nil {
@@ -2,20 +2,28 @@ package media
 
 import (
 	"context"
+	"log"
 	"media-svc/internal/models"
 	"media-svc/internal/types"
+	"path"
 	"time"
 )
 
 type UpdateTranscodeJobSuccessInput struct {
-	MediaID    string
-	OutputPath string
-	Renditions []types.Rendition
+	MediaID          string
+	JobID            string // Job of the attempt, the latest job of the media when empty
+	OutputPath       string
+	Ladder           string
+	EncryptionMethod string
+	Renditions       []types.Rendition
+	Poster           []types.Image
+	Thumbnails       []types.Image
+	Trickplay        *types.Trickplay
 }
 
 func (i *impl) UpdateTranscodeJobSuccess(ctx context.Context, input UpdateTranscodeJobSuccessInput) error {
 
-	job, err := i.mediaRepo.GetTranscodeJobByMediaID(ctx, input.MediaID)
+	job, err := i.transcodeJob(ctx, input.JobID, input.MediaID)
 	if err != nil {
 		return err
 	}
@@ -26,8 +34,13 @@ func (i *impl) UpdateTranscodeJobSuccess(ctx context.Context, input UpdateTransc
 
 	now := time.Now().UTC()
 	job.Status = types.TranscodeJobStatusDone.String()
+	job.Stage = types.TranscodeStageCompleted.String()
+	job.Progress = 100
+	job.ETASeconds = 0
 	job.OutputPath = input.OutputPath
+	job.FinishStage(now)
 	job.FinishedAt = &now
+	job.LeaseUntil = nil
 
 	err = i.mediaRepo.UpdateTranscodeJob(ctx, job)
 	if err != nil {
@@ -39,26 +52,71 @@ func (i *impl) UpdateTranscodeJobSuccess(ctx context.Context, input UpdateTransc
 		return err
 	}
 
+	previous := media.TranscodeSource
+
 	var renditions []models.Rendition
 	for _, rendition := range input.Renditions {
 		renditions = append(renditions, models.Rendition{
 			Width:        rendition.Width,
 			Height:       rendition.Height,
 			Name:         rendition.Name,
+			VideoCodec:   rendition.VideoCodec,
+			Codecs:       rendition.Codecs,
 			VideoBitrate: rendition.VideoBitrate,
 			AudioBitrate: rendition.AudioBitrate,
 		})
 	}
 
 	media.TranscodeSource = &models.TranscodeSource{
-		FilePath:   input.OutputPath,
-		Renditions: renditions,
+		FilePath:         input.OutputPath,
+		Ladder:           input.Ladder,
+		EncryptionMethod: input.EncryptionMethod,
+		Renditions:       renditions,
+	}
+	if tp := input.Trickplay; tp != nil {
+		media.TranscodeSource.Trickplay = &models.Trickplay{
+			VTTPath:      tp.VTTPath,
+			PlaylistPath: tp.PlaylistPath,
+			Sprites:      tp.Sprites,
+			Interval:     tp.Interval,
+			TileWidth:    tp.TileWidth,
+			TileHeight:   tp.TileHeight,
+			Columns:      tp.Columns,
+			Rows:         tp.Rows,
+		}
 	}
 
+	media.Poster = mediaImages(input.Poster)
+	media.Thumbnails = mediaImages(input.Thumbnails)
+
 	err = i.mediaRepo.UpdateMedia(ctx, media)
 	if err != nil {
 		return err
 	}
 
+	// The output of the previous attempt is no longer served; a leftover
+	// is only logged
+	if previous != nil && previous.FilePath != "" {
+		if prefix := path.Dir(previous.FilePath); prefix != "." && prefix != path.Dir(input.OutputPath) {
+			if err := i.streamStorage.RemovePrefix(ctx, prefix); err != nil {
+				log.Printf("remove previous stream output of media %s: %v", input.MediaID, err)
+			}
+		}
+	}
+
 	return nil
 }
+
+func mediaImages(images []types.Image) []models.MediaImage {
+	var out []models.MediaImage
+	for _, img := range images {
+		out = append(out, models.MediaImage{
+			Key:    img.Key,
+			Width:  img.Width,
+			Height: img.Height,
+			Format: img.Format,
+			Time:   img.Time,
+		})
+	}
+	return out
+}
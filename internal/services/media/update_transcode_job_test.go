@@ -0,0 +1,158 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+)
+
+func TestUpdateTranscodeJobSuccess(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+	env.createJob(t, source, models.TranscodeJob{
+		Status: types.TranscodeJobStatusProcessing.String(),
+		Stage:  types.TranscodeStageUploading.String(),
+	})
+
+	err := env.svc.UpdateTranscodeJobSuccess(context.Background(), media.UpdateTranscodeJobSuccessInput{
+		MediaID:    source.ID.Hex(),
+		OutputPath: "clip.mp4/master.m3u8",
+		Ladder:     "default",
+		Renditions: []types.Rendition{{Name: "720p", Width: 1280, Height: 720, VideoCodec: "h264", Codecs: "avc1.4d401f,mp4a.40.2"}},
+		Poster:     []types.Image{{Key: "clip.mp4/thumbnails/poster_320x180.jpg", Width: 320, Height: 180, Format: "jpeg"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateTranscodeJobSuccess: %v", err)
+	}
+
+	job := env.repo.TranscodeJobs()[0]
+	if job.Status != types.TranscodeJobStatusDone.String() || job.Stage != types.TranscodeStageCompleted.String() ||
+		job.Progress != 100 || job.OutputPath != "clip.mp4/master.m3u8" || job.FinishedAt == nil {
+		t.Errorf("job = %+v", job)
+	}
+
+	stored, _ := env.repo.GetMedia(context.Background(), source.ID.Hex())
+	ts := stored.TranscodeSource
+	if ts == nil || ts.FilePath != "clip.mp4/master.m3u8" || ts.Ladder != "default" ||
+		len(ts.Renditions) != 1 || ts.Renditions[0].Codecs != "avc1.4d401f,mp4a.40.2" {
+		t.Errorf("transcode source = %+v", ts)
+	}
+	if len(stored.Poster) != 1 || stored.Poster[0].Key != "clip.mp4/thumbnails/poster_320x180.jpg" {
+		t.Errorf("poster = %+v", stored.Poster)
+	}
+}
+
+func TestUpdateTranscodeJobSuccessRemovesPreviousOutput(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+	source.TranscodeSource = &models.TranscodeSource{FilePath: "media/job-1/master.m3u8"}
+	if err := env.repo.UpdateMedia(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"media/job-1/master.m3u8", "media/job-1/0/seg_001.m4s", "media/job-2/master.m3u8"} {
+		if _, err := env.stream.PutObject(context.Background(), key, bytes.NewReader(nil), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.createJob(t, source, models.TranscodeJob{Attempt: 2, Status: types.TranscodeJobStatusProcessing.String()})
+
+	err := env.svc.UpdateTranscodeJobSuccess(context.Background(), media.UpdateTranscodeJobSuccessInput{
+		MediaID:    source.ID.Hex(),
+		OutputPath: "media/job-2/master.m3u8",
+	})
+	if err != nil {
+		t.Fatalf("UpdateTranscodeJobSuccess: %v", err)
+	}
+
+	// The renditions of the previous attempt are no longer reachable
+	if keys := env.stream.Keys(); !slices.Equal(keys, []string{"media/job-2/master.m3u8"}) {
+		t.Errorf("stream keys = %v", keys)
+	}
+}
+
+func TestUpdateTranscodeJobError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     types.TranscodeJobStatus
+		wantStatus types.TranscodeJobStatus
+	}{
+		{name: "defaults to error", wantStatus: types.TranscodeJobStatusError},
+		{name: "timeout", status: types.TranscodeJobStatusTimeout, wantStatus: types.TranscodeJobStatusTimeout},
+		{name: "cancelled", status: types.TranscodeJobStatusCancelled, wantStatus: types.TranscodeJobStatusCancelled},
+		{name: "interrupted by shutdown", status: types.TranscodeJobStatusPending, wantStatus: types.TranscodeJobStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			source := env.createSource(t, "clip.mp4")
+			env.createJob(t, source, models.TranscodeJob{Status: types.TranscodeJobStatusProcessing.String(), WorkerID: "host-1/worker-0"})
+
+			err := env.svc.UpdateTranscodeJobError(context.Background(), media.UpdateTranscodeJobErrorInput{
+				MediaID: source.ID.Hex(),
+				Err:     "ffmpeg failed",
+				Status:  tt.status,
+			})
+			if err != nil {
+				t.Fatalf("UpdateTranscodeJobError: %v", err)
+			}
+
+			job := env.repo.TranscodeJobs()[0]
+			if job.Status != tt.wantStatus.String() || job.Error != "ffmpeg failed" {
+				t.Errorf("job = %+v", job)
+			}
+			// A pending job is not finished and can be claimed again
+			if pending := tt.wantStatus == types.TranscodeJobStatusPending; (job.FinishedAt == nil) != pending || (job.WorkerID == "") != pending {
+				t.Errorf("finished at %v by %q", job.FinishedAt, job.WorkerID)
+			}
+		})
+	}
+}
+
+func TestUpdateTranscodeJobWithoutJob(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+
+	if err := env.svc.UpdateTranscodeJobError(context.Background(), media.UpdateTranscodeJobErrorInput{MediaID: source.ID.Hex()}); err != nil {
+		t.Errorf("UpdateTranscodeJobError: %v", err)
+	}
+	if err := env.svc.UpdateTranscodeJobSuccess(context.Background(), media.UpdateTranscodeJobSuccessInput{MediaID: source.ID.Hex()}); err != nil {
+		t.Errorf("UpdateTranscodeJobSuccess: %v", err)
+	}
+	if jobs := env.repo.TranscodeJobs(); len(jobs) != 0 {
+		t.Errorf("jobs = %+v", jobs)
+	}
+}
+
+func TestUpdateTranscodeJobByID(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.createSource(t, "clip.mp4")
+	first := env.createJob(t, source, models.TranscodeJob{Attempt: 1, Status: types.TranscodeJobStatusProcessing.String()})
+	env.createJob(t, source, models.TranscodeJob{Attempt: 2, Status: types.TranscodeJobStatusProcessing.String()})
+
+	exitCode := 1
+	err := env.svc.UpdateTranscodeJobError(context.Background(), media.UpdateTranscodeJobErrorInput{
+		MediaID:    source.ID.Hex(),
+		JobID:      first.ID.Hex(),
+		Err:        "ffmpeg failed",
+		ExitCode:   &exitCode,
+		StderrTail: "Invalid data found",
+	})
+	if err != nil {
+		t.Fatalf("UpdateTranscodeJobError: %v", err)
+	}
+
+	jobs := env.repo.TranscodeJobs()
+	if jobs[0].Status != types.TranscodeJobStatusError.String() || jobs[0].ExitCode == nil || *jobs[0].ExitCode != 1 ||
+		jobs[0].StderrTail != "Invalid data found" {
+		t.Errorf("first attempt = %+v", jobs[0])
+	}
+	if jobs[1].Status != types.TranscodeJobStatusProcessing.String() {
+		t.Errorf("second attempt changed: %+v", jobs[1])
+	}
+}
@@ -2,24 +2,28 @@ package media
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 	"media-svc/internal/models"
 	"media-svc/internal/types"
 	"mime/multipart"
 	"path/filepath"
-	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UploadVideoInput struct {
-	File *multipart.FileHeader
+	File   *multipart.FileHeader
+	Ladder string // Optional rendition ladder name, empty for the default
 }
 
 func (i *impl) UploadVideo(ctx context.Context, input UploadVideoInput) (*models.Media, error) {
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(input.File.Filename))
+	ladder, _, err := i.resolveLadder(input.Ladder)
+	if err != nil {
+		return nil, err
+	}
 
-	filePath := filepath.Join("videos", filename)
+	mediaID := primitive.NewObjectID()
+	filePath := sourceObjectName(mediaID, input.File.Filename)
 
 	src, err := input.File.Open()
 	if err != nil {
@@ -33,31 +37,35 @@ func (i *impl) UploadVideo(ctx context.Context, input UploadVideoInput) (*models
 	}
 
 	media := &models.Media{
+		ID:          mediaID,
 		Name:        input.File.Filename,
 		Description: input.File.Filename,
 		Path:        filePath,
 		Size:        input.File.Size,
 		ContentType: input.File.Header.Get("Content-Type"),
-	}
-	err = i.mediaRepo.CreateMedia(ctx, media)
-	if err != nil {
-		return nil, err
+		OwnerID:     callerID(ctx),
 	}
 
-	job := types.TranscodeJob{
+	// The job is published by the outbox relay once the media is stored
+	msg, err := i.transcodeMessage(types.TranscodeJob{
 		MediaID: media.ID.Hex(),
-	}
-
-	data, err := json.Marshal(job)
+		Ladder:  ladder,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	err = i.rabbitClient.Publish(i.cfg.RabbitMQ.Queue, data)
+	err = i.mediaRepo.CreateMediaWithOutbox(ctx, media, msg)
 	if err != nil {
-		log.Println("Failed to publish job:", err)
 		return nil, err
 	}
 
+	i.probeUploadedMedia(ctx, media)
 	return media, nil
 }
+
+// sourceObjectName returns the object key the uploaded file of a media is
+// stored at. The media ID keeps uploads of the same name apart.
+func sourceObjectName(mediaID primitive.ObjectID, filename string) string {
+	return filepath.Join("videos", fmt.Sprintf("%s_%s", mediaID.Hex(), filepath.Base(filename)))
+}
@@ -0,0 +1,101 @@
+package media_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"media-svc/config"
+	"media-svc/internal/models"
+	"media-svc/internal/services/media"
+	"media-svc/internal/types"
+)
+
+func TestUploadVideo(t *testing.T) {
+	tests := []struct {
+		name       string
+		ladders    map[string][]config.Rendition
+		ladder     string
+		wantErr    bool
+		errIs      error
+		wantLadder string
+	}{
+		{
+			name:       "default ladder",
+			wantLadder: media.DefaultLadderName,
+		},
+		{
+			name:       "configured ladder",
+			ladders:    map[string][]config.Rendition{"mobile": {{Name: "240p", Width: 426, Height: 240}}},
+			ladder:     "Mobile",
+			wantLadder: "mobile",
+		},
+		{
+			name:    "unknown ladder",
+			ladder:  "cinema",
+			wantErr: true,
+			errIs:   media.ErrLadderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeFFmpeg(t)
+			env := newTestEnv(t)
+			env.cfg.Transcode.Ladders = tt.ladders
+
+			res, err := env.svc.UploadVideo(context.Background(), media.UploadVideoInput{
+				File:   fileHeader(t, "clip.mp4", []byte("video bytes")),
+				Ladder: tt.ladder,
+			})
+
+			if tt.wantErr {
+				if err == nil || tt.errIs != nil && !errors.Is(err, tt.errIs) {
+					t.Fatalf("error = %v, want %v", err, tt.errIs)
+				}
+				if tt.errIs == media.ErrLadderNotFound && len(env.storage.Keys()) != 0 {
+					t.Errorf("stored %v for a rejected upload", env.storage.Keys())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadVideo: %v", err)
+			}
+
+			if !strings.HasPrefix(res.Path, "videos/") || !strings.HasSuffix(res.Path, "_clip.mp4") {
+				t.Errorf("path = %q", res.Path)
+			}
+			data, err := env.storage.GetObject(context.Background(), res.Path)
+			if err != nil || string(data) != "video bytes" {
+				t.Errorf("stored object = %q, %v", data, err)
+			}
+
+			stored, _ := env.repo.GetMedia(context.Background(), res.ID.Hex())
+			if stored == nil || stored.Size != int64(len("video bytes")) {
+				t.Errorf("stored media = %+v", stored)
+			}
+			// The source is probed on upload, before any worker sees it
+			if stored == nil || stored.Probe == nil || stored.Duration != 10 || stored.Width != 1280 {
+				t.Errorf("stored probe = %+v", stored)
+			}
+
+			// The job goes to the outbox, not straight to the broker
+			if messages := env.publisher.Messages(testQueue); len(messages) != 0 {
+				t.Errorf("published %d messages before the relay ran", len(messages))
+			}
+			outbox := env.repo.Outbox()
+			if len(outbox) != 1 || outbox[0].Queue != testQueue || outbox[0].Status != string(models.OutboxStatusPending) {
+				t.Fatalf("outbox = %+v", outbox)
+			}
+			var job types.TranscodeJob
+			if err := json.Unmarshal(outbox[0].Payload, &job); err != nil {
+				t.Fatal(err)
+			}
+			if job.MediaID != res.ID.Hex() || job.Ladder != tt.wantLadder || job.MessageID != outbox[0].ID.Hex() {
+				t.Errorf("job = %+v, want media %s ladder %s", job, res.ID.Hex(), tt.wantLadder)
+			}
+		})
+	}
+}
@@ -1,11 +1,16 @@
 package services
 
 import (
+	"fmt"
 	"media-svc/config"
+	"media-svc/internal/adapters/localfs"
 	"media-svc/internal/adapters/minio"
+	"media-svc/internal/adapters/mongodb/auth"
 	"media-svc/internal/adapters/mongodb/media"
+	authSvc "media-svc/internal/services/auth"
 	mediaSvc "media-svc/internal/services/media"
 	"media-svc/pkgs/rabbitmq"
+	"media-svc/pkgs/scratch"
 
 	mongodb "github.com/dtome123/go-mongo-generic"
 )
@@ -13,6 +18,7 @@ import (
 type Service struct {
 	cfg          *config.Config
 	mediaSvc     mediaSvc.MediaService
+	authSvc      authSvc.AuthService
 	rabbitClient *rabbitmq.Publisher
 }
 
@@ -20,19 +26,85 @@ func NewService(
 	cfg *config.Config,
 	db *mongodb.Database,
 	rabbitClient *rabbitmq.Publisher,
-) *Service {
+) (*Service, error) {
 
 	mediaRepo := media.NewMediaRepository(db)
-	mediaStorage, _ := minio.New(cfg, cfg.S3.Bucket)
-	streamStorage, _ := minio.New(cfg, cfg.S3.StreamBucket)
+	mediaStorage, err := NewStorage(cfg, cfg.S3.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("media storage: %w", err)
+	}
+	streamStorage, err := NewStorage(cfg, cfg.S3.StreamBucket)
+	if err != nil {
+		return nil, fmt.Errorf("stream storage: %w", err)
+	}
+	authService, err := authSvc.NewService(cfg, auth.NewAuthRepository(db))
+	if err != nil {
+		return nil, err
+	}
 
 	return &Service{
 		cfg:          cfg,
 		rabbitClient: rabbitClient,
-		mediaSvc:     mediaSvc.NewService(cfg, mediaRepo, mediaStorage, streamStorage, rabbitClient),
+		mediaSvc:     mediaSvc.NewService(cfg, mediaRepo, mediaStorage, streamStorage, rabbitClient, NewScratchManager(cfg)),
+		authSvc:      authService,
+	}, nil
+}
+
+// NewServiceWith wraps services built by the caller, e.g. over the
+// in-memory adapters.
+func NewServiceWith(cfg *config.Config, media mediaSvc.MediaService, auth authSvc.AuthService) *Service {
+	return &Service{
+		cfg:      cfg,
+		mediaSvc: media,
+		authSvc:  auth,
 	}
 }
 
+// Storage drivers selectable with storage.driver.
+const (
+	StorageDriverS3 = "s3"
+	StorageDriverFS = "fs"
+)
+
+// NewStorage returns the storage of a bucket for the configured driver.
+func NewStorage(cfg *config.Config, bucket string) (minio.StorageAdapter, error) {
+	switch cfg.Storage.Driver {
+	case "", StorageDriverS3:
+		return minio.New(cfg, bucket)
+	case StorageDriverFS:
+		return localfs.New(cfg, bucket)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+// NewScratchManager returns the manager of the per-job working directories.
+func NewScratchManager(cfg *config.Config) *scratch.Manager {
+	return scratch.New(
+		cfg.Scratch.Root,
+		scratch.WithQuota(cfg.Scratch.QuotaMB<<20),
+		scratch.WithMinFreeSpace(cfg.Scratch.MinFreeMB<<20),
+		scratch.WithStaleAfter(cfg.Scratch.StaleAfter),
+	)
+}
+
+// NewConsumer connects a RabbitMQ consumer using the configured retry policy
+// and worker concurrency.
+func NewConsumer(cfg *config.Config) (*rabbitmq.Consumer, error) {
+	return rabbitmq.NewConsumer(cfg.RabbitMQ.DSN,
+		rabbitmq.WithRetryPolicy(rabbitmq.RetryPolicy{
+			MaxAttempts: cfg.RabbitMQ.MaxAttempts,
+			BaseDelay:   cfg.RabbitMQ.RetryBaseDelay,
+			MaxDelay:    cfg.RabbitMQ.RetryMaxDelay,
+		}),
+		rabbitmq.WithConcurrency(cfg.Worker.Concurrency),
+	)
+}
+
 func (i *Service) GetMediaSvc() mediaSvc.MediaService {
 	return i.mediaSvc
 }
+
+func (i *Service) GetAuthSvc() authSvc.AuthService {
+	return i.authSvc
+}
@@ -2,6 +2,24 @@ package types
 
 type TranscodeJob struct {
 	MediaID string `json:"media_id"`
+	Ladder  string `json:"ladder,omitempty"` // Rendition ladder to encode with, empty for the default
+	// MessageID identifies the dispatch, so a redelivered copy of a job that
+	// already ran can be recognised and skipped.
+	MessageID string `json:"message_id,omitempty"`
+	// JobID is set when a job whose worker was lost is dispatched again, so
+	// the next worker takes over the same job record.
+	JobID string `json:"job_id,omitempty"`
+	// Options override the codec settings of the ladder, e.g. to re-encode
+	// a media with another codec.
+	Options *TranscodeOptions `json:"options,omitempty"`
+}
+
+// TranscodeOptions override the codec settings of every rendition of a
+// ladder. Empty fields keep the ladder's value.
+type TranscodeOptions struct {
+	VideoCodec string `json:"video_codec,omitempty"` // h264, hevc, vp9 or av1
+	Preset     string `json:"preset,omitempty"`
+	Profile    string `json:"profile,omitempty"`
 }
 
 type TranscodeJobStatus string
@@ -11,16 +29,58 @@ const (
 	TranscodeJobStatusProcessing TranscodeJobStatus = "processing"
 	TranscodeJobStatusDone       TranscodeJobStatus = "done"
 	TranscodeJobStatusError      TranscodeJobStatus = "error"
+	TranscodeJobStatusTimeout    TranscodeJobStatus = "timeout"
+	TranscodeJobStatusCancelled  TranscodeJobStatus = "cancelled"
 )
 
 func (t TranscodeJobStatus) String() string {
 	return string(t)
 }
 
+// TranscodeStage is the step of the pipeline a processing job is in.
+type TranscodeStage string
+
+const (
+	TranscodeStageDownloading TranscodeStage = "downloading"
+	TranscodeStageProbing     TranscodeStage = "probing"
+	TranscodeStageEncoding    TranscodeStage = "encoding"
+	TranscodeStageThumbnails  TranscodeStage = "thumbnails"
+	TranscodeStageTrickplay   TranscodeStage = "trickplay"
+	TranscodeStageUploading   TranscodeStage = "uploading"
+	TranscodeStageCompleted   TranscodeStage = "completed"
+)
+
+func (t TranscodeStage) String() string {
+	return string(t)
+}
+
 type Rendition struct {
 	Name         string
 	Width        int
 	Height       int
+	VideoCodec   string // h264, hevc, vp9 or av1
+	Codecs       string // RFC 6381 CODECS value of the variant, e.g. "avc1.4d401f,mp4a.40.2"
 	VideoBitrate string
 	AudioBitrate string
 }
+
+// Image is a generated poster or thumbnail.
+type Image struct {
+	Key    string // Object key in the stream bucket
+	Width  int
+	Height int
+	Format string  // jpeg or webp
+	Time   float64 // Position of the frame in seconds
+}
+
+// Trickplay describes the seek-bar preview sprite sheets of a transcode.
+type Trickplay struct {
+	VTTPath      string   // Object key of the WebVTT thumbnail track
+	PlaylistPath string   // Object key of the HLS image playlist, empty when not published
+	Sprites      []string // Object keys of the sprite sheets
+	Interval     float64  // Seconds between two tiles
+	TileWidth    int
+	TileHeight   int
+	Columns      int
+	Rows         int
+}
@@ -5,11 +5,25 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// streamContentTypes covers the stream output files missing from the
+// system MIME tables on minimal images.
+var streamContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mpd":  "application/dash+xml",
+	".m4s":  "video/iso.segment",
+	".vtt":  "text/vtt",
+}
+
 func DetectContentTypeByFileName(fileName string) string {
 	ext := filepath.Ext(fileName)
 
+	if contentType, ok := streamContentTypes[strings.ToLower(ext)]; ok {
+		return contentType
+	}
+
 	if contentType := mime.TypeByExtension(ext); contentType != "" {
 		return contentType
 	}
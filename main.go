@@ -28,6 +28,13 @@ func main() {
 		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
 
-	server := port.NewServer(cfg, db, rabbitClient)
+	if !cfg.Auth.Enabled {
+		log.Println("WARNING: authentication is disabled (auth.enabled: false); every caller may read, change and delete any media")
+	}
+
+	server, err := port.NewServer(cfg, db, rabbitClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize server: %v", err)
+	}
 	server.Run()
 }
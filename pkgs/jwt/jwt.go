@@ -0,0 +1,242 @@
+// Package jwt verifies JSON Web Tokens signed with HS256 or RS256, with the
+// RSA public keys read from a JWKS document.
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformed is returned for a token that is not a signed JWT.
+	ErrMalformed = errors.New("malformed token")
+	// ErrAlgorithm is returned for a token signed with an algorithm the
+	// verifier has no key for.
+	ErrAlgorithm = errors.New("unsupported signing algorithm")
+	// ErrUnknownKey is returned when the key ID of a token is not in the
+	// key set.
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrSignature is returned when the signature does not match.
+	ErrSignature = errors.New("invalid signature")
+	// ErrExpired is returned for a token past its exp claim.
+	ErrExpired = errors.New("token expired")
+	// ErrNoExpiry is returned for a token without an exp claim, which
+	// would be valid forever.
+	ErrNoExpiry = errors.New("token has no expiry")
+	// ErrNotYetValid is returned for a token before its nbf claim.
+	ErrNotYetValid = errors.New("token not yet valid")
+	// ErrIssuer is returned when the iss claim is not the expected issuer.
+	ErrIssuer = errors.New("unexpected issuer")
+	// ErrAudience is returned when the aud claim lacks the expected
+	// audience.
+	ErrAudience = errors.New("unexpected audience")
+)
+
+// Claims are the decoded claims of a verified token.
+type Claims map[string]any
+
+// Subject returns the sub claim.
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Strings returns a claim holding a string or a list of strings. A string
+// with spaces, such as an OAuth scope, is split into its words.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// time returns a NumericDate claim and whether it is present.
+func (c Claims) time(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+// Verifier checks the signature and registered claims of tokens.
+type Verifier struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+type Option func(*Verifier)
+
+// WithHMACSecret accepts HS256 tokens signed with secret.
+func WithHMACSecret(secret []byte) Option {
+	return func(v *Verifier) {
+		v.secret = secret
+	}
+}
+
+// WithRSAKeys accepts RS256 tokens signed with one of keys, by key ID.
+func WithRSAKeys(keys map[string]*rsa.PublicKey) Option {
+	return func(v *Verifier) {
+		v.keys = keys
+	}
+}
+
+// WithIssuer requires the iss claim to be issuer.
+func WithIssuer(issuer string) Option {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience requires the aud claim to contain audience.
+func WithAudience(audience string) Option {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway allows for clock skew when checking exp and nbf.
+func WithLeeway(leeway time.Duration) Option {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// NewVerifier returns a verifier that accepts the algorithms it is given
+// keys for.
+func NewVerifier(opts ...Option) *Verifier {
+	v := &Verifier{now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks a compact serialized token and returns its claims. exp is
+// required and nbf checked when present; iss and aud when the verifier
+// expects them.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if err := v.verifySignature(h, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) verifySignature(h header, signed string, signature []byte) error {
+	switch h.Alg {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return fmt.Errorf("%w: %s", ErrAlgorithm, h.Alg)
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrSignature
+		}
+		return nil
+	case "RS256":
+		if len(v.keys) == 0 {
+			return fmt.Errorf("%w: %s", ErrAlgorithm, h.Alg)
+		}
+		key, ok := v.keys[h.Kid]
+		if !ok && h.Kid == "" && len(v.keys) == 1 {
+			// A token without kid can only mean the one key
+			for _, k := range v.keys {
+				key, ok = k, true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownKey, h.Kid)
+		}
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrAlgorithm, h.Alg)
+	}
+}
+
+func (v *Verifier) checkClaims(claims Claims) error {
+	now := v.now()
+	exp, ok := claims.time("exp")
+	if !ok {
+		return ErrNoExpiry
+	}
+	if !now.Before(exp.Add(v.leeway)) {
+		return ErrExpired
+	}
+	if nbf, ok := claims.time("nbf"); ok && now.Add(v.leeway).Before(nbf) {
+		return ErrNotYetValid
+	}
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return ErrIssuer
+		}
+	}
+	if v.audience != "" && !slices.Contains(claims.Strings("aud"), v.audience) {
+		return ErrAudience
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func sign(t *testing.T, h header, claims Claims, signer func(signed string) []byte) string {
+	t.Helper()
+
+	hdr, _ := json.Marshal(h)
+	body, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(hdr) + "." + base64.RawURLEncoding.EncodeToString(body)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signer(signed))
+}
+
+func hs256(secret string) func(string) []byte {
+	return func(signed string) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey) func(string) []byte {
+	return func(signed string) []byte {
+		digest := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := NewVerifier(WithHMACSecret([]byte("secret")), WithIssuer("https://idp.example.com"), WithAudience("media-svc"))
+	v.now = func() time.Time { return now }
+
+	valid := Claims{"sub": "user-1", "iss": "https://idp.example.com", "aud": []any{"media-svc"}, "exp": float64(now.Unix() + 60), "roles": []any{"admin"}}
+	claims, err := v.Verify(sign(t, header{Alg: "HS256"}, valid, hs256("secret")))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject() != "user-1" || len(claims.Strings("roles")) != 1 {
+		t.Errorf("claims = %v", claims)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c Claims)
+		secret string
+		want   error
+	}{
+		{name: "wrong secret", secret: "other", want: ErrSignature},
+		{name: "expired", modify: func(c Claims) { c["exp"] = float64(now.Unix()) }, want: ErrExpired},
+		{name: "no expiry", modify: func(c Claims) { delete(c, "exp") }, want: ErrNoExpiry},
+		{name: "not yet valid", modify: func(c Claims) { c["nbf"] = float64(now.Unix() + 60) }, want: ErrNotYetValid},
+		{name: "issuer", modify: func(c Claims) { c["iss"] = "https://evil.example.org" }, want: ErrIssuer},
+		{name: "audience", modify: func(c Claims) { c["aud"] = "other" }, want: ErrAudience},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := Claims{}
+			for k, val := range valid {
+				claims[k] = val
+			}
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			secret := "secret"
+			if tt.secret != "" {
+				secret = tt.secret
+			}
+			if _, err := v.Verify(sign(t, header{Alg: "HS256"}, claims, hs256(secret))); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"k1","use":"sig","alg":"RS256","n":%q,"e":%q},{"kty":"EC","kid":"k2"}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+	keys, err := ParseJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatalf("ParseJWKS: %v", err)
+	}
+	v := NewVerifier(WithRSAKeys(keys))
+
+	claims := Claims{"sub": "user-1", "exp": float64(time.Now().Unix() + 60)}
+	if _, err := v.Verify(sign(t, header{Alg: "RS256", Kid: "k1"}, claims, rs256(t, key))); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if _, err := v.Verify(sign(t, header{Alg: "RS256"}, claims, rs256(t, key))); err != nil {
+		t.Errorf("Verify without kid: %v", err)
+	}
+	if _, err := v.Verify(sign(t, header{Alg: "RS256", Kid: "k9"}, claims, rs256(t, key))); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown kid: error = %v", err)
+	}
+
+	// Without an HMAC secret, HS256 must not be accepted, e.g. signed with
+	// the public key
+	if _, err := v.Verify(sign(t, header{Alg: "HS256"}, claims, hs256("public"))); !errors.Is(err, ErrAlgorithm) {
+		t.Errorf("HS256: error = %v", err)
+	}
+	if _, err := v.Verify(sign(t, header{Alg: "none"}, claims, func(string) []byte { return nil })); !errors.Is(err, ErrAlgorithm) {
+		t.Errorf("none: error = %v", err)
+	}
+}
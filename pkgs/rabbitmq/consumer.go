@@ -2,96 +2,89 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-type Publisher struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	url     string
-}
+type Consumer struct {
+	mu      sync.RWMutex
+	conn    amqpConnection
+	channel amqpChannel
+	// confirmed publishes retries and dead letters on channel
+	confirmed *confirmedChannel
+	dial      dialer
+	url       string
+	retry     RetryPolicy
+	state     State
+	closed    bool
 
-func NewPublisher(url string) (*Publisher, error) {
-	p := &Publisher{url: url}
-	if err := p.connect(); err != nil {
-		return nil, err
-	}
-	return p, nil
-}
+	// concurrency is how many messages are handled at once; the prefetch
+	// count matches it so the broker never hands over more
+	concurrency int
+	// slots holds one token per message being handled, across connections,
+	// so jobs still draining after a lost connection count against the
+	// concurrency of the next one
+	slots    chan struct{}
+	inflight sync.WaitGroup
 
-func (p *Publisher) connect() error {
-	conn, err := amqp.Dial(p.url)
-	if err != nil {
-		return err
-	}
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return err
-	}
-	p.conn = conn
-	p.channel = ch
-	return nil
+	reconnectMin time.Duration
+	reconnectMax time.Duration
 }
 
-func (p *Publisher) Close() {
-	if p.channel != nil {
-		p.channel.Close()
-	}
-	if p.conn != nil {
-		p.conn.Close()
+// ConsumerOption configures a Consumer.
+type ConsumerOption func(*Consumer)
+
+// WithRetryPolicy sets how failed messages are retried and dead-lettered.
+func WithRetryPolicy(policy RetryPolicy) ConsumerOption {
+	return func(c *Consumer) {
+		c.retry = policy
 	}
 }
 
-// Publish gửi message lên queue với cơ chế tái kết nối nếu connection/chanel bị đóng
-func (p *Publisher) Publish(queue string, data []byte) error {
-	// Nếu channel hoặc conn đóng thì reconnect
-	if p.conn.IsClosed() || p.channel.IsClosed() {
-		if err := p.connect(); err != nil {
-			return fmt.Errorf("reconnect failed: %w", err)
-		}
+// WithConcurrency sets how many messages are handled in parallel. The
+// channel prefetch is set to the same value.
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *Consumer) {
+		c.concurrency = n
 	}
+}
 
-	_, err := p.channel.QueueDeclare(
-		queue,
-		true,  // durable
-		false, // autoDelete
-		false, // exclusive
-		false, // noWait
-		nil,
-	)
-	if err != nil {
-		return err
+// WithReconnectBackoff sets the bounds of the jittered, doubling delay
+// between reconnect attempts after the broker connection is lost.
+func WithReconnectBackoff(min, max time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.reconnectMin = min
+		c.reconnectMax = max
 	}
-
-	err = p.channel.Publish(
-		"",    // default exchange
-		queue, // routing key = queue name
-		false, // mandatory
-		false, // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         data,
-			DeliveryMode: amqp.Persistent, // tin nhắn bền vững
-		},
-	)
-
-	return err
 }
 
-// --------------------------
-
-type Consumer struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	url     string
+func NewConsumer(url string, opts ...ConsumerOption) (*Consumer, error) {
+	return newConsumer(url, dialAMQP, opts...)
 }
 
-func NewConsumer(url string) (*Consumer, error) {
-	c := &Consumer{url: url}
+func newConsumer(url string, dial dialer, opts ...ConsumerOption) (*Consumer, error) {
+	c := &Consumer{
+		url:          url,
+		dial:         dial,
+		retry:        DefaultRetryPolicy,
+		state:        StateConnecting,
+		reconnectMin: time.Second,
+		reconnectMax: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.retry = c.retry.withDefaults()
+	if c.concurrency <= 0 {
+		c.concurrency = 1
+	}
+	c.slots = make(chan struct{}, c.concurrency)
 	if err := c.connect(); err != nil {
 		return nil, err
 	}
@@ -99,7 +92,7 @@ func NewConsumer(url string) (*Consumer, error) {
 }
 
 func (c *Consumer) connect() error {
-	conn, err := amqp.Dial(c.url)
+	conn, err := c.dial(c.url)
 	if err != nil {
 		return err
 	}
@@ -109,19 +102,58 @@ func (c *Consumer) connect() error {
 		return err
 	}
 
-	err = ch.Qos(1, 0, false) // prefetch 1 message
+	err = ch.Qos(c.concurrency, 0, false) // prefetch bằng số message xử lý song song
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return err
+	}
+	// Retry và dead-letter chỉ ack message gốc khi broker đã confirm bản sao
+	confirmed, err := newConfirmedChannel(ch)
 	if err != nil {
 		ch.Close()
 		conn.Close()
 		return err
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		ch.Close()
+		conn.Close()
+		return ErrClosed
+	}
+	// Bỏ connection cũ nếu còn sống sau một lần consume thất bại
+	if c.conn != nil {
+		c.conn.Close()
+	}
 	c.conn = conn
 	c.channel = ch
+	c.confirmed = confirmed
+	c.state = StateConnected
 	return nil
 }
 
+// State reports whether the consumer is currently connected to the broker.
+func (c *Consumer) State() State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+func (c *Consumer) setState(state State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.state = state
+	}
+}
+
 func (c *Consumer) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.state = StateClosed
 	if c.channel != nil {
 		c.channel.Close()
 	}
@@ -131,20 +163,70 @@ func (c *Consumer) Close() {
 }
 
 // Consume chạy loop lấy message và xử lý, có thể dùng context để dừng
+//
+// Khi handler trả lỗi, message được gửi lại qua delay queue với backoff tăng
+// dần; sau RetryPolicy.MaxAttempts lần, hoặc khi lỗi là PermanentError,
+// message được chuyển vào dead-letter queue.
+//
+// Khi connection hoặc channel bị đóng, Consume tự kết nối lại với backoff có
+// jitter, khai báo lại topology và tiếp tục nhận message. Các message đang
+// xử lý dở vẫn chạy tiếp trong lúc kết nối lại. Consume chỉ trả về khi ctx
+// bị huỷ hoặc khi Close được gọi, sau khi mọi handler đã xong.
 func (c *Consumer) Consume(ctx context.Context, queue string, handler func([]byte) error) error {
-	_, err := c.channel.QueueDeclare(
-		queue,
-		true,  // durable
-		false, // autoDelete
-		false, // exclusive
-		false, // noWait
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("declare queue failed: %w", err)
+	defer c.inflight.Wait()
+
+	backoff := c.reconnectMin
+	for {
+		err := c.consume(ctx, queue, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if c.State() == StateClosed {
+			return ErrClosed
+		}
+		log.Printf("rabbitmq consumer interrupted: %v", err)
+
+		// Kết nối lại cho tới khi thành công hoặc ctx bị huỷ
+		for {
+			c.setState(StateReconnecting)
+			wait := jitter(backoff)
+			log.Printf("rabbitmq reconnecting in %s", wait)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+
+			backoff = min(backoff*2, c.reconnectMax)
+			if err := c.connect(); err != nil {
+				if errors.Is(err, ErrClosed) {
+					return err
+				}
+				log.Printf("rabbitmq reconnect failed: %v", err)
+				continue
+			}
+			log.Printf("rabbitmq reconnected")
+			backoff = c.reconnectMin
+			break
+		}
+	}
+}
+
+// consume declares the topology and handles deliveries on the current
+// channel until ctx is done or the connection or channel closes.
+func (c *Consumer) consume(ctx context.Context, queue string, handler func([]byte) error) error {
+	c.mu.RLock()
+	conn, ch, confirmed := c.conn, c.channel, c.confirmed
+	c.mu.RUnlock()
+
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	if err := declareTopology(ch, queue, c.retry); err != nil {
+		return err
 	}
 
-	msgs, err := c.channel.Consume(
+	msgs, err := ch.Consume(
 		queue,
 		"",    // consumer tag auto generated
 		false, // autoAck false vì muốn ack thủ công
@@ -157,28 +239,124 @@ func (c *Consumer) Consume(ctx context.Context, queue string, handler func([]byt
 		return fmt.Errorf("register consumer failed: %w", err)
 	}
 
+	// Mỗi message được giữ (chưa ack) cho tới khi handler xử lý xong. Khi
+	// mất kết nối, trạng thái chuyển sang reconnecting ngay, không chờ các
+	// handler đang chạy; ack của chúng thất bại và broker giao lại message.
+	lost := func(err error) error {
+		c.setState(StateReconnecting)
+		return err
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
+		case err := <-connClosed:
+			return lost(closeReason(err))
+		case err := <-chClosed:
+			// Channel đóng nhưng connection còn sống: đóng luôn connection để
+			// lần kết nối lại bắt đầu từ trạng thái sạch
+			conn.Close()
+			return lost(closeReason(err))
 		case msg, ok := <-msgs:
 			if !ok {
-				// Channel closed, cần reconnect hoặc thoát
-				return fmt.Errorf("channel closed")
+				return lost(errConnectionLost)
 			}
-
-			err := handler(msg.Body)
-			if err != nil {
-				log.Printf("handler error: %v", err)
-				// Nack và requeue lại message
+			select {
+			case c.slots <- struct{}{}:
+			case <-ctx.Done():
 				msg.Nack(false, true)
-				continue
+				return nil
+			case err := <-connClosed:
+				return lost(closeReason(err))
+			case err := <-chClosed:
+				conn.Close()
+				return lost(closeReason(err))
 			}
+			c.inflight.Add(1)
+			go func() {
+				defer func() {
+					<-c.slots
+					c.inflight.Done()
+				}()
+				c.handle(ctx, confirmed, queue, msg, handler)
+			}()
+		}
+	}
+}
 
-			// Ack message thành công
-			if err := msg.Ack(false); err != nil {
-				log.Printf("ack error: %v", err)
-			}
+// handle runs handler for one delivery and acks, retries or dead-letters it
+// depending on the outcome. A message interrupted by shutdown is requeued
+// without counting as an attempt.
+func (c *Consumer) handle(ctx context.Context, ch *confirmedChannel, queue string, msg amqp.Delivery, handler func([]byte) error) {
+	err := handler(msg.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Printf("handler interrupted by shutdown, requeueing: %v", err)
+			msg.Nack(false, true)
+			return
 		}
+		log.Printf("handler error: %v", err)
+		c.retryOrDeadLetter(ctx, ch, queue, msg, err)
+		return
+	}
+
+	// Ack message thành công
+	if err := msg.Ack(false); err != nil {
+		log.Printf("ack error: %v", err)
+	}
+}
+
+func closeReason(err *amqp.Error) error {
+	if err == nil {
+		return errConnectionLost
+	}
+	return err
+}
+
+// jitter returns a random duration in [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// retryOrDeadLetter moves a failed message to its next delay queue, or to
+// the dead-letter queue once it is out of attempts, then acks it. The copy
+// is published as mandatory and the original is only acked once the broker
+// confirmed it; if it is nacked, returned or not confirmed in time, the
+// original is requeued so it is not lost.
+func (c *Consumer) retryOrDeadLetter(ctx context.Context, ch *confirmedChannel, queue string, msg amqp.Delivery, handlerErr error) {
+	retries := retryCount(msg.Headers)
+	headers := copyHeaders(msg.Headers)
+	headers[HeaderLastError] = truncate(handlerErr.Error(), 1024)
+
+	var exchange, routingKey string
+	if isPermanent(handlerErr) || retries+1 >= c.retry.MaxAttempts {
+		headers[HeaderOriginalQueue] = queue
+		headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+		exchange, routingKey = DeadLetterExchangeName(queue), queue
+		log.Printf("dead-lettering message from %s after %d attempts: %v", queue, retries+1, handlerErr)
+	} else {
+		delay := c.retry.Delay(retries + 1)
+		headers[HeaderRetryCount] = int32(retries + 1)
+		exchange, routingKey = "", RetryQueueName(queue, delay)
+		log.Printf("retrying message from %s in %s (retry %d of %d)", queue, delay, retries+1, c.retry.MaxAttempts-1)
+	}
+
+	if err := ch.publish(ctx, exchange, routingKey, republishing(msg, headers)); err != nil {
+		log.Printf("move failed message error: %v", err)
+		msg.Nack(false, true)
+		return
+	}
+	if err := msg.Ack(false); err != nil {
+		log.Printf("ack error: %v", err)
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
+	return s[:n]
 }
@@ -0,0 +1,293 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsumerStateTransitions(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.State(); got != StateConnected {
+		t.Fatalf("state after connecting = %s", got)
+	}
+
+	c.setState(StateReconnecting)
+	if got := c.State(); got != StateReconnecting {
+		t.Errorf("state = %s, want reconnecting", got)
+	}
+
+	c.Close()
+	if got := c.State(); got != StateClosed {
+		t.Errorf("state after Close = %s", got)
+	}
+	// A closed consumer stays closed.
+	c.setState(StateConnected)
+	if got := c.State(); got != StateClosed {
+		t.Errorf("state after Close and setState = %s", got)
+	}
+	if err := c.connect(); !errors.Is(err, ErrClosed) {
+		t.Errorf("connect after Close = %v, want ErrClosed", err)
+	}
+	if err := c.Consume(context.Background(), "jobs", func([]byte) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Consume after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestNewConsumerDialError(t *testing.T) {
+	broker := newFakeBroker()
+	broker.dialErrs = 1
+	if _, err := broker.consumer(); err == nil {
+		t.Fatal("NewConsumer succeeded without a broker")
+	}
+}
+
+func TestConsumeReconnects(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer(WithReconnectBackoff(50*time.Millisecond, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan string, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(ctx, "jobs", func(body []byte) error {
+			handled <- string(body)
+			return nil
+		})
+	}()
+
+	first := broker.lastChannel()
+	msg := broker.delivery(first, "before", nil)
+	first.deliveries <- msg
+	if got := <-handled; got != "before" {
+		t.Fatalf("handled %q", got)
+	}
+	waitFor(t, "ack", func() bool { return broker.outcome(msg.DeliveryTag) == "ack" })
+
+	// The broker goes away and refuses the first two reconnect attempts.
+	broker.mu.Lock()
+	broker.dialErrs = 2
+	broker.mu.Unlock()
+	first.conn.drop()
+
+	waitFor(t, "reconnecting state", func() bool { return c.State() == StateReconnecting })
+	waitFor(t, "new connection", func() bool { return broker.lastChannel() != first })
+	waitFor(t, "connected state", func() bool { return c.State() == StateConnected })
+	if broker.dialErrs != 0 {
+		t.Errorf("%d refused dials left", broker.dialErrs)
+	}
+
+	second := broker.lastChannel()
+	msg = broker.delivery(second, "after", nil)
+	second.deliveries <- msg
+	if got := <-handled; got != "after" {
+		t.Fatalf("handled %q after reconnecting", got)
+	}
+	waitFor(t, "ack after reconnecting", func() bool { return broker.outcome(msg.DeliveryTag) == "ack" })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Consume = %v, want nil on cancel", err)
+	}
+}
+
+func TestConsumeStopsOnClose(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer(WithReconnectBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(context.Background(), "jobs", func([]byte) error { return nil })
+	}()
+	waitFor(t, "consume", func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		_, ok := broker.declared["jobs"]
+		return ok
+	})
+
+	c.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("Consume = %v, want ErrClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consume did not return after Close")
+	}
+}
+
+func TestHandleSettlesByOutcome(t *testing.T) {
+	failure := errors.New("encode failed")
+	tests := []struct {
+		name        string
+		err         error
+		shutdown    bool
+		wantOutcome string
+		wantKey     string // where the message is moved, empty for nowhere
+	}{
+		{name: "success", wantOutcome: "ack"},
+		{name: "failure", err: failure, wantOutcome: "ack", wantKey: RetryQueueName("jobs", DefaultRetryPolicy.BaseDelay)},
+		{name: "permanent failure", err: Permanent(failure), wantOutcome: "ack", wantKey: "jobs.dlx/jobs"},
+		// The job was interrupted, not failed: it goes back to the queue
+		// without using up an attempt.
+		{name: "interrupted by shutdown", err: failure, shutdown: true, wantOutcome: "requeue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := newFakeBroker()
+			c, err := broker.consumer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch := broker.lastChannel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			msg := broker.delivery(ch, "job", nil)
+			c.handle(ctx, c.confirmed, "jobs", msg, func([]byte) error {
+				if tt.shutdown {
+					cancel()
+				}
+				return tt.err
+			})
+
+			if got := broker.outcome(msg.DeliveryTag); got != tt.wantOutcome {
+				t.Errorf("outcome = %q, want %q", got, tt.wantOutcome)
+			}
+			published := broker.messages()
+			if tt.wantKey == "" {
+				if len(published) != 0 {
+					t.Errorf("published %+v, want nothing", published)
+				}
+				return
+			}
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			key := published[0].key
+			if published[0].exchange != "" {
+				key = published[0].exchange + "/" + key
+			}
+			if key != tt.wantKey {
+				t.Errorf("moved to %q, want %q", key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestConsumeReconnectsWhileJobsDrain(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer(WithConcurrency(1), WithReconnectBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	started := make(chan string, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(ctx, "jobs", func(body []byte) error {
+			started <- string(body)
+			if string(body) == "slow" {
+				<-release
+			}
+			return nil
+		})
+	}()
+
+	first := broker.lastChannel()
+	slow := broker.delivery(first, "slow", nil)
+	first.deliveries <- slow
+	<-started
+
+	// Losing the connection is reported at once, and the consumer
+	// reconnects while the slow job is still running.
+	first.conn.drop()
+	waitFor(t, "reconnect", func() bool { return broker.lastChannel() != first && c.State() == StateConnected })
+
+	// The slow job still holds the only slot, so the next message waits.
+	second := broker.lastChannel()
+	next := broker.delivery(second, "next", nil)
+	second.deliveries <- next
+	select {
+	case body := <-started:
+		t.Fatalf("%s started while the slow job was running", body)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if body := <-started; body != "next" {
+		t.Fatalf("started %q", body)
+	}
+	waitFor(t, "acks", func() bool {
+		return broker.outcome(slow.DeliveryTag) == "ack" && broker.outcome(next.DeliveryTag) == "ack"
+	})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Consume = %v", err)
+	}
+}
+
+func TestConsumeWaitsForJobsOnShutdown(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(ctx, "jobs", func([]byte) error {
+			close(started)
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			close(finished)
+			return ctx.Err()
+		})
+	}()
+
+	ch := broker.lastChannel()
+	msg := broker.delivery(ch, "job", nil)
+	ch.deliveries <- msg
+	<-started
+	cancel()
+
+	<-done
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Consume returned before the job finished")
+	}
+	if got := broker.outcome(msg.DeliveryTag); got != "requeue" {
+		t.Errorf("outcome = %q, want requeue", got)
+	}
+}
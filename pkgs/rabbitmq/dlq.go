@@ -0,0 +1,114 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetter is a message parked in a dead-letter queue.
+type DeadLetter struct {
+	MessageID string
+	Body      []byte
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
+
+// PeekDeadLetters returns up to limit messages from the dead-letter queue of
+// queue without removing them.
+func (c *Consumer) PeekDeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	if err := declareTopology(c.channel, queue, c.retry); err != nil {
+		return nil, err
+	}
+
+	var (
+		letters []DeadLetter
+		lastTag uint64
+	)
+	for limit <= 0 || len(letters) < limit {
+		msg, ok, err := c.channel.Get(DeadLetterQueueName(queue), false)
+		if err != nil {
+			return nil, fmt.Errorf("get dead letter failed: %w", err)
+		}
+		if !ok {
+			break
+		}
+		letters = append(letters, deadLetter(msg))
+		lastTag = msg.DeliveryTag
+	}
+
+	// Trả lại toàn bộ message đã lấy về queue
+	if lastTag > 0 {
+		if err := c.channel.Nack(lastTag, true, true); err != nil {
+			return nil, fmt.Errorf("requeue dead letters failed: %w", err)
+		}
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetters moves up to limit messages from the dead-letter queue of
+// queue back to queue with a fresh retry count, and returns how many were
+// moved. A limit of 0 replays the whole queue.
+func (c *Consumer) ReplayDeadLetters(ctx context.Context, queue string, limit int) (int, error) {
+	if err := declareTopology(c.channel, queue, c.retry); err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for limit <= 0 || replayed < limit {
+		msg, ok, err := c.channel.Get(DeadLetterQueueName(queue), false)
+		if err != nil {
+			return replayed, fmt.Errorf("get dead letter failed: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		headers := copyHeaders(msg.Headers)
+		delete(headers, HeaderRetryCount)
+		delete(headers, HeaderLastError)
+		delete(headers, HeaderOriginalQueue)
+		delete(headers, HeaderFailedAt)
+
+		if err := c.confirmed.publish(ctx, "", queue, republishing(msg, headers)); err != nil {
+			msg.Nack(false, true)
+			return replayed, fmt.Errorf("replay dead letter failed: %w", err)
+		}
+		if err := msg.Ack(false); err != nil {
+			return replayed, fmt.Errorf("ack dead letter failed: %w", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// PurgeDeadLetters drops every message in the dead-letter queue of queue and
+// returns how many were removed.
+func (c *Consumer) PurgeDeadLetters(queue string) (int, error) {
+	if err := declareTopology(c.channel, queue, c.retry); err != nil {
+		return 0, err
+	}
+	n, err := c.channel.QueuePurge(DeadLetterQueueName(queue), false)
+	if err != nil {
+		return 0, fmt.Errorf("purge dead letters failed: %w", err)
+	}
+	return n, nil
+}
+
+func deadLetter(msg amqp.Delivery) DeadLetter {
+	letter := DeadLetter{
+		MessageID: msg.MessageId,
+		Body:      msg.Body,
+		Attempts:  retryCount(msg.Headers) + 1,
+	}
+	if v, ok := msg.Headers[HeaderLastError].(string); ok {
+		letter.LastError = v
+	}
+	if v, ok := msg.Headers[HeaderFailedAt].(string); ok {
+		letter.FailedAt, _ = time.Parse(time.RFC3339, v)
+	}
+	return letter
+}
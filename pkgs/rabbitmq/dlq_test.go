@@ -0,0 +1,96 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// deadLetters parks n failed messages in the dead-letter queue of jobs.
+func deadLetters(broker *fakeBroker, n int) {
+	for i := range n {
+		broker.queues["jobs.dlq"] = append(broker.queues["jobs.dlq"], amqp.Delivery{
+			MessageId: string(rune('a' + i)),
+			Body:      []byte{byte('a' + i)},
+			Headers: amqp.Table{
+				"x-trace":           "abc",
+				HeaderRetryCount:    int32(4),
+				HeaderLastError:     "encode failed",
+				HeaderOriginalQueue: "jobs",
+				HeaderFailedAt:      "2026-01-02T03:04:05Z",
+			},
+		})
+	}
+}
+
+func TestPeekDeadLetters(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetters(broker, 3)
+
+	letters, err := c.PeekDeadLetters("jobs", 2)
+	if err != nil {
+		t.Fatalf("PeekDeadLetters: %v", err)
+	}
+	if len(letters) != 2 || string(letters[0].Body) != "a" || string(letters[1].Body) != "b" {
+		t.Fatalf("letters = %+v", letters)
+	}
+	want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if letters[0].Attempts != 5 || letters[0].LastError != "encode failed" || !letters[0].FailedAt.Equal(want) {
+		t.Errorf("letter = %+v", letters[0])
+	}
+
+	// Peeking leaves the queue as it was.
+	if got := len(broker.queues["jobs.dlq"]); got != 3 {
+		t.Errorf("%d messages left, want 3", got)
+	}
+	if string(broker.queues["jobs.dlq"][0].Body) != "a" {
+		t.Errorf("queue order changed")
+	}
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetters(broker, 3)
+
+	n, err := c.ReplayDeadLetters(context.Background(), "jobs", 2)
+	if err != nil || n != 2 {
+		t.Fatalf("ReplayDeadLetters = %d, %v, want 2", n, err)
+	}
+
+	published := broker.messages()
+	if len(published) != 2 {
+		t.Fatalf("published %d messages, want 2", len(published))
+	}
+	for _, out := range published {
+		if out.exchange != "" || out.key != "jobs" {
+			t.Errorf("replayed to %q/%q, want the work queue", out.exchange, out.key)
+		}
+		// A replayed message starts over with a full set of attempts.
+		for _, h := range []string{HeaderRetryCount, HeaderLastError, HeaderOriginalQueue, HeaderFailedAt} {
+			if _, ok := out.msg.Headers[h]; ok {
+				t.Errorf("replayed message keeps %s", h)
+			}
+		}
+		if out.msg.Headers["x-trace"] != "abc" {
+			t.Errorf("replayed message lost its other headers: %v", out.msg.Headers)
+		}
+	}
+	if got := len(broker.queues["jobs.dlq"]); got != 1 {
+		t.Errorf("%d messages left in the dead-letter queue, want 1", got)
+	}
+
+	// Without a limit the rest of the queue is replayed.
+	if n, err := c.ReplayDeadLetters(context.Background(), "jobs", 0); err != nil || n != 1 {
+		t.Errorf("ReplayDeadLetters(0) = %d, %v, want 1", n, err)
+	}
+}
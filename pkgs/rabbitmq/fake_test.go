@@ -0,0 +1,299 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeBroker is an in-memory stand-in for RabbitMQ. It records what the
+// consumer publishes and how it settles deliveries, and hands out
+// connections whose channels can be closed to simulate a lost broker.
+type fakeBroker struct {
+	mu         sync.Mutex
+	queues     map[string][]amqp.Delivery // messages waiting for Get
+	declared   map[string]amqp.Table      // queue arguments
+	bindings   map[string]string          // queue to exchange
+	published  []publishedMessage
+	outcomes   map[uint64]string // delivery tag to ack, requeue or drop
+	nextTag    uint64
+	channels   []*fakeChannel
+	dialErrs   int    // number of dials left to fail
+	publishErr error  // returned by every publish when set
+	rejectWith string // "nack" or "return" to refuse every publish
+}
+
+type publishedMessage struct {
+	exchange, key string
+	msg           amqp.Publishing
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		queues:   map[string][]amqp.Delivery{},
+		declared: map[string]amqp.Table{},
+		bindings: map[string]string{},
+		outcomes: map[uint64]string{},
+	}
+}
+
+func (b *fakeBroker) dial(string) (amqpConnection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dialErrs > 0 {
+		b.dialErrs--
+		return nil, errors.New("connection refused")
+	}
+	return &fakeConn{broker: b}, nil
+}
+
+// consumer returns a Consumer connected to the broker.
+func (b *fakeBroker) consumer(opts ...ConsumerOption) (*Consumer, error) {
+	return newConsumer("amqp://fake", b.dial, opts...)
+}
+
+// lastChannel returns the channel of the newest connection.
+func (b *fakeBroker) lastChannel() *fakeChannel {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.channels) == 0 {
+		return nil
+	}
+	return b.channels[len(b.channels)-1]
+}
+
+// delivery returns a message settled through ch, with a new delivery tag.
+func (b *fakeBroker) delivery(ch *fakeChannel, body string, headers amqp.Table) amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextTag++
+	return amqp.Delivery{
+		Acknowledger: ch,
+		DeliveryTag:  b.nextTag,
+		Headers:      headers,
+		Body:         []byte(body),
+		MessageId:    body,
+	}
+}
+
+func (b *fakeBroker) outcome(tag uint64) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.outcomes[tag]
+}
+
+func (b *fakeBroker) messages() []publishedMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]publishedMessage(nil), b.published...)
+}
+
+type fakeConn struct {
+	broker *fakeBroker
+	mu     sync.Mutex
+	notify []chan *amqp.Error
+	closed bool
+}
+
+func (c *fakeConn) Channel() (amqpChannel, error) {
+	ch := &fakeChannel{broker: c.broker, conn: c, deliveries: make(chan amqp.Delivery)}
+	c.broker.mu.Lock()
+	c.broker.channels = append(c.broker.channels, ch)
+	c.broker.mu.Unlock()
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(ch chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Like amqp, a closed connection closes the channel at once.
+	if c.closed {
+		close(ch)
+		return ch
+	}
+	c.notify = append(c.notify, ch)
+	return ch
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		for _, ch := range c.notify {
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// drop simulates the broker closing the connection.
+func (c *fakeConn) drop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		for _, ch := range c.notify {
+			ch <- &amqp.Error{Code: amqp.ConnectionForced, Reason: "broker shutdown"}
+		}
+	}
+}
+
+type fakeChannel struct {
+	broker     *fakeBroker
+	conn       *fakeConn
+	deliveries chan amqp.Delivery
+	unacked    []amqp.Delivery // taken with Get and not settled yet
+	confirming bool
+	seq        uint64
+	confirms   []chan amqp.Confirmation
+	returns    []chan amqp.Return
+}
+
+func (ch *fakeChannel) Qos(int, int, bool) error { return nil }
+
+func (ch *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	ch.broker.declared[name] = args
+	return amqp.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp.Table) error {
+	return nil
+}
+
+func (ch *fakeChannel) QueueBind(name, _, exchange string, _ bool, _ amqp.Table) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	ch.broker.bindings[name] = exchange
+	return nil
+}
+
+func (ch *fakeChannel) QueuePurge(name string, _ bool) (int, error) {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	n := len(ch.broker.queues[name])
+	delete(ch.broker.queues, name)
+	return n, nil
+}
+
+func (ch *fakeChannel) Consume(string, string, bool, bool, bool, bool, amqp.Table) (<-chan amqp.Delivery, error) {
+	return ch.deliveries, nil
+}
+
+func (ch *fakeChannel) Get(queue string, _ bool) (amqp.Delivery, bool, error) {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	msgs := ch.broker.queues[queue]
+	if len(msgs) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	msg := msgs[0]
+	ch.broker.queues[queue] = msgs[1:]
+	ch.broker.nextTag++
+	msg.DeliveryTag = ch.broker.nextTag
+	msg.RoutingKey = queue
+	msg.Acknowledger = ch
+	ch.unacked = append(ch.unacked, msg)
+	return msg, true, nil
+}
+
+// PublishWithContext records the message, or refuses it as set by
+// rejectWith. Like RabbitMQ, a return comes before the confirm.
+func (ch *fakeChannel) PublishWithContext(_ context.Context, exchange, key string, mandatory, _ bool, msg amqp.Publishing) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.broker.publishErr != nil {
+		return ch.broker.publishErr
+	}
+	reject := ch.broker.rejectWith
+	if reject == "" {
+		ch.broker.published = append(ch.broker.published, publishedMessage{exchange, key, msg})
+	}
+	if reject == "return" && mandatory {
+		for _, c := range ch.returns {
+			c <- amqp.Return{Exchange: exchange, RoutingKey: key, MessageId: msg.MessageId}
+		}
+	}
+	if ch.confirming {
+		ch.seq++
+		for _, c := range ch.confirms {
+			c <- amqp.Confirmation{DeliveryTag: ch.seq, Ack: reject != "nack"}
+		}
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Confirm(bool) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	ch.confirming = true
+	return nil
+}
+
+func (ch *fakeChannel) NotifyPublish(c chan amqp.Confirmation) chan amqp.Confirmation {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	ch.confirms = append(ch.confirms, c)
+	return c
+}
+
+func (ch *fakeChannel) NotifyReturn(c chan amqp.Return) chan amqp.Return {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	ch.returns = append(ch.returns, c)
+	return c
+}
+
+func (ch *fakeChannel) Ack(tag uint64, multiple bool) error {
+	return ch.settle(tag, multiple, "ack")
+}
+
+func (ch *fakeChannel) Nack(tag uint64, multiple, requeue bool) error {
+	if requeue {
+		return ch.settle(tag, multiple, "requeue")
+	}
+	return ch.settle(tag, multiple, "drop")
+}
+
+func (ch *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return ch.Nack(tag, false, requeue)
+}
+
+// settle records the outcome of a delivery. Requeued messages taken with
+// Get go back to the front of their queue.
+func (ch *fakeChannel) settle(tag uint64, multiple bool, outcome string) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+
+	var requeue []amqp.Delivery
+	kept := ch.unacked[:0]
+	for _, msg := range ch.unacked {
+		if msg.DeliveryTag == tag || multiple && msg.DeliveryTag < tag {
+			ch.broker.outcomes[msg.DeliveryTag] = outcome
+			if outcome == "requeue" {
+				requeue = append(requeue, msg)
+			}
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	ch.unacked = kept
+	for i := len(requeue) - 1; i >= 0; i-- {
+		q := requeue[i].RoutingKey
+		ch.broker.queues[q] = append([]amqp.Delivery{requeue[i]}, ch.broker.queues[q]...)
+	}
+	ch.broker.outcomes[tag] = outcome
+	return nil
+}
+
+func (ch *fakeChannel) NotifyClose(c chan *amqp.Error) chan *amqp.Error {
+	return ch.conn.NotifyClose(c)
+}
+
+func (ch *fakeChannel) Close() error {
+	return nil
+}
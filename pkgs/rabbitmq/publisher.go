@@ -1 +1,125 @@
 package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrNotConfirmed is returned by Publish when the broker nacks a message,
+// meaning it was not stored and must be published again.
+var ErrNotConfirmed = errors.New("rabbitmq: message not confirmed by broker")
+
+// confirmTimeout bounds how long Publish waits for the broker to confirm a
+// message.
+const confirmTimeout = 10 * time.Second
+
+type Publisher struct {
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	url     string
+}
+
+func NewPublisher(url string) (*Publisher, error) {
+	p := &Publisher{url: url}
+	if err := p.connect(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Publisher) connect() error {
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	// Bật publisher confirms để biết broker đã lưu message hay chưa
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("enable publisher confirms failed: %w", err)
+	}
+	p.conn = conn
+	p.channel = ch
+	return nil
+}
+
+func (p *Publisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.channel != nil {
+		p.channel.Close()
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+// Publish gửi message lên queue với cơ chế tái kết nối nếu connection/chanel bị đóng
+//
+// Publish chỉ trả về nil khi broker đã confirm message; message bị nack hoặc
+// không được confirm kịp thời trả về lỗi và cần được gửi lại.
+func (p *Publisher) Publish(queue string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Nếu channel hoặc conn đóng thì reconnect
+	if p.conn.IsClosed() || p.channel.IsClosed() {
+		if p.conn != nil {
+			p.conn.Close()
+		}
+		if err := p.connect(); err != nil {
+			return fmt.Errorf("reconnect failed: %w", err)
+		}
+	}
+
+	_, err := p.channel.QueueDeclare(
+		queue,
+		true,  // durable
+		false, // autoDelete
+		false, // exclusive
+		false, // noWait
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	confirm, err := p.channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		"",    // default exchange
+		queue, // routing key = queue name
+		false, // mandatory
+		false, // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			Body:         data,
+			DeliveryMode: amqp.Persistent, // tin nhắn bền vững
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("wait for confirm failed: %w", err)
+	}
+	if !acked {
+		return ErrNotConfirmed
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrReturned is returned when the broker cannot route a mandatory message
+// to any queue, e.g. because its delay queue is missing.
+var ErrReturned = errors.New("rabbitmq: message returned as unroutable")
+
+// confirmedChannel publishes on a consumer channel in confirm mode and
+// waits until the broker has stored each message, so a delivery is only
+// acked once its retry or dead-letter copy is safe.
+//
+// Publishes are serialized: the broker returns an unroutable message right
+// before confirming it, which only tells which publish was returned while
+// a single one is outstanding.
+type confirmedChannel struct {
+	mu       sync.Mutex
+	ch       amqpChannel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	// seq is the delivery tag of the last publish on the channel
+	seq uint64
+}
+
+// newConfirmedChannel puts ch in confirm mode.
+func newConfirmedChannel(ch amqpChannel) (*confirmedChannel, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("enable publisher confirms failed: %w", err)
+	}
+	return &confirmedChannel{
+		ch:       ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:  ch.NotifyReturn(make(chan amqp.Return, 1)),
+	}, nil
+}
+
+// publish sends a mandatory message and returns nil only once the broker
+// has routed and confirmed it. A nack, a return or no confirm within
+// confirmTimeout are errors and the message must be kept.
+func (c *confirmedChannel) publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Returns of earlier publishes that timed out are stale
+	c.drainReturns()
+
+	if err := c.ch.PublishWithContext(ctx, exchange, key, true, false, msg); err != nil {
+		return err
+	}
+	c.seq++
+
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+	for {
+		select {
+		case confirm, ok := <-c.confirms:
+			if !ok {
+				return errConnectionLost
+			}
+			// Confirms of earlier publishes that timed out
+			if confirm.DeliveryTag < c.seq {
+				continue
+			}
+			if !confirm.Ack {
+				return ErrNotConfirmed
+			}
+			if c.drainReturns() {
+				return ErrReturned
+			}
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("wait for confirm failed: %w", ctx.Err())
+		}
+	}
+}
+
+// drainReturns empties the returns and reports whether there were any.
+func (c *confirmedChannel) drainReturns() bool {
+	returned := false
+	for {
+		select {
+		case _, ok := <-c.returns:
+			if !ok {
+				return returned
+			}
+			returned = true
+		default:
+			return returned
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers set on retried and dead-lettered messages.
+const (
+	HeaderRetryCount    = "x-retry-count"
+	HeaderLastError     = "x-last-error"
+	HeaderOriginalQueue = "x-original-queue"
+	HeaderFailedAt      = "x-failed-at"
+)
+
+// RetryPolicy bounds how often a failing message is redelivered. Attempt n
+// (n >= 1 retries so far) waits BaseDelay * 2^(n-1), capped at MaxDelay, in
+// a delay queue before returning to the work queue.
+type RetryPolicy struct {
+	MaxAttempts int           // Total deliveries including the first, before dead-lettering
+	BaseDelay   time.Duration // Delay before the first retry
+	MaxDelay    time.Duration // Upper bound of the delay
+}
+
+// DefaultRetryPolicy is used by consumers created without WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   5 * time.Second,
+	MaxDelay:    5 * time.Minute,
+}
+
+// Delay returns how long the message waits before retry number retry.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	return p
+}
+
+// PermanentError marks a handler error that retrying cannot fix, such as a
+// malformed message. The message goes to the dead-letter queue at once.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so the consumer dead-letters the message without
+// retrying it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func isPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryQueueName returns the delay queue holding messages of queue for
+// delay. The delay is part of the name because a queue's TTL cannot change
+// once declared.
+func RetryQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+// DeadLetterExchangeName returns the exchange failed messages of queue are
+// published to.
+func DeadLetterExchangeName(queue string) string {
+	return queue + ".dlx"
+}
+
+// DeadLetterQueueName returns the queue holding failed messages of queue.
+func DeadLetterQueueName(queue string) string {
+	return queue + ".dlq"
+}
+
+// declareTopology declares the work queue, its delay queues, and its
+// dead-letter exchange and queue. The work queue keeps the arguments used
+// by Publisher so both sides can declare it.
+func declareTopology(ch amqpChannel, queue string, policy RetryPolicy) error {
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare queue failed: %w", err)
+	}
+
+	// Expired messages in a delay queue are dead-lettered back to the work
+	// queue through the default exchange.
+	for retry := 1; retry < policy.MaxAttempts; retry++ {
+		delay := policy.Delay(retry)
+		_, err := ch.QueueDeclare(RetryQueueName(queue, delay), true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		})
+		if err != nil {
+			return fmt.Errorf("declare retry queue failed: %w", err)
+		}
+	}
+
+	dlx := DeadLetterExchangeName(queue)
+	if err := ch.ExchangeDeclare(dlx, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare dead-letter exchange failed: %w", err)
+	}
+	dlq := DeadLetterQueueName(queue)
+	if _, err := ch.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare dead-letter queue failed: %w", err)
+	}
+	if err := ch.QueueBind(dlq, queue, dlx, false, nil); err != nil {
+		return fmt.Errorf("bind dead-letter queue failed: %w", err)
+	}
+	return nil
+}
+
+// retryCount reads the x-retry-count header of a delivery.
+func retryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// copyHeaders returns a copy of headers that can be modified.
+func copyHeaders(headers amqp.Table) amqp.Table {
+	out := amqp.Table{}
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
+
+// republishing returns a persistent copy of a delivery with headers.
+func republishing(msg amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType:  msg.ContentType,
+		Headers:      headers,
+		Body:         msg.Body,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.MessageId,
+		Timestamp:    msg.Timestamp,
+	}
+}
@@ -0,0 +1,177 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 6, BaseDelay: 5 * time.Second, MaxDelay: 30 * time.Second}
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, w := range want {
+		if got := p.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestDeclareTopology(t *testing.T) {
+	broker := newFakeBroker()
+	c, err := broker.consumer(WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := declareTopology(c.channel, "jobs", c.retry); err != nil {
+		t.Fatalf("declareTopology: %v", err)
+	}
+
+	// One delay queue per retry, each expiring back into the work queue.
+	for retry, delay := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second} {
+		args, ok := broker.declared[RetryQueueName("jobs", delay)]
+		if !ok {
+			t.Fatalf("delay queue of retry %d not declared", retry)
+		}
+		if args["x-message-ttl"] != delay.Milliseconds() || args["x-dead-letter-exchange"] != "" || args["x-dead-letter-routing-key"] != "jobs" {
+			t.Errorf("delay queue %d args = %v", retry, args)
+		}
+	}
+	if len(broker.declared) != 4 {
+		t.Errorf("declared %d queues, want work, 2 delay and dead-letter queues", len(broker.declared))
+	}
+	if broker.bindings["jobs.dlq"] != "jobs.dlx" {
+		t.Errorf("dead-letter queue bound to %q", broker.bindings["jobs.dlq"])
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	tests := []struct {
+		headers amqp.Table
+		want    int
+	}{
+		{nil, 0},
+		{amqp.Table{HeaderRetryCount: int32(2)}, 2},
+		{amqp.Table{HeaderRetryCount: int64(3)}, 3},
+		{amqp.Table{HeaderRetryCount: uint8(4)}, 4},
+		{amqp.Table{HeaderRetryCount: float64(1)}, 1},
+		{amqp.Table{HeaderRetryCount: "2"}, 0},
+	}
+	for _, tt := range tests {
+		if got := retryCount(tt.headers); got != tt.want {
+			t.Errorf("retryCount(%v) = %d, want %d", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestRetryOrDeadLetter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, MaxDelay: time.Minute}
+	failure := errors.New("encode failed")
+
+	tests := []struct {
+		name        string
+		retries     int // retries before this delivery
+		err         error
+		wantKey     string
+		wantRetries int32
+		deadLetter  bool
+	}{
+		{name: "first failure", retries: 0, err: failure, wantKey: RetryQueueName("jobs", time.Second), wantRetries: 1},
+		{name: "backs off", retries: 2, err: failure, wantKey: RetryQueueName("jobs", 4*time.Second), wantRetries: 3},
+		{name: "out of attempts", retries: 3, err: failure, deadLetter: true},
+		{name: "permanent", retries: 0, err: Permanent(failure), deadLetter: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := newFakeBroker()
+			c, err := broker.consumer(WithRetryPolicy(policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch := broker.lastChannel()
+
+			headers := amqp.Table{"x-trace": "abc"}
+			if tt.retries > 0 {
+				headers[HeaderRetryCount] = int32(tt.retries)
+			}
+			msg := broker.delivery(ch, "job", headers)
+			c.retryOrDeadLetter(context.Background(), c.confirmed, "jobs", msg, tt.err)
+
+			if got := broker.outcome(msg.DeliveryTag); got != "ack" {
+				t.Errorf("delivery outcome = %q, want ack once moved", got)
+			}
+			published := broker.messages()
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			out := published[0]
+			if string(out.msg.Body) != "job" || out.msg.Headers["x-trace"] != "abc" || out.msg.DeliveryMode != amqp.Persistent {
+				t.Errorf("republished %+v", out.msg)
+			}
+			if out.msg.Headers[HeaderLastError] != "encode failed" {
+				t.Errorf("last error = %v", out.msg.Headers[HeaderLastError])
+			}
+
+			if tt.deadLetter {
+				if out.exchange != "jobs.dlx" || out.key != "jobs" {
+					t.Errorf("published to %q/%q, want the dead-letter exchange", out.exchange, out.key)
+				}
+				if out.msg.Headers[HeaderOriginalQueue] != "jobs" || out.msg.Headers[HeaderFailedAt] == nil {
+					t.Errorf("dead letter headers = %v", out.msg.Headers)
+				}
+				return
+			}
+			if out.exchange != "" || out.key != tt.wantKey {
+				t.Errorf("published to %q/%q, want delay queue %q", out.exchange, out.key, tt.wantKey)
+			}
+			if out.msg.Headers[HeaderRetryCount] != tt.wantRetries {
+				t.Errorf("retry count = %v, want %d", out.msg.Headers[HeaderRetryCount], tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestRetryOrDeadLetterPublishFails(t *testing.T) {
+	// A copy the broker does not take, e.g. because its delay queue is
+	// missing, must leave the message in the work queue.
+	tests := []struct {
+		name       string
+		publishErr error
+		reject     string
+		err        error
+	}{
+		{name: "channel closed", publishErr: errors.New("channel closed"), err: errors.New("encode failed")},
+		{name: "retry nacked", reject: "nack", err: errors.New("encode failed")},
+		{name: "retry returned", reject: "return", err: errors.New("encode failed")},
+		{name: "dead letter nacked", reject: "nack", err: Permanent(errors.New("bad input"))},
+		{name: "dead letter returned", reject: "return", err: Permanent(errors.New("bad input"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := newFakeBroker()
+			c, err := broker.consumer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			broker.publishErr = tt.publishErr
+			broker.rejectWith = tt.reject
+			ch := broker.lastChannel()
+
+			msg := broker.delivery(ch, "job", nil)
+			c.retryOrDeadLetter(context.Background(), c.confirmed, "jobs", msg, tt.err)
+			if got := broker.outcome(msg.DeliveryTag); got != "requeue" {
+				t.Errorf("delivery outcome = %q, want requeue", got)
+			}
+
+			// Once the broker takes copies again, the next one is moved
+			broker.publishErr, broker.rejectWith = nil, ""
+			msg = broker.delivery(ch, "job", nil)
+			c.retryOrDeadLetter(context.Background(), c.confirmed, "jobs", msg, tt.err)
+			if got := broker.outcome(msg.DeliveryTag); got != "ack" {
+				t.Errorf("delivery outcome after recovery = %q, want ack", got)
+			}
+		})
+	}
+}
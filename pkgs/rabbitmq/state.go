@@ -0,0 +1,33 @@
+package rabbitmq
+
+import "errors"
+
+// State is the connection state of a Consumer.
+type State int
+
+const (
+	StateConnecting State = iota
+	StateConnected
+	StateReconnecting
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrClosed is returned by Consume once Close has been called.
+var ErrClosed = errors.New("rabbitmq: consumer closed")
+
+var errConnectionLost = errors.New("rabbitmq: connection lost")
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin)
+
+package scratch
+
+// freeSpace is not supported on this platform; the free space check is
+// skipped.
+func freeSpace(path string) (int64, bool) {
+	return 0, false
+}
+
+// processAlive cannot be determined on this platform, so directories are
+// only swept once they are stale.
+func processAlive(pid int) bool {
+	return true
+}
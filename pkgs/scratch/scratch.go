@@ -0,0 +1,292 @@
+// Package scratch manages per-job temporary working directories under a
+// common root, with a disk-space quota and cleanup of directories left
+// behind by crashed processes.
+package scratch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQuotaExceeded is returned when the scratch space in use, or about
+	// to be reserved, exceeds the manager's quota.
+	ErrQuotaExceeded = errors.New("scratch quota exceeded")
+	// ErrInsufficientSpace is returned when the filesystem holding the root
+	// does not have enough free space for a new directory.
+	ErrInsufficientSpace = errors.New("insufficient free disk space")
+)
+
+// DefaultStaleAfter is the age after which Sweep removes a directory even if
+// its owner cannot be identified as dead.
+const DefaultStaleAfter = 24 * time.Hour
+
+// ownerFile marks a directory with the process that created it.
+const ownerFile = ".owner"
+
+type owner struct {
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Nonce     string    `json:"nonce"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// processNonce tells this process apart from earlier ones with the same
+// host and PID, as containers restart with the same hostname as PID 1.
+var processNonce = newNonce()
+
+func newNonce() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Manager creates scratch directories under a root directory.
+type Manager struct {
+	root       string
+	quota      int64 // bytes, 0 for unlimited
+	minFree    int64 // bytes kept free on the filesystem
+	staleAfter time.Duration
+
+	mu       sync.Mutex
+	reserved int64
+}
+
+type Option func(*Manager)
+
+// WithQuota limits the total size of the scratch directories in bytes.
+func WithQuota(bytes int64) Option {
+	return func(m *Manager) {
+		m.quota = bytes
+	}
+}
+
+// WithMinFreeSpace refuses new directories when fewer than bytes would stay
+// free on the filesystem.
+func WithMinFreeSpace(bytes int64) Option {
+	return func(m *Manager) {
+		m.minFree = bytes
+	}
+}
+
+// WithStaleAfter sets the age after which Sweep removes directories of any
+// owner.
+func WithStaleAfter(d time.Duration) Option {
+	return func(m *Manager) {
+		if d > 0 {
+			m.staleAfter = d
+		}
+	}
+}
+
+// New returns a Manager for root. An empty root uses a directory under the
+// system temp directory.
+func New(root string, opts ...Option) *Manager {
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "media-svc")
+	}
+	// Tools such as ffmpeg run inside job directories, so paths handed out
+	// must not depend on the working directory.
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	m := &Manager{
+		root:       root,
+		staleAfter: DefaultStaleAfter,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Root returns the directory scratch directories are created in.
+func (m *Manager) Root() string {
+	return m.root
+}
+
+// Create makes a new uniquely named directory for one job attempt and
+// reserves reserve bytes of the quota for it. The caller must Close the
+// directory on every exit path, typically with defer.
+func (m *Manager) Create(prefix string, reserve int64) (*Dir, error) {
+	if err := os.MkdirAll(m.root, 0755); err != nil {
+		return nil, fmt.Errorf("create scratch root: %w", err)
+	}
+
+	if free, ok := freeSpace(m.root); ok && free-reserve < m.minFree {
+		return nil, fmt.Errorf("%w: %d bytes free, %d needed", ErrInsufficientSpace, free, reserve+m.minFree)
+	}
+
+	m.mu.Lock()
+	if m.quota > 0 && m.reserved+reserve > m.quota {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d of %d bytes reserved, %d requested", ErrQuotaExceeded, m.reserved, m.quota, reserve)
+	}
+	m.reserved += reserve
+	m.mu.Unlock()
+
+	path, err := os.MkdirTemp(m.root, prefix+"-*")
+	if err != nil {
+		m.release(reserve)
+		return nil, fmt.Errorf("create scratch dir: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	data, _ := json.Marshal(owner{Host: host, PID: os.Getpid(), Nonce: processNonce, CreatedAt: time.Now().UTC()})
+	if err := os.WriteFile(filepath.Join(path, ownerFile), data, 0644); err != nil {
+		os.RemoveAll(path)
+		m.release(reserve)
+		return nil, fmt.Errorf("write scratch owner: %w", err)
+	}
+
+	return &Dir{m: m, path: path, reserved: reserve}, nil
+}
+
+// Usage returns the bytes currently used under the root.
+func (m *Manager) Usage() (int64, error) {
+	return dirSize(m.root)
+}
+
+// CheckQuota returns ErrQuotaExceeded when the bytes used under the root
+// exceed the quota.
+func (m *Manager) CheckQuota() error {
+	if m.quota <= 0 {
+		return nil
+	}
+	used, err := m.Usage()
+	if err != nil {
+		return err
+	}
+	if used > m.quota {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, used, m.quota)
+	}
+	return nil
+}
+
+// Sweep removes directories left behind by processes that exited without
+// cleaning up: those owned by a dead process on this host, and any older
+// than the stale age. It is meant to run at startup, before new jobs.
+func (m *Manager) Sweep() (int, error) {
+	entries, err := os.ReadDir(m.root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read scratch root: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(m.root, entry.Name())
+		if !m.isStale(path, host) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			log.Printf("scratch: remove stale dir %s: %v", path, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (m *Manager) isStale(path, host string) bool {
+	created := time.Time{}
+	if data, err := os.ReadFile(filepath.Join(path, ownerFile)); err == nil {
+		var o owner
+		if json.Unmarshal(data, &o) == nil {
+			if o.Host == host && o.PID == os.Getpid() {
+				// Ours, unless left by an earlier process with our PID.
+				return o.Nonce != processNonce
+			}
+			if o.Host == host && !processAlive(o.PID) {
+				return true
+			}
+			created = o.CreatedAt
+		}
+	}
+	if created.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		created = info.ModTime()
+	}
+	return time.Since(created) > m.staleAfter
+}
+
+func (m *Manager) release(bytes int64) {
+	m.mu.Lock()
+	m.reserved -= bytes
+	m.mu.Unlock()
+}
+
+// Dir is a scratch directory owned by one job attempt.
+type Dir struct {
+	m        *Manager
+	path     string
+	reserved int64
+	once     sync.Once
+	err      error
+}
+
+// Path returns the directory path.
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// Join joins elem onto the directory path.
+func (d *Dir) Join(elem ...string) string {
+	return filepath.Join(append([]string{d.path}, elem...)...)
+}
+
+// CheckQuota returns ErrQuotaExceeded when the scratch space in use exceeds
+// the manager's quota.
+func (d *Dir) CheckQuota() error {
+	return d.m.CheckQuota()
+}
+
+// Close removes the directory and releases its reservation. It is safe to
+// call more than once.
+func (d *Dir) Close() error {
+	d.once.Do(func() {
+		d.err = os.RemoveAll(d.path)
+		d.m.release(d.reserved)
+	})
+	return d.err
+}
+
+func dirSize(root string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(root, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories of other jobs may disappear during the walk.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,158 @@
+package scratch
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSweepRestartedProcess(t *testing.T) {
+	m := New(t.TempDir())
+	host, _ := os.Hostname()
+
+	// A directory of an earlier process that ran with our host and PID, as
+	// after a container restart.
+	earlier := filepath.Join(m.Root(), "job-earlier")
+	if err := os.MkdirAll(earlier, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(owner{Host: host, PID: os.Getpid(), Nonce: "earlier", CreatedAt: time.Now().UTC()})
+	if err := os.WriteFile(filepath.Join(earlier, ownerFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := m.Create("job", 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer dir.Close()
+
+	removed, err := m.Sweep()
+	if err != nil || removed != 1 {
+		t.Fatalf("Sweep = %d, %v, want 1 removed", removed, err)
+	}
+	if _, err := os.Stat(earlier); !os.IsNotExist(err) {
+		t.Errorf("directory of the earlier process kept")
+	}
+	if _, err := os.Stat(dir.Path()); err != nil {
+		t.Errorf("own directory removed: %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		reserves []int64 // reserved by earlier, still open directories
+		reserve  int64
+		want     error
+	}{
+		{name: "unlimited", reserves: []int64{1 << 20}, reserve: 1 << 20},
+		{name: "within quota", opts: []Option{WithQuota(100)}, reserves: []int64{40}, reserve: 60},
+		{name: "over quota", opts: []Option{WithQuota(100)}, reserves: []int64{40}, reserve: 61, want: ErrQuotaExceeded},
+		{name: "alone over quota", opts: []Option{WithQuota(100)}, reserve: 101, want: ErrQuotaExceeded},
+		{name: "free space", opts: []Option{WithMinFreeSpace(1)}, reserve: 1},
+		{name: "too little free space", opts: []Option{WithMinFreeSpace(1 << 62)}, reserve: 1, want: ErrInsufficientSpace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(t.TempDir(), tt.opts...)
+			for _, reserve := range tt.reserves {
+				dir, err := m.Create("job", reserve)
+				if err != nil {
+					t.Fatalf("Create(%d): %v", reserve, err)
+				}
+				defer dir.Close()
+			}
+
+			dir, err := m.Create("job", tt.reserve)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Create(%d) error = %v, want %v", tt.reserve, err, tt.want)
+			}
+			if err != nil {
+				// A refused directory is neither created nor reserved
+				if entries, _ := os.ReadDir(m.Root()); len(entries) != len(tt.reserves) {
+					t.Errorf("%d directories, want %d", len(entries), len(tt.reserves))
+				}
+				return
+			}
+			defer dir.Close()
+			if _, err := os.Stat(filepath.Join(dir.Path(), ownerFile)); err != nil {
+				t.Errorf("owner file: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	m := New(t.TempDir(), WithQuota(100))
+	dir, err := m.Create("job", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	if err := dir.CheckQuota(); err != nil {
+		t.Errorf("CheckQuota before writing: %v", err)
+	}
+	// A job writing more than it reserved is caught by its checks
+	if err := os.WriteFile(dir.Join("out.ts"), make([]byte, 200), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.CheckQuota(); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckQuota = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestDirClose(t *testing.T) {
+	tests := []struct {
+		name string
+		job  func(dir *Dir)
+	}{
+		{name: "close", job: func(dir *Dir) {
+			dir.Close()
+		}},
+		{name: "close twice", job: func(dir *Dir) {
+			dir.Close()
+			if err := dir.Close(); err != nil {
+				t.Errorf("second Close: %v", err)
+			}
+		}},
+		{name: "panic", job: func(dir *Dir) {
+			defer func() { recover() }()
+			defer dir.Close()
+			panic("encoder crashed")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(t.TempDir(), WithQuota(100))
+			dir, err := m.Create("job", 80)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dir.Join("seg_001.m4s"), []byte("segment"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.job(dir)
+
+			if _, err := os.Stat(dir.Path()); !os.IsNotExist(err) {
+				t.Errorf("directory kept: %v", err)
+			}
+			// The reservation is released exactly once: another 80 bytes
+			// fit, but not 80 more on top of them.
+			next, err := m.Create("job", 80)
+			if err != nil {
+				t.Fatalf("Create after Close: %v", err)
+			}
+			defer next.Close()
+			if _, err := m.Create("job", 40); !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("Create over quota after Close = %v, want ErrQuotaExceeded", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package transcoder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Video codecs a Rendition can be encoded with.
+const (
+	CodecH264 = "h264"
+	CodecHEVC = "hevc"
+	CodecVP9  = "vp9"
+	CodecAV1  = "av1"
+)
+
+// Encoders used for the video codecs.
+const (
+	EncoderX264   = "libx264"
+	EncoderX265   = "libx265"
+	EncoderVPX    = "libvpx-vp9"
+	EncoderSVTAV1 = "libsvtav1"
+	EncoderAOMAV1 = "libaom-av1"
+)
+
+// audioCodecAAC is the RFC 6381 string of the AAC-LC audio tracks.
+const audioCodecAAC = "mp4a.40.2"
+
+// codecDefaults holds the encoder and tuning used when a Rendition does not
+// override them.
+var codecDefaults = map[string]struct {
+	encoder string
+	preset  string
+	profile string
+}{
+	CodecH264: {EncoderX264, "veryfast", "main"},
+	CodecHEVC: {EncoderX265, "fast", "main"},
+	CodecVP9:  {EncoderVPX, "", "0"},
+	CodecAV1:  {EncoderSVTAV1, "8", "main"},
+}
+
+// NormalizeCodec maps codec aliases to one of the Codec constants. An empty
+// codec is H.264.
+func NormalizeCodec(codec string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(codec)) {
+	case "", "h264", "avc", "x264":
+		return CodecH264, nil
+	case "hevc", "h265", "x265":
+		return CodecHEVC, nil
+	case "vp9":
+		return CodecVP9, nil
+	case "av1":
+		return CodecAV1, nil
+	default:
+		return "", fmt.Errorf("unsupported video codec %q", codec)
+	}
+}
+
+// resolveRendition fills in the codec, encoder, preset, profile and level
+// defaults of r, so the encoder arguments and the advertised CODECS string
+// are derived from the same values.
+func resolveRendition(r Rendition) (Rendition, error) {
+	codec, err := NormalizeCodec(r.VideoCodec)
+	if err != nil {
+		return r, err
+	}
+	defaults := codecDefaults[codec]
+
+	r.VideoCodec = codec
+	if r.Encoder == "" {
+		r.Encoder = defaults.encoder
+	}
+	if r.Preset == "" {
+		r.Preset = defaults.preset
+	}
+	if r.Profile == "" {
+		r.Profile = defaults.profile
+	}
+	if r.Level == "" {
+		r.Level = defaultLevel(codec, r.Width, r.Height)
+	}
+	return r, nil
+}
+
+// defaultLevel picks the lowest common level that fits the resolution.
+func defaultLevel(codec string, width, height int) string {
+	pixels := width * height
+	if codec == CodecVP9 {
+		// Maximum luma picture sizes of the VP9 levels.
+		switch {
+		case pixels <= 245760:
+			return "2.1"
+		case pixels <= 552960:
+			return "3.0"
+		case pixels <= 983040:
+			return "3.1"
+		case pixels <= 2228224:
+			return "4.0"
+		case pixels <= 8912896:
+			return "5.0"
+		default:
+			return "6.0"
+		}
+	}
+	if codec == CodecAV1 {
+		switch {
+		case pixels <= 640*360:
+			return "2.1"
+		case pixels <= 854*480:
+			return "3.0"
+		case pixels <= 1280*720:
+			return "3.1"
+		case pixels <= 1920*1080:
+			return "4.0"
+		case pixels <= 3840*2160:
+			return "5.0"
+		default:
+			return "6.0"
+		}
+	}
+	switch {
+	case pixels <= 640*360:
+		return "3.0"
+	case pixels <= 1280*720:
+		return "3.1"
+	case pixels <= 1920*1080:
+		return "4.0"
+	case pixels <= 2560*1440:
+		return "5.0"
+	default:
+		return "5.1"
+	}
+}
+
+// highBitDepth reports whether the rendition's profile encodes 10-bit
+// samples: H.264 high10, HEVC and AV1 main10 and VP9 profiles 2 and 3.
+func (r Rendition) highBitDepth() bool {
+	switch strings.ToLower(r.Profile) {
+	case "high10", "main10":
+		return r.VideoCodec != CodecVP9
+	case "2", "3":
+		return r.VideoCodec == CodecVP9
+	}
+	return false
+}
+
+// pixelFormat returns the output pixel format, 4:2:0 at the bit depth of
+// the profile.
+func (r Rendition) pixelFormat() string {
+	if r.highBitDepth() {
+		return "yuv420p10le"
+	}
+	return "yuv420p"
+}
+
+// bitDepth returns the sample bit depth of the output pixel format.
+func (r Rendition) bitDepth() int {
+	if strings.HasSuffix(r.pixelFormat(), "10le") {
+		return 10
+	}
+	return 8
+}
+
+// videoEncoderArgs returns the per-stream encoder arguments of output video
+// stream i.
+func videoEncoderArgs(i int, r Rendition) []string {
+	s := fmt.Sprint(i)
+	args := []string{
+		"-c:v:" + s, r.Encoder,
+		"-b:v:" + s, r.VideoBitrate,
+		"-pix_fmt:v:" + s, r.pixelFormat(),
+	}
+	if r.MaxRate != "" {
+		args = append(args, "-maxrate:v:"+s, r.MaxRate)
+	}
+	if r.BufSize != "" {
+		args = append(args, "-bufsize:v:"+s, r.BufSize)
+	}
+
+	switch r.Encoder {
+	case EncoderX264:
+		args = append(args,
+			"-preset:v:"+s, r.Preset,
+			"-profile:v:"+s, r.Profile,
+			"-level:v:"+s, r.Level,
+			"-sc_threshold:v:"+s, "0",
+		)
+	case EncoderX265:
+		// Tag as hvc1 so Apple players accept the fMP4 segments.
+		args = append(args,
+			"-preset:v:"+s, r.Preset,
+			"-profile:v:"+s, r.Profile,
+			"-tag:v:"+s, "hvc1",
+			"-x265-params:v:"+s, "scenecut=0:open-gop=0:level-idc="+r.Level,
+		)
+	case EncoderVPX:
+		args = append(args,
+			"-profile:v:"+s, r.Profile,
+			"-deadline:v:"+s, "good",
+			"-cpu-used:v:"+s, "4",
+			"-row-mt:v:"+s, "1",
+		)
+	case EncoderSVTAV1:
+		args = append(args,
+			"-preset:v:"+s, r.Preset,
+			"-svtav1-params:v:"+s, "scd=0",
+		)
+	case EncoderAOMAV1:
+		args = append(args,
+			"-cpu-used:v:"+s, "6",
+			"-row-mt:v:"+s, "1",
+		)
+	}
+
+	return args
+}
+
+// CodecString returns the RFC 6381 codec string of the rendition's video
+// track, as advertised in the HLS CODECS attribute and the DASH codecs
+// attribute, e.g. "avc1.4d401f".
+func (r Rendition) CodecString() string {
+	resolved, err := resolveRendition(r)
+	if err != nil {
+		return ""
+	}
+	level := parseLevel(resolved.Level)
+
+	switch resolved.VideoCodec {
+	case CodecHEVC:
+		if strings.EqualFold(resolved.Profile, "main10") {
+			return fmt.Sprintf("hvc1.2.4.L%d.B0", level*3)
+		}
+		return fmt.Sprintf("hvc1.1.6.L%d.B0", level*3)
+	case CodecVP9:
+		profile, _ := strconv.Atoi(resolved.Profile)
+		return fmt.Sprintf("vp09.%02d.%02d.%02d", profile, level, resolved.bitDepth())
+	case CodecAV1:
+		// seq_level_idx is (major - 2) * 4 + minor.
+		idx := (level/10-2)*4 + level%10
+		if idx < 0 {
+			idx = 0
+		}
+		return fmt.Sprintf("av01.0.%02dM.%02d", idx, resolved.bitDepth())
+	default:
+		var profile string
+		switch strings.ToLower(resolved.Profile) {
+		case "baseline", "constrained_baseline":
+			profile = "42e0"
+		case "high":
+			profile = "6400"
+		case "high10":
+			profile = "6e00"
+		default:
+			profile = "4d40"
+		}
+		return fmt.Sprintf("avc1.%s%02x", profile, level)
+	}
+}
+
+// CodecsAttribute returns the full CODECS value of a variant, including the
+// audio track when present.
+func (r Rendition) CodecsAttribute(hasAudio bool) string {
+	codecs := r.CodecString()
+	if hasAudio {
+		codecs += "," + audioCodecAAC
+	}
+	return codecs
+}
+
+// parseLevel converts a level such as "4.1" or "41" to its ×10 integer form.
+func parseLevel(level string) int {
+	if major, minor, ok := strings.Cut(level, "."); ok {
+		ma, _ := strconv.Atoi(major)
+		mi, _ := strconv.Atoi(minor)
+		return ma*10 + mi
+	}
+	v, _ := strconv.Atoi(level)
+	if v < 10 {
+		return v * 10
+	}
+	return v
+}
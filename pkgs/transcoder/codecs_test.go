@@ -0,0 +1,58 @@
+package transcoder
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDefaultLevelVP9(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          string
+	}{
+		{640, 360, "2.1"},
+		{854, 480, "3.0"},
+		{1280, 720, "3.1"},
+		{1920, 1080, "4.0"},
+		{2560, 1440, "5.0"},
+		{3840, 2160, "5.0"},
+		{7680, 4320, "6.0"},
+	}
+	for _, tt := range tests {
+		if got := defaultLevel(CodecVP9, tt.width, tt.height); got != tt.want {
+			t.Errorf("defaultLevel(vp9, %dx%d) = %s, want %s", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestVideoEncoderPixelFormat(t *testing.T) {
+	tests := []struct {
+		codec, profile string
+		wantPixFmt     string
+		wantCodecs     string
+	}{
+		{CodecH264, "", "yuv420p", "avc1.4d4028"},
+		{CodecH264, "high10", "yuv420p10le", "avc1.6e0028"},
+		{CodecHEVC, "main", "yuv420p", "hvc1.1.6.L120.B0"},
+		{CodecHEVC, "main10", "yuv420p10le", "hvc1.2.4.L120.B0"},
+		{CodecVP9, "", "yuv420p", "vp09.00.40.08"},
+		{CodecVP9, "2", "yuv420p10le", "vp09.02.40.10"},
+		{CodecAV1, "", "yuv420p", "av01.0.08M.08"},
+		{CodecAV1, "main10", "yuv420p10le", "av01.0.08M.10"},
+	}
+	for _, tt := range tests {
+		r, err := resolveRendition(Rendition{Width: 1920, Height: 1080, VideoCodec: tt.codec, Profile: tt.profile})
+		if err != nil {
+			t.Fatalf("resolveRendition(%s %s): %v", tt.codec, tt.profile, err)
+		}
+
+		args := videoEncoderArgs(0, r)
+		i := slices.Index(args, "-pix_fmt:v:0")
+		if i < 0 || args[i+1] != tt.wantPixFmt {
+			t.Errorf("%s %s: args %v, want pix_fmt %s", tt.codec, tt.profile, args, tt.wantPixFmt)
+		}
+		if got := r.CodecString(); got != tt.wantCodecs {
+			t.Errorf("%s %s: CodecString = %s, want %s", tt.codec, tt.profile, got, tt.wantCodecs)
+		}
+	}
+}
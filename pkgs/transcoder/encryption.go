@@ -0,0 +1,220 @@
+package transcoder
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HLS encryption methods.
+const (
+	// EncryptionAES128 encrypts whole segments with AES-128-CBC.
+	EncryptionAES128 = "AES-128"
+	// EncryptionSampleAES encrypts the samples inside the fMP4 segments with
+	// the cbcs common encryption scheme that Apple players require. ffmpeg
+	// only writes cenc, so the clear segments are encrypted afterwards with
+	// Bento4's mp4encrypt.
+	EncryptionSampleAES = "SAMPLE-AES"
+)
+
+// mp4encryptTracks are the track IDs of the video and audio track in the
+// fMP4 segments of a variant.
+var mp4encryptTracks = []string{"1", "2"}
+
+// Encryption configures HLS content encryption for one transcode. Only the
+// HLS output is produced when encryption is enabled, since the key delivery
+// through EXT-X-KEY has no DASH equivalent here.
+type Encryption struct {
+	Method string // EncryptionAES128 or EncryptionSampleAES
+	Key    []byte // 16 byte content key
+	IV     []byte // Optional 16 byte IV; AES-128 uses the segment sequence number and SAMPLE-AES a random constant IV if empty
+	KeyID  []byte // 16 byte key ID for SAMPLE-AES
+	KeyURI string // URI players fetch the key from, written to EXT-X-KEY
+}
+
+func (e *Encryption) validate() error {
+	if len(e.Key) != 16 {
+		return fmt.Errorf("encryption key must be 16 bytes, got %d", len(e.Key))
+	}
+	if e.KeyURI == "" {
+		return fmt.Errorf("encryption key URI is required")
+	}
+	if len(e.IV) != 0 && len(e.IV) != 16 {
+		return fmt.Errorf("encryption IV must be 16 bytes, got %d", len(e.IV))
+	}
+	switch e.Method {
+	case EncryptionAES128:
+	case EncryptionSampleAES:
+		if len(e.KeyID) != 16 {
+			return fmt.Errorf("encryption key ID must be 16 bytes, got %d", len(e.KeyID))
+		}
+	default:
+		return fmt.Errorf("unsupported encryption method %q", e.Method)
+	}
+	return nil
+}
+
+// hlsArgs prepares the ffmpeg HLS muxer arguments for the encryption. The
+// key material is written to a private directory outside the output, which
+// the returned cleanup function removes. SAMPLE-AES needs no arguments, as
+// its segments are encrypted after the encode by encryptSamples.
+func (e *Encryption) hlsArgs() ([]string, func(), error) {
+	if err := e.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if e.Method == EncryptionSampleAES {
+		return nil, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "hls-key-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create key directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	keyPath := filepath.Join(dir, "enc.key")
+	if err := os.WriteFile(keyPath, e.Key, 0600); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("write key file: %w", err)
+	}
+
+	// The key info file holds the key URI, the key file path and an
+	// optional IV, one per line.
+	info := e.KeyURI + "\n" + keyPath + "\n"
+	if len(e.IV) > 0 {
+		info += hex.EncodeToString(e.IV) + "\n"
+	}
+	infoPath := filepath.Join(dir, "enc.keyinfo")
+	if err := os.WriteFile(infoPath, []byte(info), 0600); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("write key info file: %w", err)
+	}
+
+	return []string{"-hls_key_info_file", infoPath}, cleanup, nil
+}
+
+// encryptSamples encrypts the init and media segments of every variant of
+// a SAMPLE-AES encode in place with cbcs. The segments share one constant
+// IV, which the init segments carry to the players.
+func (t *Transcoder) encryptSamples(ctx context.Context, e *Encryption, outputDir string, variants int) error {
+	if e.Method != EncryptionSampleAES {
+		return nil
+	}
+
+	iv := e.IV
+	if len(iv) == 0 {
+		iv = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+			return fmt.Errorf("generate encryption IV: %w", err)
+		}
+	}
+	var keyArgs []string
+	for _, track := range mp4encryptTracks {
+		keyArgs = append(keyArgs,
+			"--key", track+":"+hex.EncodeToString(e.Key)+":"+hex.EncodeToString(iv),
+			"--property", track+":KID:"+hex.EncodeToString(e.KeyID),
+		)
+	}
+
+	for i := 0; i < variants; i++ {
+		variantDir := filepath.Join(outputDir, fmt.Sprint(i))
+		initName, segments, err := playlistSegments(filepath.Join(variantDir, "stream.m3u8"))
+		if err != nil {
+			return err
+		}
+		if initName == "" {
+			return fmt.Errorf("variant %d has no init segment", i)
+		}
+
+		// Segments are encrypted against the clear init segment, so it goes
+		// last.
+		for _, name := range append(segments, initName) {
+			args := append([]string{"--method", "MPEG-CBCS"}, keyArgs...)
+			if name != initName {
+				args = append(args, "--fragments-info", initName)
+			}
+			if err := t.encryptFile(ctx, variantDir, name, args); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encryptFile runs mp4encrypt on the file name in dir and replaces it with
+// the encrypted copy.
+func (t *Transcoder) encryptFile(ctx context.Context, dir, name string, args []string) error {
+	tmp := name + ".enc"
+	var output strings.Builder
+	args = append(args, name, tmp)
+	if err := t.runCommand(ctx, dir, &output, &output, "mp4encrypt", args...); err != nil {
+		os.Remove(filepath.Join(dir, tmp))
+		return fmt.Errorf("encrypt %s: %w: %s", name, err, output.String())
+	}
+	return os.Rename(filepath.Join(dir, tmp), filepath.Join(dir, name))
+}
+
+// playlistSegments returns the init segment and the media segments a
+// variant playlist references.
+func playlistSegments(path string) (string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("read variant playlist: %w", err)
+	}
+
+	var initName string
+	var segments []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if _, uri, ok := strings.Cut(line, `URI="`); ok {
+				initName, _, _ = strings.Cut(uri, `"`)
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			segments = append(segments, line)
+		}
+	}
+	return initName, segments, nil
+}
+
+// writeKeyTags adds the EXT-X-KEY tag to the variant playlists of a
+// SAMPLE-AES encode, which ffmpeg does not write for sample encryption.
+// AES-128 playlists already carry the tag written by ffmpeg.
+func (e *Encryption) writeKeyTags(outputDir string, variants int) error {
+	if e.Method != EncryptionSampleAES {
+		return nil
+	}
+
+	tag := fmt.Sprintf(`#EXT-X-KEY:METHOD=SAMPLE-AES,URI="%s",KEYFORMAT="identity"`, e.KeyURI)
+	for i := 0; i < variants; i++ {
+		path := filepath.Join(outputDir, fmt.Sprint(i), "stream.m3u8")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read variant playlist: %w", err)
+		}
+
+		lines := strings.Split(string(data), "\n")
+		out := make([]string, 0, len(lines)+1)
+		inserted := false
+		for _, line := range lines {
+			// The key must precede the init segment and the first media segment.
+			if !inserted && (strings.HasPrefix(line, "#EXT-X-MAP") || strings.HasPrefix(line, "#EXTINF")) {
+				out = append(out, tag)
+				inserted = true
+			}
+			out = append(out, line)
+		}
+
+		if err := os.WriteFile(path, []byte(strings.Join(out, "\n")), 0644); err != nil {
+			return fmt.Errorf("write variant playlist: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeMP4Encrypt is an mp4encrypt that logs its arguments and prefixes the
+// output copy of its input with "enc:".
+const fakeMP4Encrypt = `#!/bin/sh
+echo "$@" >>"$MP4ENCRYPT_LOG"
+for last; do :; done
+in=""
+for arg in "$@"; do
+  if [ "$arg" = "$last" ]; then break; fi
+  in="$arg"
+done
+{ printf 'enc:'; cat "$in"; } >"$last"
+`
+
+const testVariantPlaylist = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:4
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4.000000,
+seg_000.m4s
+#EXTINF:2.000000,
+seg_001.m4s
+#EXT-X-ENDLIST
+`
+
+func TestSampleAESEncryption(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "mp4encrypt"), []byte(fakeMP4Encrypt), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	logPath := filepath.Join(bin, "log")
+	t.Setenv("MP4ENCRYPT_LOG", logPath)
+
+	outputDir := t.TempDir()
+	variantDir := filepath.Join(outputDir, "0")
+	files := map[string]string{
+		"stream.m3u8": testVariantPlaylist,
+		"init.mp4":    "init",
+		"seg_000.m4s": "seg0",
+		"seg_001.m4s": "seg1",
+	}
+	os.MkdirAll(variantDir, 0755)
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(variantDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enc := &Encryption{
+		Method: EncryptionSampleAES,
+		Key:    bytes.Repeat([]byte{1}, 16),
+		KeyID:  bytes.Repeat([]byte{2}, 16),
+		KeyURI: "https://example.com/v1/videos/1/key",
+	}
+	args, cleanup, err := enc.hlsArgs()
+	if err != nil {
+		t.Fatalf("hlsArgs: %v", err)
+	}
+	cleanup()
+	if len(args) != 0 {
+		t.Errorf("hlsArgs = %v, want none for SAMPLE-AES", args)
+	}
+
+	tr := New()
+	if err := tr.encryptSamples(context.Background(), enc, outputDir, 1); err != nil {
+		t.Fatalf("encryptSamples: %v", err)
+	}
+	if err := enc.writeKeyTags(outputDir, 1); err != nil {
+		t.Fatalf("writeKeyTags: %v", err)
+	}
+
+	for _, name := range []string{"init.mp4", "seg_000.m4s", "seg_001.m4s"} {
+		data, _ := os.ReadFile(filepath.Join(variantDir, name))
+		if string(data) != "enc:"+files[name] {
+			t.Errorf("%s = %q, want it encrypted once", name, data)
+		}
+		if _, err := os.Stat(filepath.Join(variantDir, name+".enc")); !os.IsNotExist(err) {
+			t.Errorf("%s.enc left behind", name)
+		}
+	}
+
+	log, _ := os.ReadFile(logPath)
+	calls := strings.Split(strings.TrimSpace(string(log)), "\n")
+	if len(calls) != 3 {
+		t.Fatalf("mp4encrypt calls = %q", calls)
+	}
+	for _, call := range calls {
+		if !strings.Contains(call, "--method MPEG-CBCS") || !strings.Contains(call, "--property 1:KID:"+strings.Repeat("02", 16)) {
+			t.Errorf("call %q does not encrypt with cbcs", call)
+		}
+	}
+	// Segments are encrypted against the clear init segment.
+	if !strings.Contains(calls[0], "--fragments-info init.mp4 seg_000.m4s") || strings.Contains(calls[2], "--fragments-info") {
+		t.Errorf("calls = %q", calls)
+	}
+
+	playlist, _ := os.ReadFile(filepath.Join(variantDir, "stream.m3u8"))
+	tag := `#EXT-X-KEY:METHOD=SAMPLE-AES,URI="https://example.com/v1/videos/1/key",KEYFORMAT="identity"` + "\n#EXT-X-MAP"
+	if !strings.Contains(string(playlist), tag) {
+		t.Errorf("playlist has no SAMPLE-AES key tag:\n%s", playlist)
+	}
+}
@@ -0,0 +1,70 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// stderrTailSize is how much of the end of a failed command's stderr is kept
+// on its ExitError.
+const stderrTailSize = 4 << 10
+
+// ExitError is returned when ffmpeg or ffprobe exits with a non-zero status.
+// A TimeoutError or CanceledError wraps it when the process was stopped.
+type ExitError struct {
+	Command    string // Program name, e.g. ffmpeg
+	ExitCode   int    // Process exit code, -1 when killed by a signal
+	StderrTail string // Last bytes written to stderr
+	Err        error  // The underlying *exec.ExitError
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d: %v", e.Command, e.ExitCode, e.Err)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// stderrTail returns the end of the stderr of the command that failed with
+// err, or "" if it did not exit with an error.
+func stderrTail(err error) string {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.StderrTail
+	}
+	return ""
+}
+
+// TimeoutError is returned when ffmpeg is stopped because the transcode
+// exceeded its wall-clock timeout.
+type TimeoutError struct {
+	Timeout time.Duration // The timeout that was exceeded, zero if it came from the caller's deadline
+	Err     error         // The underlying process error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("transcode timed out after %s: %v", e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("transcode deadline exceeded: %v", e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// CanceledError is returned when ffmpeg is stopped because the caller's
+// context was cancelled, e.g. during a worker shutdown.
+type CanceledError struct {
+	Err error // The underlying process error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("transcode cancelled: %v", e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
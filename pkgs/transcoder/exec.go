@@ -0,0 +1,102 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// errJobTimeout is the cancellation cause used for the per-job timeout, so
+// it can be told apart from a cancellation by the caller.
+var errJobTimeout = errors.New("transcode job timeout")
+
+// jobContext derives the context a single transcode runs under, applying
+// the configured wall-clock timeout if any.
+func (t *Transcoder) jobContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, t.timeout, errJobTimeout)
+}
+
+// runCommand runs name with args in dir, writing its output to stdout and
+// stderr. When ctx is done the process group receives SIGTERM, followed by
+// SIGKILL if it is still running after the kill grace period.
+func (t *Transcoder) runCommand(ctx context.Context, dir string, stdout, stderr io.Writer, name string, args ...string) error {
+	tail := &tailBuffer{size: stderrTailSize}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(stderr, tail)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminateProcess(cmd)
+	}
+	// Stop waiting on output pipes held open by orphaned children shortly
+	// after the process group has been killed.
+	cmd.WaitDelay = t.killGrace + 5*time.Second
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			timer := time.NewTimer(t.killGrace)
+			defer timer.Stop()
+			select {
+			case <-done:
+			case <-timer.C:
+				_ = killProcess(cmd)
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	close(done)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		err = &ExitError{Command: name, ExitCode: exitErr.ExitCode(), StderrTail: tail.String(), Err: err}
+	}
+	return t.classifyError(ctx, err)
+}
+
+// tailBuffer keeps the last size bytes written to it.
+type tailBuffer struct {
+	size int
+	buf  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
+
+// classifyError maps a process error caused by ctx into a TimeoutError or a
+// CanceledError. Errors unrelated to ctx are returned unchanged.
+func (t *Transcoder) classifyError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if errors.Is(context.Cause(ctx), errJobTimeout) {
+		return &TimeoutError{Timeout: t.timeout, Err: err}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Err: err}
+	}
+	return &CanceledError{Err: err}
+}
@@ -0,0 +1,28 @@
+package transcoder
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunCommandKeepsStderrTail(t *testing.T) {
+	// Far more stderr than the tail keeps, ending in the actual error
+	script := `i=0; while [ $i -lt 2000 ]; do echo "warning: frame $i dropped" >&2; i=$((i+1)); done; echo "fatal: encoder failed" >&2; exit 3`
+	err := New().runCommand(context.Background(), t.TempDir(), io.Discard, io.Discard, "sh", "-c", script)
+	if err == nil {
+		t.Fatal("runCommand succeeded")
+	}
+
+	tail := stderrTail(err)
+	if len(tail) > stderrTailSize || !strings.HasSuffix(tail, "fatal: encoder failed\n") {
+		t.Errorf("tail of %d bytes = ...%q", len(tail), tail[max(0, len(tail)-64):])
+	}
+	if strings.Contains(tail, "frame 0 ") {
+		t.Error("tail kept the start of stderr")
+	}
+	if stderrTail(&TimeoutError{Err: err}) != tail {
+		t.Error("tail lost behind a TimeoutError")
+	}
+}
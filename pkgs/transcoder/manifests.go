@@ -0,0 +1,128 @@
+package transcoder
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// writeHLSCodecs sets the CODECS attribute of every variant in the HLS
+// master playlist at path. ffmpeg omits or truncates it for some codecs, and
+// players rely on it to skip variants they cannot decode.
+func writeHLSCodecs(path string, selected []Rendition, hasAudio bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read master playlist: %w", err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines)-1; i++ {
+		if !strings.HasPrefix(lines[i], "#EXT-X-STREAM-INF:") {
+			continue
+		}
+		idx, ok := variantIndex(lines[i+1])
+		if !ok || idx >= len(selected) {
+			continue
+		}
+		lines[i] = setM3U8Attribute(lines[i], "CODECS", strconv.Quote(selected[idx].CodecsAttribute(hasAudio)))
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// variantIndex extracts the rendition index from a variant playlist URI of
+// the form "<index>/stream.m3u8".
+func variantIndex(uri string) (int, bool) {
+	dir, _, ok := strings.Cut(strings.TrimSpace(uri), "/")
+	if !ok {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(dir)
+	if err != nil {
+		return 0, false
+	}
+	return idx, true
+}
+
+// setM3U8Attribute sets key to the already quoted or enumerated value in an
+// attribute-list tag line, replacing an existing value if present.
+func setM3U8Attribute(line, key, value string) string {
+	tag, list, _ := strings.Cut(line, ":")
+	attrs := splitM3U8Attributes(list)
+
+	replaced := false
+	for i, attr := range attrs {
+		if name, _, _ := strings.Cut(attr, "="); name == key {
+			attrs[i] = key + "=" + value
+			replaced = true
+		}
+	}
+	if !replaced {
+		attrs = append(attrs, key+"="+value)
+	}
+	return tag + ":" + strings.Join(attrs, ",")
+}
+
+// splitM3U8Attributes splits an attribute list on commas that are not inside
+// a quoted string.
+func splitM3U8Attributes(list string) []string {
+	var attrs []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				attrs = append(attrs, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(list) {
+		attrs = append(attrs, list[start:])
+	}
+	return attrs
+}
+
+var (
+	dashRepresentationTag = regexp.MustCompile(`<Representation\b[^>]*>`)
+	dashIDAttribute       = regexp.MustCompile(`\bid="(\d+)"`)
+	dashCodecsAttribute   = regexp.MustCompile(`\bcodecs="[^"]*"`)
+)
+
+// writeDASHCodecs sets the codecs attribute of every video Representation in
+// the DASH manifest at path. Representation ids are output stream indexes.
+func writeDASHCodecs(path string, selected []Rendition, hasAudio bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read dash manifest: %w", err)
+	}
+
+	codecsByStream := map[int]string{}
+	for i, r := range selected {
+		codecsByStream[videoOutputIndex(i, hasAudio)] = r.CodecString()
+	}
+
+	out := dashRepresentationTag.ReplaceAllStringFunc(string(data), func(tag string) string {
+		m := dashIDAttribute.FindStringSubmatch(tag)
+		if m == nil {
+			return tag
+		}
+		id, _ := strconv.Atoi(m[1])
+		codecs, ok := codecsByStream[id]
+		if !ok {
+			return tag
+		}
+		attr := fmt.Sprintf(`codecs="%s"`, codecs)
+		if dashCodecsAttribute.MatchString(tag) {
+			return dashCodecsAttribute.ReplaceAllString(tag, attr)
+		}
+		return strings.Replace(tag, "<Representation", "<Representation "+attr, 1)
+	})
+
+	return os.WriteFile(path, []byte(out), 0644)
+}
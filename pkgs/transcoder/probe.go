@@ -0,0 +1,223 @@
+package transcoder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// HDR formats reported by VideoStream.HDRFormat.
+const (
+	HDRFormatHDR10       = "hdr10"
+	HDRFormatHLG         = "hlg"
+	HDRFormatDolbyVision = "dolby_vision"
+)
+
+// ProbeResult describes a media file as reported by ffprobe.
+type ProbeResult struct {
+	FormatName      string            // Container format, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	FormatLongName  string            // Human readable container format
+	Duration        time.Duration     // Container duration, 0 if unknown
+	BitRate         int64             // Overall bitrate in bits per second, 0 if unknown
+	Size            int64             // File size in bytes
+	Tags            map[string]string // Container level tags
+	VideoStreams    []VideoStream
+	AudioStreams    []AudioStream
+	SubtitleStreams []SubtitleStream
+}
+
+// VideoStream describes one video stream of a probed file.
+type VideoStream struct {
+	Index          int     // Stream index in the container
+	Codec          string  // Codec name, e.g. "h264", "hevc"
+	CodecLongName  string  // Human readable codec name
+	CodecTag       string  // Codec tag, e.g. "avc1", "hvc1"
+	Profile        string  // Codec profile, e.g. "High"
+	Level          int     // Codec level as reported by ffprobe, e.g. 41
+	Width          int     // Coded width in pixels
+	Height         int     // Coded height in pixels
+	PixelFormat    string  // Pixel format, e.g. "yuv420p10le"
+	FrameRate      float64 // Real base frame rate
+	AvgFrameRate   float64 // Average frame rate
+	BitRate        int64   // Stream bitrate in bits per second, 0 if unknown
+	Rotation       int     // Display rotation in degrees, e.g. 90 or -90
+	Language       string  // Language tag, empty if untagged
+	Default        bool    // Whether the stream is flagged as default
+	ColorRange     string  // e.g. "tv", "pc"
+	ColorSpace     string  // e.g. "bt709", "bt2020nc"
+	ColorTransfer  string  // e.g. "bt709", "smpte2084", "arib-std-b67"
+	ColorPrimaries string  // e.g. "bt709", "bt2020"
+	HDRFormat      string  // One of the HDRFormat constants, empty for SDR
+}
+
+// DisplaySize returns the width and height the stream is displayed at,
+// accounting for a 90 or 270 degree rotation.
+func (v VideoStream) DisplaySize() (int, int) {
+	switch ((v.Rotation % 360) + 360) % 360 {
+	case 90, 270:
+		return v.Height, v.Width
+	default:
+		return v.Width, v.Height
+	}
+}
+
+// AudioStream describes one audio stream of a probed file.
+type AudioStream struct {
+	Index         int    // Stream index in the container
+	Codec         string // Codec name, e.g. "aac", "opus"
+	CodecLongName string // Human readable codec name
+	Profile       string // Codec profile, e.g. "LC"
+	SampleRate    int    // Sample rate in Hz
+	Channels      int    // Number of channels
+	ChannelLayout string // e.g. "stereo", "5.1(side)"
+	BitRate       int64  // Stream bitrate in bits per second, 0 if unknown
+	Language      string // Language tag, empty if untagged
+	Default       bool   // Whether the stream is flagged as default
+}
+
+// SubtitleStream describes one subtitle stream of a probed file.
+type SubtitleStream struct {
+	Index    int    // Stream index in the container
+	Codec    string // Codec name, e.g. "mov_text", "subrip"
+	Language string // Language tag, empty if untagged
+	Title    string // Title tag, empty if untagged
+	Default  bool   // Whether the stream is flagged as default
+	Forced   bool   // Whether the stream is flagged as forced
+}
+
+// PrimaryVideo returns the default video stream, or the first one if none is
+// flagged as default. It returns nil when the file has no video.
+func (p *ProbeResult) PrimaryVideo() *VideoStream {
+	if len(p.VideoStreams) == 0 {
+		return nil
+	}
+	for i := range p.VideoStreams {
+		if p.VideoStreams[i].Default {
+			return &p.VideoStreams[i]
+		}
+	}
+	return &p.VideoStreams[0]
+}
+
+// HasAudio reports whether the file contains at least one audio stream.
+func (p *ProbeResult) HasAudio() bool {
+	return len(p.AudioStreams) > 0
+}
+
+// Probe runs ffprobe on inputPath and returns its container and stream
+// metadata. Attached pictures such as cover art are not reported as video.
+func Probe(ctx context.Context, inputPath string) (*ProbeResult, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "quiet", "-print_format", "json", "-show_format", "-show_streams", inputPath)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe error: %w", err)
+	}
+
+	var probeData ffprobeOutput
+	if err := json.Unmarshal(out, &probeData); err != nil {
+		return nil, fmt.Errorf("ffprobe json unmarshal error: %w", err)
+	}
+
+	return newProbeResult(probeData), nil
+}
+
+// newProbeResult converts the raw ffprobe output into a ProbeResult.
+func newProbeResult(data ffprobeOutput) *ProbeResult {
+	result := &ProbeResult{
+		FormatName:     data.Format.FormatName,
+		FormatLongName: data.Format.FormatLongName,
+		Duration:       time.Duration(parseFloat(data.Format.Duration) * float64(time.Second)),
+		BitRate:        parseInt(data.Format.BitRate),
+		Size:           parseInt(data.Format.Size),
+		Tags:           data.Format.Tags,
+	}
+
+	for _, s := range data.Streams {
+		switch s.CodecType {
+		case "video":
+			if s.Disposition["attached_pic"] == 1 {
+				continue
+			}
+			result.VideoStreams = append(result.VideoStreams, VideoStream{
+				Index:          s.Index,
+				Codec:          s.CodecName,
+				CodecLongName:  s.CodecLongName,
+				CodecTag:       s.CodecTagString,
+				Profile:        s.Profile,
+				Level:          s.Level,
+				Width:          s.Width,
+				Height:         s.Height,
+				PixelFormat:    s.PixFmt,
+				FrameRate:      parseRational(s.RFrameRate),
+				AvgFrameRate:   parseRational(s.AvgFrameRate),
+				BitRate:        parseInt(s.BitRate),
+				Rotation:       streamRotation(s),
+				Language:       s.Tags["language"],
+				Default:        s.Disposition["default"] == 1,
+				ColorRange:     s.ColorRange,
+				ColorSpace:     s.ColorSpace,
+				ColorTransfer:  s.ColorTransfer,
+				ColorPrimaries: s.ColorPrimaries,
+				HDRFormat:      hdrFormat(s),
+			})
+		case "audio":
+			result.AudioStreams = append(result.AudioStreams, AudioStream{
+				Index:         s.Index,
+				Codec:         s.CodecName,
+				CodecLongName: s.CodecLongName,
+				Profile:       s.Profile,
+				SampleRate:    int(parseInt(s.SampleRate)),
+				Channels:      s.Channels,
+				ChannelLayout: s.ChannelLayout,
+				BitRate:       parseInt(s.BitRate),
+				Language:      s.Tags["language"],
+				Default:       s.Disposition["default"] == 1,
+			})
+		case "subtitle":
+			result.SubtitleStreams = append(result.SubtitleStreams, SubtitleStream{
+				Index:    s.Index,
+				Codec:    s.CodecName,
+				Language: s.Tags["language"],
+				Title:    s.Tags["title"],
+				Default:  s.Disposition["default"] == 1,
+				Forced:   s.Disposition["forced"] == 1,
+			})
+		}
+	}
+
+	return result
+}
+
+// streamRotation reads the rotation from the display matrix side data, or
+// from the legacy "rotate" tag written by older muxers.
+func streamRotation(s ffprobeStream) int {
+	for _, sd := range s.SideDataList {
+		if sd.Rotation != nil {
+			return *sd.Rotation
+		}
+	}
+	if rotate, err := strconv.Atoi(s.Tags["rotate"]); err == nil {
+		return rotate
+	}
+	return 0
+}
+
+// hdrFormat derives the HDR format from the stream's side data and color
+// transfer characteristics.
+func hdrFormat(s ffprobeStream) string {
+	for _, sd := range s.SideDataList {
+		if sd.SideDataType == "DOVI configuration record" {
+			return HDRFormatDolbyVision
+		}
+	}
+	switch s.ColorTransfer {
+	case "smpte2084":
+		return HDRFormatHDR10
+	case "arib-std-b67":
+		return HDRFormatHLG
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package transcoder
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on platforms without process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcess kills the process; graceful termination is not
+// available on this platform.
+func terminateProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// killProcess forcibly kills the process.
+func killProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,139 @@
+package transcoder
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is a snapshot of a running ffmpeg encode, as reported through
+// ffmpeg's -progress output.
+type Progress struct {
+	Frame    int64         // Number of frames encoded so far
+	FPS      float64       // Current encoding speed in frames per second
+	Speed    float64       // Encoding speed relative to real time, e.g. 1.5
+	OutTime  time.Duration // Position of the encoded output
+	Duration time.Duration // Duration of the source, 0 if unknown
+	Percent  float64       // Completion in the range [0, 100], 0 if the duration is unknown
+	ETA      time.Duration // Estimated time remaining, 0 if unknown
+	Done     bool          // True for the final report of the encode
+}
+
+// ProgressFunc receives progress reports while ffmpeg runs. It is called
+// from the goroutine reading ffmpeg's output and should return quickly.
+type ProgressFunc func(Progress)
+
+// progressParser turns the key=value blocks written by "ffmpeg -progress"
+// into Progress reports. Each block ends with a "progress=" line.
+type progressParser struct {
+	duration time.Duration
+	onReport ProgressFunc
+	current  Progress
+	pending  []byte
+}
+
+func newProgressParser(duration time.Duration, onReport ProgressFunc) *progressParser {
+	return &progressParser{
+		duration: duration,
+		onReport: onReport,
+		current:  Progress{Duration: duration},
+	}
+}
+
+// Write implements io.Writer so the parser can be used as ffmpeg's stdout.
+func (p *progressParser) Write(b []byte) (int, error) {
+	p.pending = append(p.pending, b...)
+	for {
+		idx := bytes.IndexByte(p.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		p.parseLine(string(bytes.TrimSpace(p.pending[:idx])))
+		p.pending = p.pending[idx+1:]
+	}
+	return len(b), nil
+}
+
+func (p *progressParser) parseLine(line string) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "frame":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			p.current.Frame = v
+		}
+	case "fps":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			p.current.FPS = v
+		}
+	case "speed":
+		if v, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+			p.current.Speed = v
+		}
+	case "out_time_us", "out_time_ms":
+		// Both keys are expressed in microseconds.
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil && v >= 0 {
+			p.current.OutTime = time.Duration(v) * time.Microsecond
+		}
+	case "out_time":
+		if p.current.OutTime == 0 {
+			if v, ok := parseTimestamp(value); ok {
+				p.current.OutTime = v
+			}
+		}
+	case "progress":
+		p.current.Done = value == "end"
+		p.report()
+	}
+}
+
+// report computes the derived fields and hands the snapshot to the callback.
+func (p *progressParser) report() {
+	progress := p.current
+	if p.duration > 0 {
+		progress.Percent = float64(progress.OutTime) / float64(p.duration) * 100
+		if progress.Percent > 100 {
+			progress.Percent = 100
+		}
+		if remaining := p.duration - progress.OutTime; remaining > 0 && progress.Speed > 0 {
+			progress.ETA = time.Duration(float64(remaining) / progress.Speed)
+		}
+	}
+	if progress.Done {
+		progress.Percent = 100
+		progress.ETA = 0
+	}
+
+	if p.onReport != nil {
+		p.onReport(progress)
+	}
+	p.current = Progress{Duration: p.duration}
+}
+
+// parseTimestamp parses an ffmpeg "HH:MM:SS.micro" timestamp.
+func parseTimestamp(value string) (time.Duration, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), true
+}
@@ -23,13 +23,20 @@ func filterRenditions(srcW, srcH int, renditions []Rendition) []Rendition {
 
 // buildFilterComplex generates the filter_complex argument for ffmpeg
 // that splits the input video into multiple streams and scales them
-// according to the selected renditions.
-func buildFilterComplex(selected []Rendition) string {
+// according to the selected renditions. The HLS output reads the scaled
+// streams from [v<i>out]; with dash, each is split again into [v<i>dash],
+// since a filter output can only feed one output file.
+func buildFilterComplex(selected []Rendition, dash bool) string {
 	splitOutputs := []string{}
 	filterScales := []string{}
 	for i, r := range selected {
 		splitOutputs = append(splitOutputs, fmt.Sprintf("[v%d]", i))
-		filterScales = append(filterScales, fmt.Sprintf("[v%d]scale=w=%d:h=%d:force_original_aspect_ratio=decrease[v%dout]", i, r.Width, r.Height, i))
+		scale := fmt.Sprintf("[v%d]scale=w=%d:h=%d:force_original_aspect_ratio=decrease", i, r.Width, r.Height)
+		if dash {
+			filterScales = append(filterScales, fmt.Sprintf("%s,split=2[v%dout][v%ddash]", scale, i, i))
+		} else {
+			filterScales = append(filterScales, fmt.Sprintf("%s[v%dout]", scale, i))
+		}
 	}
 	return fmt.Sprintf("[0:v]split=%d%s;%s",
 		len(selected),
@@ -40,20 +47,67 @@ func buildFilterComplex(selected []Rendition) string {
 
 // buildVarStreamMap constructs the var_stream_map parameter used by ffmpeg to map
 // video and audio streams for each rendition in adaptive streaming.
-func buildVarStreamMap(selected []Rendition) string {
+func buildVarStreamMap(selected []Rendition, hasAudio bool) string {
 	parts := []string{}
 	for i := range selected {
-		parts = append(parts, fmt.Sprintf("v:%d,a:%d", i, i))
+		if hasAudio {
+			parts = append(parts, fmt.Sprintf("v:%d,a:%d", i, i))
+		} else {
+			parts = append(parts, fmt.Sprintf("v:%d", i))
+		}
 	}
 	return strings.Join(parts, " ")
 }
 
+// videoOutputIndex returns the index of rendition i's video stream among all
+// output streams, which interleave video and audio when audio is mapped.
+func videoOutputIndex(i int, hasAudio bool) int {
+	if hasAudio {
+		return 2 * i
+	}
+	return i
+}
+
+// buildAdaptationSets groups the video streams into one DASH adaptation set
+// per codec, since players may not switch codecs within a set, and puts the
+// audio streams into a final set.
+func buildAdaptationSets(selected []Rendition, hasAudio bool) string {
+	var codecs []string
+	streams := map[string][]string{}
+	for i, r := range selected {
+		if _, ok := streams[r.VideoCodec]; !ok {
+			codecs = append(codecs, r.VideoCodec)
+		}
+		streams[r.VideoCodec] = append(streams[r.VideoCodec], fmt.Sprint(videoOutputIndex(i, hasAudio)))
+	}
+
+	sets := []string{}
+	for id, codec := range codecs {
+		sets = append(sets, fmt.Sprintf("id=%d,streams=%s", id, strings.Join(streams[codec], ",")))
+	}
+	if hasAudio {
+		sets = append(sets, fmt.Sprintf("id=%d,streams=a", len(codecs)))
+	}
+	return strings.Join(sets, " ")
+}
+
+// ffmpegOutputs describes the HLS and DASH outputs of a transcode.
+type ffmpegOutputs struct {
+	hlsSegmentPattern  string   // Relative segment file pattern
+	hlsPlaylistPattern string   // Relative variant playlist pattern
+	masterPlaylist     string   // Master playlist file name
+	varStreamMap       string   // Value of -var_stream_map
+	hlsExtraArgs       []string // Additional HLS muxer options, e.g. encryption
+	dashManifest       string   // DASH manifest file name, empty to skip DASH
+}
+
 // buildFFmpegArgs assembles the complete list of ffmpeg command-line arguments
 // required to transcode into multiple renditions with HLS CMAF segments and DASH manifest.
 func buildFFmpegArgs(
 	inputPath, filterComplex string,
 	selected []Rendition,
-	hlsSegmentPattern, hlsPlaylistPattern, masterPlaylist, varStreamMap, dashManifest string,
+	hasAudio bool,
+	outputs ffmpegOutputs,
 ) []string {
 	args := []string{
 		"-y",
@@ -61,41 +115,68 @@ func buildFFmpegArgs(
 		"-filter_complex", filterComplex,
 	}
 
+	// HLS and DASH output options. ffmpeg applies stream maps and codec
+	// options to the next output only, so each output repeats them.
+	args = append(args, outputStreamArgs(selected, hasAudio, "out")...)
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", "4",
+		"-hls_playlist_type", "vod",
+		"-hls_segment_type", "fmp4",
+		"-hls_segment_filename", outputs.hlsSegmentPattern,
+		"-master_pl_name", outputs.masterPlaylist,
+		"-var_stream_map", outputs.varStreamMap,
+	)
+	args = append(args, outputs.hlsExtraArgs...)
+	args = append(args, outputs.hlsPlaylistPattern)
+
+	if outputs.dashManifest != "" {
+		args = append(args, outputStreamArgs(selected, hasAudio, "dash")...)
+		args = append(args,
+			"-f", "dash",
+			"-use_template", "1",
+			"-use_timeline", "1",
+			"-adaptation_sets", buildAdaptationSets(selected, hasAudio),
+			outputs.dashManifest,
+		)
+	}
+
+	return args
+}
+
+// outputStreamArgs maps the video streams labelled [v<i><label>] of the
+// filter graph and the source audio for each rendition, and sets their
+// encoding, as options of the next output file.
+func outputStreamArgs(selected []Rendition, hasAudio bool, label string) []string {
+	args := []string{}
+
 	// Map video and audio streams for each rendition.
 	for i := range selected {
-		args = append(args, "-map", fmt.Sprintf("[v%dout]", i), "-map", "a:0?")
+		args = append(args, "-map", fmt.Sprintf("[v%d%s]", i, label))
+		if hasAudio {
+			args = append(args, "-map", "0:a:0")
+		}
 	}
 
+	// Keyframe cadence shared by all renditions so segments stay aligned.
+	args = append(args, "-g", "48", "-keyint_min", "48")
+
 	// Encoding settings for each rendition.
 	for i, r := range selected {
+		args = append(args, videoEncoderArgs(i, r)...)
+		if !hasAudio {
+			continue
+		}
 		args = append(args,
-			"-c:v:"+fmt.Sprint(i), "libx264",
-			"-b:v:"+fmt.Sprint(i), r.VideoBitrate,
-			"-preset", "veryfast",
-			"-profile:v:"+fmt.Sprint(i), "main",
-			"-g", "48", "-keyint_min", "48",
-			"-sc_threshold", "0",
 			"-c:a:"+fmt.Sprint(i), "aac",
 			"-b:a:"+fmt.Sprint(i), r.AudioBitrate,
 		)
+		if r.AudioChannels > 0 {
+			args = append(args, "-ac:a:"+fmt.Sprint(i), fmt.Sprint(r.AudioChannels))
+		}
+		if r.AudioSampleRate > 0 {
+			args = append(args, "-ar:a:"+fmt.Sprint(i), fmt.Sprint(r.AudioSampleRate))
+		}
 	}
-
-	// HLS and DASH output options.
-	args = append(args,
-		"-f", "hls",
-		"-hls_time", "4",
-		"-hls_playlist_type", "vod",
-		"-hls_segment_type", "fmp4",
-		"-hls_segment_filename", hlsSegmentPattern,
-		"-master_pl_name", masterPlaylist,
-		"-var_stream_map", varStreamMap,
-		hlsPlaylistPattern,
-		"-f", "dash",
-		"-use_template", "1",
-		"-use_timeline", "1",
-		"-adaptation_sets", "id=0,streams=v id=1,streams=a",
-		dashManifest,
-	)
-
 	return args
 }
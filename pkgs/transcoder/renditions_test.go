@@ -0,0 +1,69 @@
+package transcoder
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildFFmpegArgsDASHOutput(t *testing.T) {
+	var selected []Rendition
+	for _, r := range []Rendition{
+		{Name: "1080p-av1", Width: 1920, Height: 1080, VideoCodec: CodecAV1, VideoBitrate: "2500k", AudioBitrate: "128k"},
+		{Name: "720p", Width: 1280, Height: 720, VideoBitrate: "3000k", AudioBitrate: "96k", AudioChannels: 2},
+	} {
+		resolved, err := resolveRendition(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		selected = append(selected, resolved)
+	}
+
+	filter := buildFilterComplex(selected, true)
+	for _, label := range []string{"[v0out]", "[v0dash]", "[v1out]", "[v1dash]"} {
+		if strings.Count(filter, label) != 1 {
+			t.Errorf("filter %q: want one %s", filter, label)
+		}
+	}
+
+	args := buildFFmpegArgs("in.mp4", filter, selected, true, ffmpegOutputs{
+		hlsSegmentPattern:  "%v/seg_%03d.m4s",
+		hlsPlaylistPattern: "%v/stream.m3u8",
+		masterPlaylist:     "master.m3u8",
+		varStreamMap:       buildVarStreamMap(selected, true),
+		dashManifest:       "manifest.mpd",
+	})
+	hlsAt := slices.Index(args, "hls")
+	dashAt := slices.Index(args, "dash")
+	if hlsAt < 0 || dashAt < hlsAt || args[len(args)-1] != "manifest.mpd" {
+		t.Fatalf("args = %v", args)
+	}
+	hls, dash := args[:hlsAt], args[slices.Index(args, "%v/stream.m3u8")+1:dashAt]
+
+	// The DASH output maps its own copy of every stream, in the order
+	// buildAdaptationSets assumes, and encodes it like the HLS one.
+	wantMaps := []string{"-map", "[v0dash]", "-map", "0:a:0", "-map", "[v1dash]", "-map", "0:a:0"}
+	if !slices.Equal(dash[:len(wantMaps)], wantMaps) {
+		t.Errorf("dash maps = %v, want %v", dash[:len(wantMaps)], wantMaps)
+	}
+	hlsOptions := slices.Clone(hls[slices.Index(hls, "-g"):])
+	dashOptions := dash[slices.Index(dash, "-g"):]
+	if !slices.Equal(hlsOptions, dashOptions) {
+		t.Errorf("dash options = %v, want the hls options %v", dashOptions, hlsOptions)
+	}
+	for _, want := range []string{"-c:v:0", EncoderSVTAV1, "-b:v:1", "3000k", "-c:a:1", "-ac:a:1"} {
+		if !slices.Contains(dashOptions, want) {
+			t.Errorf("dash options %v lack %s", dashOptions, want)
+		}
+	}
+	if i := slices.Index(args, "-adaptation_sets"); i < dashAt || args[i+1] != "id=0,streams=0 id=1,streams=2 id=2,streams=a" {
+		t.Errorf("args = %v, want adaptation sets after -f dash", args)
+	}
+}
+
+func TestBuildFilterComplexWithoutDASH(t *testing.T) {
+	filter := buildFilterComplex([]Rendition{{Width: 1280, Height: 720}}, false)
+	if want := "[0:v]split=1[v0];[v0]scale=w=1280:h=720:force_original_aspect_ratio=decrease[v0out]"; filter != want {
+		t.Errorf("filter = %q, want %q", filter, want)
+	}
+}
@@ -0,0 +1,221 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Image formats supported for posters and thumbnails.
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatWebP = "webp"
+)
+
+// Image kinds produced by GenerateThumbnails.
+const (
+	ImageKindPoster    = "poster"
+	ImageKindThumbnail = "thumbnail"
+)
+
+// ImageSize is a bounding box for generated images. A zero Height keeps the
+// source aspect ratio for the given Width.
+type ImageSize struct {
+	Width  int
+	Height int
+}
+
+// ThumbnailOptions configures poster and thumbnail generation.
+type ThumbnailOptions struct {
+	PosterAt   time.Duration // Poster timestamp; ignored when PosterAuto is set
+	PosterAuto bool          // Pick the poster with ffmpeg's representative frame heuristic
+	Count      int           // Number of evenly spaced thumbnails, 0 for none
+	Sizes      []ImageSize   // Sizes every image is rendered at
+	Formats    []string      // ImageFormatJPEG and/or ImageFormatWebP
+	Quality    int           // Image quality from 1 to 100, defaults to 80
+	Dir        string        // Output subdirectory, defaults to "thumbnails"
+}
+
+// Image is one generated poster or thumbnail file.
+type Image struct {
+	Path   string        // Path relative to the output directory
+	Kind   string        // ImageKindPoster or ImageKindThumbnail
+	Width  int           // Width in pixels
+	Height int           // Height in pixels
+	Format string        // ImageFormatJPEG or ImageFormatWebP
+	Time   time.Duration // Position of the frame in the source
+}
+
+// GenerateThumbnails renders a poster frame and Count evenly spaced
+// thumbnails of inputPath into outputDir, in every configured size and
+// format. Each frame is decoded once and scaled to all outputs.
+func (t *Transcoder) GenerateThumbnails(ctx context.Context, inputPath, outputDir string, opts ThumbnailOptions) ([]Image, error) {
+	ctx, cancel := t.jobContext(ctx)
+	defer cancel()
+
+	// ffmpeg runs inside outputDir, so the input must not be relative.
+	inputPath, err := filepath.Abs(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	probe, err := t.sourceProbe(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe source: %w", err)
+	}
+	video := probe.PrimaryVideo()
+	if video == nil {
+		return nil, fmt.Errorf("no video stream found")
+	}
+	srcW, srcH := video.DisplaySize()
+
+	if len(opts.Sizes) == 0 {
+		opts.Sizes = []ImageSize{{Width: srcW, Height: srcH}}
+	}
+	if len(opts.Formats) == 0 {
+		opts.Formats = []string{ImageFormatJPEG}
+	}
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = 80
+	}
+	if opts.Dir == "" {
+		opts.Dir = "thumbnails"
+	}
+	if err := os.MkdirAll(filepath.Join(outputDir, opts.Dir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail directory: %w", err)
+	}
+
+	var images []Image
+
+	// Poster frame, either at a fixed timestamp or the most representative
+	// frame of the first part of the video.
+	posterAt := opts.PosterAt
+	if opts.PosterAuto || posterAt >= probe.Duration && probe.Duration > 0 {
+		posterAt = probe.Duration / 10
+	}
+	poster, err := t.renderFrame(ctx, inputPath, outputDir, opts, srcW, srcH, ImageKindPoster, "poster", posterAt, opts.PosterAuto)
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, poster...)
+
+	// Evenly spaced thumbnails, each taken from the middle of its interval.
+	if opts.Count > 0 && probe.Duration > 0 {
+		step := probe.Duration / time.Duration(opts.Count)
+		for i := 0; i < opts.Count; i++ {
+			at := step*time.Duration(i) + step/2
+			thumbs, err := t.renderFrame(ctx, inputPath, outputDir, opts, srcW, srcH, ImageKindThumbnail, fmt.Sprintf("thumb_%03d", i), at, false)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, thumbs...)
+		}
+	}
+
+	return images, nil
+}
+
+// renderFrame decodes the frame at position and writes it in every size and
+// format. With representative set, ffmpeg's thumbnail filter picks the most
+// representative frame of the following batch instead, which skips black
+// and transition frames.
+func (t *Transcoder) renderFrame(
+	ctx context.Context,
+	inputPath, outputDir string,
+	opts ThumbnailOptions,
+	srcW, srcH int,
+	kind, name string,
+	position time.Duration,
+	representative bool,
+) ([]Image, error) {
+	var images []Image
+	var outputs []string
+	for _, size := range opts.Sizes {
+		w, h := fitSize(srcW, srcH, size)
+		for _, format := range opts.Formats {
+			ext := "jpg"
+			if format == ImageFormatWebP {
+				ext = "webp"
+			}
+			rel := filepath.Join(opts.Dir, fmt.Sprintf("%s_%dx%d.%s", name, w, h, ext))
+			images = append(images, Image{
+				Path:   filepath.ToSlash(rel),
+				Kind:   kind,
+				Width:  w,
+				Height: h,
+				Format: format,
+				Time:   position,
+			})
+			outputs = append(outputs, rel)
+		}
+	}
+
+	// Split the decoded frame once per output and scale each copy.
+	source := "[0:v]"
+	if representative {
+		source = "[0:v]thumbnail=n=240,"
+	}
+	var filters []string
+	var splits []string
+	for i := range images {
+		splits = append(splits, fmt.Sprintf("[s%d]", i))
+		filters = append(filters, fmt.Sprintf("[s%d]scale=%d:%d[o%d]", i, images[i].Width, images[i].Height, i))
+	}
+	filterComplex := fmt.Sprintf("%ssplit=%d%s;%s", source, len(images), strings.Join(splits, ""), strings.Join(filters, ";"))
+
+	args := []string{
+		"-y",
+		"-ss", fmt.Sprintf("%.3f", position.Seconds()),
+		"-i", inputPath,
+		"-filter_complex", filterComplex,
+	}
+	for i, img := range images {
+		args = append(args, "-map", fmt.Sprintf("[o%d]", i), "-frames:v", "1")
+		if img.Format == ImageFormatWebP {
+			args = append(args, "-c:v", "libwebp", "-quality", fmt.Sprint(opts.Quality))
+		} else {
+			args = append(args, "-q:v", fmt.Sprint(jpegQScale(opts.Quality)))
+		}
+		args = append(args, outputs[i])
+	}
+
+	if err := t.runCommand(ctx, outputDir, io.Discard, io.Discard, "ffmpeg", args...); err != nil {
+		log.Printf("ffmpeg thumbnail error: %v\nOutput:\n%s", err, stderrTail(err))
+		return nil, fmt.Errorf("ffmpeg thumbnail failed: %w", err)
+	}
+
+	return images, nil
+}
+
+// fitSize returns the even dimensions of the source scaled to fit inside
+// the bounding box while keeping its aspect ratio.
+func fitSize(srcW, srcH int, size ImageSize) (int, int) {
+	if srcW <= 0 || srcH <= 0 {
+		return even(size.Width), even(size.Height)
+	}
+	w := size.Width
+	h := w * srcH / srcW
+	if size.Height > 0 && h > size.Height {
+		h = size.Height
+		w = h * srcW / srcH
+	}
+	return even(w), even(h)
+}
+
+func even(v int) int {
+	if v < 2 {
+		return 2
+	}
+	return v - v%2
+}
+
+// jpegQScale maps a 1-100 quality to ffmpeg's 2-31 JPEG qscale, where lower
+// is better.
+func jpegQScale(quality int) int {
+	return 31 - (quality*29)/100
+}
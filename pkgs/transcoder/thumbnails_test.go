@@ -0,0 +1,80 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeFFmpeg is an ffmpeg that fails unless its -i input exists from its
+// working directory, and writes every image output it is given.
+const fakeFFmpeg = `#!/bin/sh
+prev=""
+for arg in "$@"; do
+  if [ "$prev" = "-i" ] && [ ! -f "$arg" ]; then
+    echo "fake ffmpeg: $arg: No such file or directory" >&2
+    exit 1
+  fi
+  case "$arg" in
+    *.jpg|*.webp)
+      out=$(printf '%s' "$arg" | sed 's/%03d/001/')
+      mkdir -p "$(dirname "$out")"
+      echo image >"$out"
+      ;;
+  esac
+  prev="$arg"
+done
+`
+
+// useFakeFFmpeg puts fakeFFmpeg first on PATH for the duration of the test.
+func useFakeFFmpeg(t *testing.T) {
+	t.Helper()
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(fakeFFmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// relativeInput creates a source file and changes into its directory, so
+// that it can be passed by its relative name.
+func relativeInput(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.mp4"), []byte("source"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	return "input.mp4"
+}
+
+var testProbe = &ProbeResult{
+	Duration:     30 * time.Second,
+	VideoStreams: []VideoStream{{Width: 1280, Height: 720}},
+}
+
+func TestGenerateThumbnailsRelativeInput(t *testing.T) {
+	useFakeFFmpeg(t)
+	input := relativeInput(t)
+	outputDir := t.TempDir()
+
+	images, err := New(WithProbeResult(testProbe)).GenerateThumbnails(context.Background(), input, outputDir, ThumbnailOptions{
+		Count: 2,
+		Sizes: []ImageSize{{Width: 320}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateThumbnails: %v", err)
+	}
+	if len(images) != 3 {
+		t.Fatalf("got %d images, want a poster and 2 thumbnails", len(images))
+	}
+	for _, img := range images {
+		if _, err := os.Stat(filepath.Join(outputDir, img.Path)); err != nil {
+			t.Errorf("image %s not written: %v", img.Path, err)
+		}
+	}
+}
@@ -1,35 +1,107 @@
 package transcoder
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"time"
 )
 
 // Rendition defines one output quality profile for transcoding.
 type Rendition struct {
-	Name         string // Rendition name, e.g., "1080p"
-	Width        int    // Target width
-	Height       int    // Target height
-	VideoBitrate string // Video bitrate string, e.g., "5000k"
-	AudioBitrate string // Audio bitrate string, e.g., "192k"
+	Name            string // Rendition name, e.g., "1080p"
+	Width           int    // Target width
+	Height          int    // Target height
+	VideoCodec      string // One of the Codec constants, defaults to CodecH264
+	Encoder         string // Optional encoder override, e.g., EncoderAOMAV1 for av1
+	Preset          string // Optional encoder preset, defaults per codec
+	VideoBitrate    string // Video bitrate string, e.g., "5000k"
+	MaxRate         string // Optional VBV max rate, e.g., "5350k"
+	BufSize         string // Optional VBV buffer size, e.g., "7500k"
+	Profile         string // Encoder profile, defaults per codec, e.g., "main"
+	Level           string // Encoder level, e.g., "4.0", derived from the resolution if empty
+	AudioBitrate    string // Audio bitrate string, e.g., "192k"
+	AudioChannels   int    // Audio channel count, 0 keeps the source layout
+	AudioSampleRate int    // Audio sample rate in Hz, 0 keeps the source rate
 }
 
 // DefaultRenditions contains common adaptive streaming resolutions and bitrates.
+// It is used when no ladder is supplied to TranscodeAdaptiveCMAF.
 var DefaultRenditions = []Rendition{
-	{"1080p", 1920, 1080, "5000k", "192k"},
-	{"720p", 1280, 720, "3000k", "128k"},
-	{"360p", 640, 360, "1000k", "96k"},
+	{Name: "1080p", Width: 1920, Height: 1080, VideoBitrate: "5000k", AudioBitrate: "192k"},
+	{Name: "720p", Width: 1280, Height: 720, VideoBitrate: "3000k", AudioBitrate: "128k"},
+	{Name: "360p", Width: 640, Height: 360, VideoBitrate: "1000k", AudioBitrate: "96k"},
 }
 
+// DefaultKillGracePeriod is how long ffmpeg is given to exit after SIGTERM
+// before it is killed.
+const DefaultKillGracePeriod = 10 * time.Second
+
 // Transcoder provides methods to perform video transcoding.
-type Transcoder struct{}
+type Transcoder struct {
+	timeout   time.Duration // Wall-clock limit for a single transcode, 0 for none
+	killGrace time.Duration // Delay between SIGTERM and SIGKILL on cancellation
+	progress  ProgressFunc  // Optional receiver of encode progress reports
+	probe     *ProbeResult  // Optional source metadata, probed on demand if nil
+	encrypt   *Encryption   // Optional HLS encryption
+}
+
+// Option configures a Transcoder.
+type Option func(*Transcoder)
+
+// WithTimeout limits how long a single transcode may run. Zero disables the limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Transcoder) {
+		t.timeout = timeout
+	}
+}
+
+// WithKillGracePeriod sets how long ffmpeg may take to exit after SIGTERM
+// before the process group is killed.
+func WithKillGracePeriod(grace time.Duration) Option {
+	return func(t *Transcoder) {
+		if grace > 0 {
+			t.killGrace = grace
+		}
+	}
+}
+
+// WithProgress registers a callback that receives progress reports while
+// ffmpeg encodes.
+func WithProgress(fn ProgressFunc) Option {
+	return func(t *Transcoder) {
+		t.progress = fn
+	}
+}
+
+// WithProbeResult supplies already known source metadata so the source is
+// not probed again before encoding.
+func WithProbeResult(probe *ProbeResult) Option {
+	return func(t *Transcoder) {
+		t.probe = probe
+	}
+}
+
+// WithEncryption encrypts the HLS output. Encrypted transcodes produce no
+// DASH manifest.
+func WithEncryption(enc *Encryption) Option {
+	return func(t *Transcoder) {
+		t.encrypt = enc
+	}
+}
 
 // New returns a new Transcoder instance.
-func New() *Transcoder {
-	return &Transcoder{}
+func New(opts ...Option) *Transcoder {
+	t := &Transcoder{
+		killGrace: DefaultKillGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // TranscodeAdaptiveCMAF performs adaptive bitrate transcoding using CMAF segments,
@@ -37,17 +109,45 @@ func New() *Transcoder {
 //
 // inputPath: path to source video file.
 // outputDir: directory where transcoded files will be stored.
+// renditions: the ladder to encode, ordered from highest to lowest quality;
+// DefaultRenditions is used when it is empty.
 //
 // Returns the list of renditions created, or an error if transcoding fails.
-func (t *Transcoder) TranscodeAdaptiveCMAF(inputPath, outputDir string) ([]Rendition, error) {
-	// Get input video resolution using ffprobe.
-	srcW, srcH, err := getVideoResolution(inputPath)
+func (t *Transcoder) TranscodeAdaptiveCMAF(inputPath, outputDir string, renditions []Rendition) ([]Rendition, error) {
+	return t.TranscodeAdaptiveCMAFContext(context.Background(), inputPath, outputDir, renditions)
+}
+
+// TranscodeAdaptiveCMAFContext is like TranscodeAdaptiveCMAF but stops ffmpeg
+// when ctx is done or the configured timeout elapses. In that case the error
+// is a *TimeoutError or a *CanceledError.
+func (t *Transcoder) TranscodeAdaptiveCMAFContext(ctx context.Context, inputPath, outputDir string, renditions []Rendition) ([]Rendition, error) {
+	if len(renditions) == 0 {
+		renditions = DefaultRenditions
+	}
+
+	ctx, cancel := t.jobContext(ctx)
+	defer cancel()
+
+	// Get input video resolution and duration using ffprobe.
+	probe, err := t.sourceProbe(ctx, inputPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get source resolution: %w", err)
 	}
+	video := probe.PrimaryVideo()
+	if video == nil {
+		return nil, fmt.Errorf("failed to get source resolution: no video stream found")
+	}
+	srcW, srcH := video.DisplaySize()
 
-	// Select renditions that are smaller or equal to source resolution.
-	selected := filterRenditions(srcW, srcH, DefaultRenditions)
+	// Select renditions that are smaller or equal to source resolution and
+	// fill in their codec defaults.
+	selected := filterRenditions(srcW, srcH, renditions)
+	for i := range selected {
+		if selected[i], err = resolveRendition(selected[i]); err != nil {
+			return nil, fmt.Errorf("rendition %s: %w", selected[i].Name, err)
+		}
+	}
+	hasAudio := probe.HasAudio()
 
 	// Create output directory and variant subdirectories.
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -65,30 +165,75 @@ func (t *Transcoder) TranscodeAdaptiveCMAF(inputPath, outputDir string) ([]Rendi
 		return nil, err
 	}
 
-	// Build ffmpeg filter_complex argument for splitting and scaling.
-	filterComplex := buildFilterComplex(selected)
+	// Relative segment and playlist patterns for HLS, and the
+	// var_stream_map argument for ffmpeg.
+	outputs := ffmpegOutputs{
+		hlsSegmentPattern:  filepath.Join("%v", "seg_%03d.m4s"),
+		hlsPlaylistPattern: filepath.Join("%v", "stream.m3u8"),
+		masterPlaylist:     "master.m3u8",
+		varStreamMap:       buildVarStreamMap(selected, hasAudio),
+		dashManifest:       "manifest.mpd",
+	}
 
-	// Relative segment and playlist patterns for HLS.
-	hlsSegmentPattern := filepath.Join("%v", "seg_%03d.m4s")
-	hlsPlaylistPattern := filepath.Join("%v", "stream.m3u8")
-	masterPlaylist := "master.m3u8"
-	dashManifest := "manifest.mpd"
+	if t.encrypt != nil {
+		encArgs, cleanup, err := t.encrypt.hlsArgs()
+		if err != nil {
+			return nil, fmt.Errorf("prepare encryption: %w", err)
+		}
+		defer cleanup()
+		outputs.hlsExtraArgs = encArgs
+		outputs.dashManifest = ""
+	}
 
-	// Construct the var_stream_map argument for ffmpeg.
-	varStreamMap := buildVarStreamMap(selected)
+	// Build ffmpeg filter_complex argument for splitting and scaling, with
+	// a second copy of each stream for the DASH output.
+	filterComplex := buildFilterComplex(selected, outputs.dashManifest != "")
 
 	// Build the full ffmpeg command-line arguments.
-	args := buildFFmpegArgs(absInputPath, filterComplex, selected, hlsSegmentPattern, hlsPlaylistPattern, masterPlaylist, varStreamMap, dashManifest)
+	args := buildFFmpegArgs(absInputPath, filterComplex, selected, hasAudio, outputs)
 
-	// Create and execute ffmpeg command.
-	cmd := exec.Command("ffmpeg", args...)
-	cmd.Dir = outputDir // Set working directory for output files
+	// Report progress as machine-readable key=value blocks on stdout.
+	args = append([]string{"-progress", "pipe:1", "-nostats"}, args...)
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Printf("ffmpeg error: %v\nOutput:\n%s", err, string(output))
-		return nil, fmt.Errorf("ffmpeg failed: %v", err)
+	// Execute ffmpeg with the output directory as working directory. Only
+	// the tail of its stderr is kept, as a long encode can log a lot.
+	progress := newProgressParser(probe.Duration, t.progress)
+	if err := t.runCommand(ctx, outputDir, progress, io.Discard, "ffmpeg", args...); err != nil {
+		log.Printf("ffmpeg error: %v\nOutput:\n%s", err, stderrTail(err))
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+
+	// Advertise the exact codecs of each variant so players can pick the
+	// most efficient one they support.
+	if err := writeHLSCodecs(filepath.Join(outputDir, outputs.masterPlaylist), selected, hasAudio); err != nil {
+		return nil, err
+	}
+	if outputs.dashManifest != "" {
+		if err := writeDASHCodecs(filepath.Join(outputDir, outputs.dashManifest), selected, hasAudio); err != nil {
+			return nil, err
+		}
+	}
+	if t.encrypt != nil {
+		if err := t.encryptSamples(ctx, t.encrypt, outputDir, len(selected)); err != nil {
+			return nil, err
+		}
+		if err := t.encrypt.writeKeyTags(outputDir, len(selected)); err != nil {
+			return nil, err
+		}
 	}
 
 	return selected, nil
 }
+
+// sourceProbe returns the metadata supplied through WithProbeResult, or
+// probes inputPath.
+func (t *Transcoder) sourceProbe(ctx context.Context, inputPath string) (*ProbeResult, error) {
+	if t.probe != nil {
+		return t.probe, nil
+	}
+	probe, err := Probe(ctx, inputPath)
+	if err != nil {
+		return nil, t.classifyError(ctx, err)
+	}
+	return probe, nil
+}
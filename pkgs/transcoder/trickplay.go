@@ -0,0 +1,230 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrickplayOptions configures the seek-bar preview sprite sheets.
+type TrickplayOptions struct {
+	Interval      time.Duration // Time between two preview frames, defaults to 10s
+	Width         int           // Tile width in pixels, defaults to 160
+	Columns       int           // Tiles per sprite row, defaults to 10
+	Rows          int           // Tile rows per sprite, defaults to 10
+	Quality       int           // JPEG quality from 1 to 100, defaults to 80
+	Dir           string        // Output subdirectory, defaults to "trickplay"
+	ImagePlaylist bool          // Also write an HLS image playlist and reference it from master.m3u8
+}
+
+// Trickplay describes the generated sprite sheets. Paths are relative to
+// the output directory.
+type Trickplay struct {
+	VTTPath      string        // WebVTT track mapping time ranges to sprite regions
+	PlaylistPath string        // HLS image playlist, empty when not requested
+	Sprites      []string      // Sprite sheet images in playback order
+	Interval     time.Duration // Time between two tiles
+	TileWidth    int
+	TileHeight   int
+	Columns      int
+	Rows         int
+}
+
+// GenerateTrickplay extracts a frame every Interval, tiles the frames into
+// JPEG sprite sheets and writes a WebVTT track whose cues point at each
+// tile with a #xywh= media fragment.
+func (t *Transcoder) GenerateTrickplay(ctx context.Context, inputPath, outputDir string, opts TrickplayOptions) (*Trickplay, error) {
+	ctx, cancel := t.jobContext(ctx)
+	defer cancel()
+
+	// ffmpeg runs inside outputDir, so the input must not be relative.
+	inputPath, err := filepath.Abs(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.Width <= 0 {
+		opts.Width = 160
+	}
+	if opts.Columns <= 0 {
+		opts.Columns = 10
+	}
+	if opts.Rows <= 0 {
+		opts.Rows = 10
+	}
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = 80
+	}
+	if opts.Dir == "" {
+		opts.Dir = "trickplay"
+	}
+
+	probe, err := t.sourceProbe(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe source: %w", err)
+	}
+	video := probe.PrimaryVideo()
+	if video == nil {
+		return nil, fmt.Errorf("no video stream found")
+	}
+	if probe.Duration <= 0 {
+		return nil, fmt.Errorf("unknown source duration")
+	}
+	srcW, srcH := video.DisplaySize()
+	tileW, tileH := fitSize(srcW, srcH, ImageSize{Width: opts.Width})
+
+	dir := filepath.Join(outputDir, opts.Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trickplay directory: %w", err)
+	}
+
+	filter := fmt.Sprintf("fps=1/%g,scale=%d:%d,tile=%dx%d",
+		opts.Interval.Seconds(), tileW, tileH, opts.Columns, opts.Rows)
+	args := []string{
+		"-y",
+		"-i", inputPath,
+		"-an", "-sn",
+		"-vf", filter,
+		"-q:v", fmt.Sprint(jpegQScale(opts.Quality)),
+		filepath.Join(opts.Dir, "sprite_%03d.jpg"),
+	}
+
+	if err := t.runCommand(ctx, outputDir, io.Discard, io.Discard, "ffmpeg", args...); err != nil {
+		log.Printf("ffmpeg trickplay error: %v\nOutput:\n%s", err, stderrTail(err))
+		return nil, fmt.Errorf("ffmpeg trickplay failed: %w", err)
+	}
+
+	sprites, err := filepath.Glob(filepath.Join(dir, "sprite_*.jpg"))
+	if err != nil || len(sprites) == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no sprite sheets")
+	}
+	sort.Strings(sprites)
+
+	result := &Trickplay{
+		VTTPath:    filepath.ToSlash(filepath.Join(opts.Dir, "thumbnails.vtt")),
+		Interval:   opts.Interval,
+		TileWidth:  tileW,
+		TileHeight: tileH,
+		Columns:    opts.Columns,
+		Rows:       opts.Rows,
+	}
+	for _, sprite := range sprites {
+		result.Sprites = append(result.Sprites, filepath.ToSlash(filepath.Join(opts.Dir, filepath.Base(sprite))))
+	}
+
+	if err := writeTrickplayVTT(filepath.Join(outputDir, result.VTTPath), result, probe.Duration); err != nil {
+		return nil, err
+	}
+
+	if opts.ImagePlaylist {
+		result.PlaylistPath = filepath.ToSlash(filepath.Join(opts.Dir, "images.m3u8"))
+		if err := writeImagePlaylist(outputDir, result, probe.Duration); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// writeTrickplayVTT writes one cue per tile. Sprite references are relative
+// to the VTT file, which lives next to the sprites.
+func writeTrickplayVTT(path string, tp *Trickplay, duration time.Duration) error {
+	perSprite := tp.Columns * tp.Rows
+	tiles := int(math.Ceil(duration.Seconds() / tp.Interval.Seconds()))
+	if limit := perSprite * len(tp.Sprites); tiles > limit {
+		tiles = limit
+	}
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for k := 0; k < tiles; k++ {
+		start := time.Duration(k) * tp.Interval
+		end := start + tp.Interval
+		if end > duration {
+			end = duration
+		}
+		pos := k % perSprite
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end),
+			filepath.Base(tp.Sprites[k/perSprite]),
+			(pos%tp.Columns)*tp.TileWidth, (pos/tp.Columns)*tp.TileHeight,
+			tp.TileWidth, tp.TileHeight)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("write trickplay vtt: %w", err)
+	}
+	return nil
+}
+
+// writeImagePlaylist writes an HLS image media playlist with one
+// EXT-X-TILES entry per sprite sheet and advertises it in master.m3u8
+// with EXT-X-IMAGE-STREAM-INF.
+func writeImagePlaylist(outputDir string, tp *Trickplay, duration time.Duration) error {
+	perSprite := tp.Columns * tp.Rows
+	spriteDuration := tp.Interval * time.Duration(perSprite)
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(spriteDuration.Seconds())))
+	b.WriteString("#EXT-X-VERSION:7\n")
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:1\n")
+	b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	b.WriteString("#EXT-X-IMAGES-ONLY\n")
+
+	var peak float64
+	for i, sprite := range tp.Sprites {
+		start := spriteDuration * time.Duration(i)
+		length := spriteDuration
+		if start+length > duration {
+			length = duration - start
+		}
+		if length <= 0 {
+			length = tp.Interval
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", length.Seconds())
+		fmt.Fprintf(&b, "#EXT-X-TILES:RESOLUTION=%dx%d,LAYOUT=%dx%d,DURATION=%.3f\n",
+			tp.TileWidth, tp.TileHeight, tp.Columns, tp.Rows, tp.Interval.Seconds())
+		b.WriteString(filepath.Base(sprite) + "\n")
+
+		if info, err := os.Stat(filepath.Join(outputDir, sprite)); err == nil {
+			if bw := float64(info.Size()*8) / length.Seconds(); bw > peak {
+				peak = bw
+			}
+		}
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+
+	if err := os.WriteFile(filepath.Join(outputDir, tp.PlaylistPath), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("write image playlist: %w", err)
+	}
+
+	masterPath := filepath.Join(outputDir, "master.m3u8")
+	master, err := os.ReadFile(masterPath)
+	if err != nil {
+		return fmt.Errorf("read master playlist: %w", err)
+	}
+	tag := fmt.Sprintf("#EXT-X-IMAGE-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"jpeg\",URI=\"%s\"\n",
+		int64(math.Ceil(peak)), tp.TileWidth*tp.Columns, tp.TileHeight*tp.Rows, tp.PlaylistPath)
+	content := strings.TrimRight(string(master), "\n") + "\n" + tag
+	if err := os.WriteFile(masterPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("write master playlist: %w", err)
+	}
+	return nil
+}
+
+// vttTimestamp formats d as a WebVTT HH:MM:SS.mmm timestamp.
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
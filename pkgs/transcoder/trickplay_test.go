@@ -0,0 +1,40 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateTrickplayRelativeInput(t *testing.T) {
+	useFakeFFmpeg(t)
+	input := relativeInput(t)
+	outputDir := t.TempDir()
+
+	tp, err := New(WithProbeResult(testProbe)).GenerateTrickplay(context.Background(), input, outputDir, TrickplayOptions{
+		Interval: 5 * time.Second,
+		Columns:  2,
+		Rows:     2,
+	})
+	if err != nil {
+		t.Fatalf("GenerateTrickplay: %v", err)
+	}
+	if len(tp.Sprites) != 1 {
+		t.Fatalf("sprites = %v", tp.Sprites)
+	}
+
+	vtt, err := os.ReadFile(filepath.Join(outputDir, tp.VTTPath))
+	if err != nil {
+		t.Fatalf("read vtt: %v", err)
+	}
+	// 30s at 5s per tile is 6 tiles, of which one 2x2 sprite holds 4
+	if cues := strings.Count(string(vtt), "-->"); cues != 4 {
+		t.Errorf("vtt has %d cues, want 4:\n%s", cues, vtt)
+	}
+	if !strings.Contains(string(vtt), "sprite_001.jpg#xywh=160,90,160,90") {
+		t.Errorf("vtt lacks the last tile of the sprite:\n%s", vtt)
+	}
+}
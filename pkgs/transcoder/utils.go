@@ -1,41 +1,91 @@
 package transcoder
 
 import (
-	"encoding/json"
-	"fmt"
-	"os/exec"
+	"strconv"
+	"strings"
 )
 
-// videoStream is used to parse JSON output from ffprobe for video streams.
-type videoStream struct {
-	Width  int `json:"width"`
-	Height int `json:"height"`
+// ffprobeStream is used to parse one entry of the "streams" section of
+// ffprobe's JSON output.
+type ffprobeStream struct {
+	Index          int               `json:"index"`
+	CodecType      string            `json:"codec_type"`
+	CodecName      string            `json:"codec_name"`
+	CodecLongName  string            `json:"codec_long_name"`
+	CodecTagString string            `json:"codec_tag_string"`
+	Profile        string            `json:"profile"`
+	Level          int               `json:"level"`
+	Width          int               `json:"width"`
+	Height         int               `json:"height"`
+	PixFmt         string            `json:"pix_fmt"`
+	RFrameRate     string            `json:"r_frame_rate"`
+	AvgFrameRate   string            `json:"avg_frame_rate"`
+	BitRate        string            `json:"bit_rate"`
+	SampleRate     string            `json:"sample_rate"`
+	Channels       int               `json:"channels"`
+	ChannelLayout  string            `json:"channel_layout"`
+	ColorRange     string            `json:"color_range"`
+	ColorSpace     string            `json:"color_space"`
+	ColorTransfer  string            `json:"color_transfer"`
+	ColorPrimaries string            `json:"color_primaries"`
+	Disposition    map[string]int    `json:"disposition"`
+	Tags           map[string]string `json:"tags"`
+	SideDataList   []ffprobeSideData `json:"side_data_list"`
 }
 
-// ffprobeOutput holds the ffprobe JSON output structure for streams.
+// ffprobeSideData is used to parse stream side data such as the display
+// matrix or HDR mastering metadata.
+type ffprobeSideData struct {
+	SideDataType string `json:"side_data_type"`
+	Rotation     *int   `json:"rotation"`
+}
+
+// ffprobeFormat is used to parse the container section of ffprobe's output.
+type ffprobeFormat struct {
+	FormatName     string            `json:"format_name"`
+	FormatLongName string            `json:"format_long_name"`
+	Duration       string            `json:"duration"`
+	Size           string            `json:"size"`
+	BitRate        string            `json:"bit_rate"`
+	Tags           map[string]string `json:"tags"`
+}
+
+// ffprobeOutput holds the ffprobe JSON output structure.
 type ffprobeOutput struct {
-	Streams []videoStream `json:"streams"`
+	Streams []ffprobeStream `json:"streams"`
+	Format  ffprobeFormat   `json:"format"`
 }
 
-// getVideoResolution runs ffprobe on the input video file and extracts
-// the width and height of the first video stream.
-//
-// Returns width, height or an error if probing fails or no video stream found.
-func getVideoResolution(inputPath string) (int, int, error) {
-	cmd := exec.Command("ffprobe", "-v", "quiet", "-print_format", "json", "-show_streams", "-select_streams", "v", inputPath)
-	out, err := cmd.Output()
+// parseInt parses an integer reported as a string by ffprobe, returning 0
+// for missing or "N/A" values.
+func parseInt(value string) int64 {
+	v, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return 0, 0, fmt.Errorf("ffprobe error: %w", err)
+		return 0
 	}
+	return v
+}
 
-	var probeData ffprobeOutput
-	if err := json.Unmarshal(out, &probeData); err != nil {
-		return 0, 0, fmt.Errorf("ffprobe json unmarshal error: %w", err)
+// parseFloat parses a float reported as a string by ffprobe, returning 0
+// for missing or "N/A" values.
+func parseFloat(value string) float64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
 	}
+	return v
+}
 
-	if len(probeData.Streams) == 0 {
-		return 0, 0, fmt.Errorf("no video stream found")
+// parseRational parses a rational such as "30000/1001" into a float,
+// returning 0 for "0/0" or malformed values.
+func parseRational(value string) float64 {
+	num, den, ok := strings.Cut(value, "/")
+	if !ok {
+		return parseFloat(value)
 	}
-
-	return probeData.Streams[0].Width, probeData.Streams[0].Height, nil
+	n, d := parseFloat(num), parseFloat(den)
+	if d == 0 {
+		return 0
+	}
+	return n / d
 }